@@ -21,9 +21,16 @@
 //
 //  email  E-mail address of the BLAST user. Its value must be a string with no internal
 //         spaces, and should be a valid e-mail address.
+//
+// The package level BLAST functions and Rid methods make requests using the ncbi package's
+// default HTTP client. A Client may be used to specify the HTTP client, service base URL,
+// tool and email parameters, and request Limiter for a set of requests. The Bases and
+// AllowHTTP fields of a Client direct requests to other BLAST URL API servers, such as
+// the NCBI BLAST cloud images, which are not subject to the package level Limit.
 package blast
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -41,12 +48,15 @@ var (
 	ErrMissingStatus = errors.New("blast: missing Status field")
 )
 
+// host is the host of the NCBI BLAST server.
+const host = "www.ncbi.nlm.nih.gov"
+
 // Limit is a package level limit on requests that can be sent to the BLAST server. This
 // limit is mandated by the BLAST service usage policy. Limit is exported to allow reuse
 // of http.Requests provided by RequestWebReadCloser without overrunning the BLAST request limit.
 // Changing the the value of Limit to allow more frequent requests may result in IP blocking
-// by the BLAST servers.
-var Limit = ncbi.NewLimiter(3 * time.Second)
+// by the BLAST servers. Limit is registered as the shared Limiter for the BLAST host.
+var Limit = ncbi.SharedLimiter(host, 3*time.Second, 1)
 
 const cmdParam = "CMD" // parameter CMD
 
@@ -235,15 +245,116 @@ func fillParams(cmd string, p interface{}, v url.Values) {
 	}
 }
 
+// Client is a BLAST client. The BLAST methods of a Client make requests using the
+// embedded ncbi.Client. Requests made by a Client with a nil Limiter are subject to
+// the package level Limit unless they are sent to a host other than the NCBI BLAST
+// host by the Client's Bases. Those requests are subject only to any Limiter
+// registered for their host.
+type Client struct {
+	ncbi.Client
+
+	// PollLimit is the minimum interval between Get requests
+	// for the results of a RID made by the Client. If PollLimit
+	// is zero, RidPollLimit is used. If PollLimit is negative,
+	// requests are not limited. The usage policy of the NCBI
+	// BLAST server requires the use of RidPollLimit.
+	PollLimit time.Duration
+}
+
+// NewCloudClient returns a Client that sends requests to the BLAST URL API at the given
+// URL, for example "http://blast.example.org/cgi-bin/blast.cgi" for an NCBI BLAST cloud
+// image. Plain http requests are allowed to the host of the URL, and neither requests nor
+// RID polls made by the returned Client are limited. The databases provided by the server
+// may be listed using the Databases method of the Info returned by RequestInfo.
+func NewCloudClient(base string) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("blast: invalid URL API URL: %q", base)
+	}
+	c := Client{Client: *ncbi.DefaultClient}
+	c.Bases = map[string]string{string(URL): base}
+	if u.Scheme == "http" {
+		c.AllowHTTP = []string{u.Host}
+	}
+	c.Limiter = ncbi.NewLimiter(0)
+	c.PollLimit = -1
+	return &c, nil
+}
+
+// clientFor returns a Client based on ncbi.DefaultClient with the given tool and email,
+// for use by the package level BLAST functions and Rid methods. The Client's Limiter
+// is nil so that Limit is applied only to requests sent to the NCBI BLAST host.
+func clientFor(tool, email string) *Client {
+	c := Client{Client: *ncbi.DefaultClient}
+	c.Tool = tool
+	c.Email = email
+	c.Limiter = nil
+	return &c
+}
+
+// client returns the ncbi.Client used to make requests.
+func (c *Client) client() *ncbi.Client {
+	nc := c.Client
+	if nc.Limiter == nil && isNCBI(nc.Resolve(URL)) {
+		nc.Limiter = Limit
+	}
+	return &nc
+}
+
+// pollWait waits until r may be polled according to the Client's PollLimit or ctx is done.
+func (c *Client) pollWait(ctx context.Context, r *Rid) error {
+	d := c.PollLimit
+	switch {
+	case d < 0:
+		return ctx.Err()
+	case d == 0:
+		d = RidPollLimit
+	}
+	r.mu.Lock()
+	if r.limit == nil {
+		r.limit = ncbi.NewLimiter(d)
+	} else if r.limit.Interval() != d {
+		r.limit.SetInterval(d)
+	}
+	l := r.limit
+	r.mu.Unlock()
+	return l.WaitContext(ctx)
+}
+
+// isNCBI returns whether requests to ut are sent to the NCBI BLAST host.
+func isNCBI(ut ncbi.Util) bool {
+	u, err := url.Parse(string(ut))
+	return err == nil && strings.EqualFold(u.Hostname(), host)
+}
+
 // RequestWebReadCloser returns an io.ReadCloser that reads from the stream returned by a Web request
 // of the the given page. It is the responsibility of the caller to close the returned stream.
 func RequestWebReadCloser(page string, p *WebParameters, tool, email string) (io.ReadCloser, error) {
+	return RequestWebReadCloserContext(context.Background(), page, p, tool, email)
+}
+
+// RequestWebReadCloserContext is like RequestWebReadCloser but uses ctx to cancel the request.
+func RequestWebReadCloserContext(ctx context.Context, page string, p *WebParameters, tool, email string) (io.ReadCloser, error) {
+	return clientFor(tool, email).RequestWebReadCloserContext(ctx, page, p)
+}
+
+// RequestWebReadCloser returns an io.ReadCloser that reads from the stream returned by a Web request
+// of the the given page. It is the responsibility of the caller to close the returned stream.
+func (c *Client) RequestWebReadCloser(page string, p *WebParameters) (io.ReadCloser, error) {
+	return c.RequestWebReadCloserContext(context.Background(), page, p)
+}
+
+// RequestWebReadCloserContext is like RequestWebReadCloser but uses ctx to cancel the request.
+func (c *Client) RequestWebReadCloserContext(ctx context.Context, page string, p *WebParameters) (io.ReadCloser, error) {
 	v := url.Values{}
 	fillParams("Web", p, v)
 	if page != "" {
 		v["PAGE"] = []string{page}
 	}
-	resp, err := URL.Get(v, tool, email, Limit)
+	resp, err := c.client().GetContext(ctx, URL, v)
 	if err != nil {
 		return nil, err
 	}
@@ -251,15 +362,36 @@ func RequestWebReadCloser(page string, p *WebParameters, tool, email string) (io
 }
 
 // Put submits a request for a BLAST job to the NCBI BLAST server and returns the associated
-// Rid containing the RID for the request.
+// Rid containing the RID for the request. Put requests are not retried.
 func Put(query string, p *PutParameters, tool, email string) (*Rid, error) {
+	return PutContext(context.Background(), query, p, tool, email)
+}
+
+// PutContext is like Put but uses ctx to cancel the request.
+func PutContext(ctx context.Context, query string, p *PutParameters, tool, email string) (*Rid, error) {
+	return clientFor(tool, email).PutContext(ctx, query, p)
+}
+
+// Put submits a request for a BLAST job to the BLAST server and returns the associated
+// Rid containing the RID for the request. Put requests are not retried, regardless of
+// the Client's Retry policy, since a failed request may have submitted a search.
+func (c *Client) Put(query string, p *PutParameters) (*Rid, error) {
+	return c.PutContext(context.Background(), query, p)
+}
+
+// PutContext is like Put but uses ctx to cancel the request.
+func (c *Client) PutContext(ctx context.Context, query string, p *PutParameters) (*Rid, error) {
 	v := url.Values{}
 	if query != "" {
 		v["QUERY"] = []string{query}
 	}
 	fillParams("Put", p, v)
 	rid := Rid{}
-	resp, err := URL.Get(v, tool, email, Limit)
+	// A Put that fails after the server has accepted
+	// the search would submit a duplicate if retried.
+	nc := c.client()
+	nc.Retry = ncbi.NoRetry
+	resp, err := nc.GetContext(ctx, URL, v)
 	if err != nil {
 		return nil, err
 	}
@@ -272,7 +404,27 @@ func Put(query string, p *PutParameters, tool, email string) (*Rid, error) {
 }
 
 // SearchInfo returns status information for the search request corresponding to r.
+// If the search has failed, the SearchInfo is returned with a *SearchError or
+// *CPULimitError, and if the search is not known to the server, with an *ExpiredError.
 func (r *Rid) SearchInfo(tool, email string) (*SearchInfo, error) {
+	return r.SearchInfoContext(context.Background(), tool, email)
+}
+
+// SearchInfoContext is like SearchInfo but uses ctx to cancel the request and the wait for the RTOE of r to elapse.
+func (r *Rid) SearchInfoContext(ctx context.Context, tool, email string) (*SearchInfo, error) {
+	return clientFor(tool, email).SearchInfoContext(ctx, r)
+}
+
+// SearchInfo returns status information for the search request corresponding to r.
+// SearchInfo blocks until the estimated time of execution for r has elapsed. If the
+// search has failed, the SearchInfo is returned with a *SearchError or *CPULimitError,
+// and if the search is not known to the server, with an *ExpiredError.
+func (c *Client) SearchInfo(r *Rid) (*SearchInfo, error) {
+	return c.SearchInfoContext(context.Background(), r)
+}
+
+// SearchInfoContext is like SearchInfo but uses ctx to cancel the request and the wait for the RTOE of r to elapse.
+func (c *Client) SearchInfoContext(ctx context.Context, r *Rid) (*SearchInfo, error) {
 	v := url.Values{}
 	if r.rid != "" {
 		v["RID"] = []string{r.rid}
@@ -281,8 +433,11 @@ func (r *Rid) SearchInfo(tool, email string) (*SearchInfo, error) {
 	}
 	v[cmdParam] = []string{"Get"}
 	v["FORMAT_OBJECT"] = []string{"SearchInfo"}
-	<-r.Ready()
-	resp, err := URL.Get(v, tool, email, Limit)
+	err := r.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().GetContext(ctx, URL, v)
 	if err != nil {
 		return nil, err
 	}
@@ -292,12 +447,31 @@ func (r *Rid) SearchInfo(tool, email string) (*SearchInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &s, nil
+	return &s, s.err()
 }
 
 // GetOutput returns an Output filled with data obtained from an Get request for the request
-// corresponding to r.
+// corresponding to r. If the search was terminated for exceeding the CPU usage limit, the
+// Output is returned with a *CPULimitError.
 func (r *Rid) GetOutput(p *GetParameters, tool, email string) (*Output, error) {
+	return r.GetOutputContext(context.Background(), p, tool, email)
+}
+
+// GetOutputContext is like GetOutput but uses ctx to cancel the request and the wait imposed by RidPollLimit.
+func (r *Rid) GetOutputContext(ctx context.Context, p *GetParameters, tool, email string) (*Output, error) {
+	return clientFor(tool, email).GetOutputContext(ctx, r, p)
+}
+
+// GetOutput returns an Output filled with data obtained from an Get request for the request
+// corresponding to r. If the search was terminated for exceeding the CPU usage limit, the
+// Output is returned with a *CPULimitError.
+func (c *Client) GetOutput(r *Rid, p *GetParameters) (*Output, error) {
+	return c.GetOutputContext(context.Background(), r, p)
+}
+
+// GetOutputContext is like GetOutput but uses ctx to cancel the request and the wait imposed by the
+// Client's PollLimit.
+func (c *Client) GetOutputContext(ctx context.Context, r *Rid, p *GetParameters) (*Output, error) {
 	v := url.Values{}
 	if r.rid != "" {
 		v["RID"] = []string{r.rid}
@@ -307,17 +481,42 @@ func (r *Rid) GetOutput(p *GetParameters, tool, email string) (*Output, error) {
 	fillParams("Get", p, v)
 	v["FORMAT_TYPE"] = []string{"XML"}
 	o := Output{}
-	r.limit.Wait()
-	err := URL.GetXML(v, tool, email, Limit, &o)
+	err := c.pollWait(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	err = c.client().GetXMLContext(ctx, URL, v, &o)
 	if err != nil {
 		return nil, err
 	}
+	for _, it := range o.Iterations {
+		if it.Message != nil && isCPULimit(*it.Message) {
+			return &o, &CPULimitError{Rid: r.rid, Msg: *it.Message}
+		}
+	}
 	return &o, nil
 }
 
 // GetReadCloser returns an io.ReadCloser that reads from the stream returned by a Get request
 // corresponding to r. It is the responsibility of the caller to close the returned stream.
 func (r *Rid) GetReadCloser(p *GetParameters, tool, email string) (io.ReadCloser, error) {
+	return r.GetReadCloserContext(context.Background(), p, tool, email)
+}
+
+// GetReadCloserContext is like GetReadCloser but uses ctx to cancel the request and the wait imposed by RidPollLimit.
+func (r *Rid) GetReadCloserContext(ctx context.Context, p *GetParameters, tool, email string) (io.ReadCloser, error) {
+	return clientFor(tool, email).GetReadCloserContext(ctx, r, p)
+}
+
+// GetReadCloser returns an io.ReadCloser that reads from the stream returned by a Get request
+// corresponding to r. It is the responsibility of the caller to close the returned stream.
+func (c *Client) GetReadCloser(r *Rid, p *GetParameters) (io.ReadCloser, error) {
+	return c.GetReadCloserContext(context.Background(), r, p)
+}
+
+// GetReadCloserContext is like GetReadCloser but uses ctx to cancel the request and the wait imposed by the
+// Client's PollLimit.
+func (c *Client) GetReadCloserContext(ctx context.Context, r *Rid, p *GetParameters) (io.ReadCloser, error) {
 	v := url.Values{}
 	if r.rid != "" {
 		v["RID"] = []string{r.rid}
@@ -325,8 +524,11 @@ func (r *Rid) GetReadCloser(p *GetParameters, tool, email string) (io.ReadCloser
 		return nil, ErrNoRidProvided
 	}
 	fillParams("Get", p, v)
-	r.limit.Wait()
-	resp, err := URL.Get(v, tool, email, Limit)
+	err := c.pollWait(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().GetContext(ctx, URL, v)
 	if err != nil {
 		return nil, err
 	}
@@ -335,6 +537,21 @@ func (r *Rid) GetReadCloser(p *GetParameters, tool, email string) (io.ReadCloser
 
 // Delete deletes the the request and results corresponding to r from the NCBI BLAST server.
 func (r *Rid) Delete(tool, email string) error {
+	return r.DeleteContext(context.Background(), tool, email)
+}
+
+// DeleteContext is like Delete but uses ctx to cancel the request.
+func (r *Rid) DeleteContext(ctx context.Context, tool, email string) error {
+	return clientFor(tool, email).DeleteContext(ctx, r)
+}
+
+// Delete deletes the the request and results corresponding to r from the BLAST server.
+func (c *Client) Delete(r *Rid) error {
+	return c.DeleteContext(context.Background(), r)
+}
+
+// DeleteContext is like Delete but uses ctx to cancel the request.
+func (c *Client) DeleteContext(ctx context.Context, r *Rid) error {
 	v := url.Values{}
 	if r.rid != "" {
 		v["RID"] = []string{r.rid}
@@ -342,7 +559,7 @@ func (r *Rid) Delete(tool, email string) error {
 		return ErrNoRidProvided
 	}
 	v[cmdParam] = []string{"Delete"}
-	resp, err := URL.Get(v, tool, email, Limit)
+	resp, err := c.client().GetContext(ctx, URL, v)
 	if err != nil {
 		return err
 	}
@@ -351,13 +568,28 @@ func (r *Rid) Delete(tool, email string) error {
 
 // RequestInfo returns an Info with up-to-date information about NCBI BLAST services.
 func RequestInfo(target string, tool, email string) (*Info, error) {
+	return RequestInfoContext(context.Background(), target, tool, email)
+}
+
+// RequestInfoContext is like RequestInfo but uses ctx to cancel the request.
+func RequestInfoContext(ctx context.Context, target string, tool, email string) (*Info, error) {
+	return clientFor(tool, email).RequestInfoContext(ctx, target)
+}
+
+// RequestInfo returns an Info with up-to-date information about BLAST services.
+func (c *Client) RequestInfo(target string) (*Info, error) {
+	return c.RequestInfoContext(context.Background(), target)
+}
+
+// RequestInfoContext is like RequestInfo but uses ctx to cancel the request.
+func (c *Client) RequestInfoContext(ctx context.Context, target string) (*Info, error) {
 	v := url.Values{}
 	if target != "" {
 		v["TARGET"] = []string{target}
 	}
 	v[cmdParam] = []string{"Info"}
 	var i Info
-	resp, err := URL.Get(v, tool, email, Limit)
+	resp, err := c.client().GetContext(ctx, URL, v)
 	if err != nil {
 		return nil, err
 	}
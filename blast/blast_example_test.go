@@ -31,9 +31,19 @@ func Example(query string, retry int, pp *blast.PutParameters, gp *blast.GetPara
 	var o *blast.Output
 	for k := 0; k < retry; k++ {
 		// Wait for RTOE to elapse and get search status.
+		// Failed and expired searches are reported as errors.
 		var s *blast.SearchInfo
 		s, err = r.SearchInfo(tool, email)
-		if err != nil {
+		var (
+			failed  *blast.SearchError
+			expired *blast.ExpiredError
+		)
+		switch {
+		case errors.As(err, &failed):
+			return nil, fmt.Errorf("search: %s failed: %s", r, failed.Msg)
+		case errors.As(err, &expired):
+			return nil, fmt.Errorf("search: %s expired", r)
+		case err != nil:
 			return nil, err
 		}
 
@@ -43,10 +53,6 @@ func Example(query string, retry int, pp *blast.PutParameters, gp *blast.GetPara
 		switch s.Status {
 		case "WAITING":
 			continue
-		case "FAILED":
-			return nil, fmt.Errorf("search: %s failed", r)
-		case "UNKNOWN":
-			return nil, fmt.Errorf("search: %s expired", r)
 		case "READY":
 			if !s.HaveHits {
 				return nil, fmt.Errorf("search: %s no hits", r)
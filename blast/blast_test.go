@@ -5,10 +5,19 @@
 package blast
 
 import (
+	"context"
 	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/ncbitest"
+
 	"gopkg.in/check.v1"
 )
 
@@ -43,6 +52,98 @@ func (s *S) TestLimiter(c *check.C) {
 	c.Check(count, check.Equals, 3)
 }
 
+func (s *S) TestClientLimit(c *check.C) {
+	cl := &Client{}
+	c.Check(cl.client().Limiter, check.Equals, Limit)
+	l := ncbi.NewLimiter(0)
+	cl.Limiter = l
+	c.Check(cl.client().Limiter, check.Equals, l)
+	cl.Limiter = nil
+	cl.Bases = map[string]string{string(URL): "http://localhost/cgi-bin/blast.cgi"}
+	c.Check(cl.client().Limiter, check.IsNil)
+
+	cl = clientFor("tool", "email")
+	c.Check(cl.client().Limiter, check.Equals, Limit)
+	cl.Bases = map[string]string{"https://www.ncbi.nlm.nih.gov/blast/": "http://localhost/cgi-bin/"}
+	c.Check(cl.client().Limiter, check.IsNil)
+}
+
+func (s *S) TestPollLimit(c *check.C) {
+	cl := &Client{PollLimit: 50 * time.Millisecond}
+	r := NewRid("XXXXXXXX01R")
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(cl.pollWait(context.Background(), r), check.Equals, nil)
+	}
+	c.Check(time.Since(start) >= 100*time.Millisecond, check.Equals, true)
+
+	cl.PollLimit = -1
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(cl.pollWait(context.Background(), r), check.Equals, nil)
+	}
+	c.Check(time.Since(start) < 50*time.Millisecond, check.Equals, true)
+
+	cl.PollLimit = 0
+	r = NewRid("XXXXXXXX01R")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Check(cl.pollWait(ctx, r), check.Equals, nil)
+	c.Check(cl.pollWait(ctx, r), check.Equals, context.DeadlineExceeded)
+	c.Check(r.limit.Interval(), check.Equals, RidPollLimit)
+
+	// A Rid may be polled concurrently.
+	cl.PollLimit = time.Millisecond
+	r = &Rid{rid: "XXXXXXXX01R"}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(cl.pollWait(context.Background(), r), check.Equals, nil)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *S) TestPutNoRetry(c *check.C) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cl := &Client{Client: ncbi.Client{
+		HTTP:  srv.Client(),
+		Bases: map[string]string{string(URL): srv.URL + "/"},
+		Retry: &ncbi.Retry{MaxAttempts: 3, MinBackoff: time.Millisecond},
+	}}
+	_, err := cl.Put("MEEPQSDPSV", nil)
+	c.Check(err, check.NotNil)
+	c.Check(atomic.LoadInt32(&calls), check.Equals, int32(1))
+}
+
+func (s *S) TestNewCloudClient(c *check.C) {
+	cl, err := NewCloudClient("http://blast.example.org:8080/cgi-bin/blast.cgi")
+	c.Assert(err, check.Equals, nil)
+	c.Check(cl.Resolve(URL), check.Equals, ncbi.Util("http://blast.example.org:8080/cgi-bin/blast.cgi"))
+	c.Check(cl.AllowHTTP, check.DeepEquals, []string{"blast.example.org:8080"})
+	c.Check(cl.PollLimit < 0, check.Equals, true)
+	l := cl.Limiter
+	c.Check(l, check.NotNil)
+	c.Check(cl.client().Limiter, check.Equals, l)
+
+	u, err := cl.Prepare(cl.Resolve(URL), url.Values{})
+	c.Assert(err, check.Equals, nil)
+	c.Check(u.Scheme, check.Equals, "http")
+
+	for _, base := range []string{"blast.example.org", "ftp://blast.example.org/", "http://%zz"} {
+		_, err = NewCloudClient(base)
+		c.Check(err, check.Not(check.Equals), nil, check.Commentf("base %q", base))
+	}
+}
+
 var net = flag.String("net", "", "Runs tests involving network connections if given an email address.")
 
 func (s *S) TestBlast(c *check.C) {
@@ -153,3 +254,22 @@ func (s *S) TestBlast(c *check.C) {
 		c.Check(o.Iterations[0].Hits[0], check.DeepEquals, t.expect.Iterations[0].Hits[0])
 	}
 }
+
+func (s *S) TestReplay(c *check.C) {
+	rec := ncbitest.NewRecorder("testdata/cassette", ncbitest.Replay)
+	cl := &Client{Client: ncbi.Client{HTTP: rec.Client(), Tool: tool, Limiter: ncbi.NewLimiter(0)}}
+
+	r, err := cl.Put("TGCTTCACCGTGACCTGGTCGCGGACCACTTC", &PutParameters{Program: "blastn", Database: "nr"})
+	c.Assert(err, check.Equals, nil)
+	c.Check(r.String(), check.Equals, "JUU6AN9D01R")
+
+	si, err := cl.SearchInfo(r)
+	c.Assert(err, check.Equals, nil)
+	c.Check(si.Status, check.Equals, "READY")
+	c.Check(si.HaveHits, check.Equals, true)
+
+	o, err := cl.GetOutput(r, nil)
+	c.Assert(err, check.Equals, nil)
+	c.Check(o.Program, check.Equals, "blastn")
+	c.Check(len(o.Iterations), check.Equals, 1)
+}
@@ -0,0 +1,316 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package blasttest provides an in-process fake of the BLAST URL API for testing
+// code that uses the blast package without network access.
+//
+// A Server accepts Put, Get, Delete and Info commands. Each Put creates a Job that is
+// assigned a new RID and that is walked through the WAITING status to its final
+// status by successive Get requests. The Client method returns a blast.Client that
+// directs requests for blast.URL to the Server.
+//
+// The Server may be configured to enforce a poll limit on each RID, so that the
+// handling of the BLAST usage policy may be tested with a shorter limit than
+// blast.RidPollLimit by setting the PollLimit of both the Server and the Client.
+// The Client's PollLimit should be slightly longer than the Server's to allow for
+// variation in request latency.
+package blasttest
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/blast"
+)
+
+// Search statuses reported by the BLAST server.
+const (
+	Waiting = "WAITING"
+	Ready   = "READY"
+	Failed  = "FAILED"
+	Unknown = "UNKNOWN"
+)
+
+// Job describes the progress and result of a search.
+type Job struct {
+	// RTOE is the estimated time to completion in
+	// seconds returned by Put.
+	RTOE int
+
+	// Waits is the number of Get requests that report
+	// the search as WAITING before Status is reported.
+	Waits int
+
+	// Wait is the delay in seconds until the next poll
+	// reported in WAITING responses.
+	Wait int
+
+	// Status is the final status of the search. If
+	// Status is empty, READY is reported.
+	Status string
+
+	// Message is the error message reported for
+	// a FAILED search.
+	Message string
+
+	// HaveHits specifies whether a READY search
+	// reports that there are hits.
+	HaveHits bool
+
+	// Output is the BlastOutput XML document returned
+	// for READY searches when XML output is requested.
+	Output string
+
+	// Text is returned for READY searches when other
+	// output formats are requested. If Text is empty,
+	// Output is returned.
+	Text string
+}
+
+// JobFunc returns the Job for a search of query with the given Put parameters.
+// If a JobFunc returns a non-nil error, the Put request fails with the error's
+// message.
+type JobFunc func(query string, params url.Values) (*Job, error)
+
+// EmptyOutput is a BlastOutput document holding no iterations.
+const EmptyOutput = `<?xml version="1.0"?>
+<BlastOutput>
+  <BlastOutput_program>blastn</BlastOutput_program>
+  <BlastOutput_version>BLASTN 2.2.27+</BlastOutput_version>
+  <BlastOutput_reference></BlastOutput_reference>
+  <BlastOutput_db>nr</BlastOutput_db>
+  <BlastOutput_query-ID>Query_1</BlastOutput_query-ID>
+  <BlastOutput_query-def>No definition line</BlastOutput_query-def>
+  <BlastOutput_query-len>0</BlastOutput_query-len>
+  <BlastOutput_param>
+    <Parameters>
+      <Parameters_expect>10</Parameters_expect>
+      <Parameters_gap-open>5</Parameters_gap-open>
+      <Parameters_gap-extend>2</Parameters_gap-extend>
+    </Parameters>
+  </BlastOutput_param>
+  <BlastOutput_iterations></BlastOutput_iterations>
+</BlastOutput>
+`
+
+// Server is a fake BLAST URL API server.
+type Server struct {
+	*httptest.Server
+
+	// PollLimit is the minimum interval between status
+	// requests, and between output requests, for a RID.
+	// Requests made sooner are counted as violations and
+	// answered with a 429 Too Many Requests status. If
+	// PollLimit is zero, polling is not limited.
+	PollLimit time.Duration
+
+	// Databases holds the databases listed in
+	// response to Info requests.
+	Databases []blast.Database
+
+	mu         sync.Mutex
+	newJob     JobFunc
+	jobs       map[string]*job
+	nextRid    int
+	violations int
+}
+
+type job struct {
+	Job
+	polls   int
+	last    map[string]time.Time
+	deleted bool
+}
+
+// NewServer returns a started Server that uses fn to create the Job for each Put
+// request. If fn is nil, searches are immediately READY with no hits and return
+// EmptyOutput. The Server must be closed after use.
+func NewServer(fn JobFunc) *Server {
+	if fn == nil {
+		fn = func(string, url.Values) (*Job, error) {
+			return &Job{Output: EmptyOutput}, nil
+		}
+	}
+	s := &Server{newJob: fn, jobs: make(map[string]*job)}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a blast.Client that sends requests for blast.URL to the Server.
+// Requests made by the Client are not rate limited or retried.
+func (s *Server) Client() *blast.Client {
+	return &blast.Client{Client: ncbi.Client{
+		HTTP:    s.Server.Client(),
+		Bases:   map[string]string{string(blast.URL): s.URL + "/blast/Blast.cgi"},
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+	}}
+}
+
+// Polls returns the number of Get requests made for rid.
+func (s *Server) Polls(rid string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[rid]
+	if !ok {
+		return 0
+	}
+	return j.polls
+}
+
+// Violations returns the number of requests that violated the PollLimit.
+func (s *Server) Violations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.violations
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd := r.Form.Get("CMD"); cmd {
+	case "Put":
+		s.put(w, r.Form)
+	case "Get":
+		s.get(w, r.Form)
+	case "Info":
+		s.info(w)
+	case "Delete":
+		if j, ok := s.jobs[r.Form.Get("RID")]; ok {
+			j.deleted = true
+		}
+		writeHTML(w, "")
+	default:
+		http.Error(w, "unknown command: "+cmd, http.StatusBadRequest)
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, v url.Values) {
+	query := v.Get("QUERY")
+	params := make(url.Values)
+	for k, p := range v {
+		if k != "CMD" && k != "QUERY" {
+			params[k] = p
+		}
+	}
+	if query == "" {
+		writeHTML(w, `<div class="error">Message ID#24 Error: Failed to read the Blast query: Nucleotide FASTA provided for protein sequence</div>`)
+		return
+	}
+	spec, err := s.newJob(query, params)
+	if err != nil {
+		writeHTML(w, `<div class="error">Message ID#24 Error: `+html.EscapeString(err.Error())+"</div>")
+		return
+	}
+	s.nextRid++
+	rid := fmt.Sprintf("%09X01R", s.nextRid)
+	s.jobs[rid] = &job{Job: *spec, last: make(map[string]time.Time)}
+	writeHTML(w, fmt.Sprintf("<!--QBlastInfoBegin\n    RID = %s\n    RTOE = %d\nQBlastInfoEnd\n-->", rid, spec.RTOE))
+}
+
+func (s *Server) get(w http.ResponseWriter, v url.Values) {
+	rid := v.Get("RID")
+	j, ok := s.jobs[rid]
+	if !ok || j.deleted {
+		writeStatus(w, Unknown, "")
+		return
+	}
+
+	kind := v.Get("FORMAT_OBJECT")
+	now := time.Now()
+	if last, ok := j.last[kind]; ok && s.PollLimit > 0 && now.Sub(last) < s.PollLimit {
+		s.violations++
+		http.Error(w, "polling too frequently for RID "+rid, http.StatusTooManyRequests)
+		return
+	}
+	j.last[kind] = now
+	j.polls++
+
+	status := j.Status
+	if status == "" {
+		status = Ready
+	}
+	if j.polls <= j.Waits {
+		status = Waiting
+	}
+	if kind == "SearchInfo" || status != Ready {
+		switch status {
+		case Waiting:
+			writeHTML(w, fmt.Sprintf(`<p class="WAITING">This page will be automatically updated in <b>%d</b> seconds</p>
+<!--
+QBlastInfoBegin
+	Status=WAITING
+QBlastInfoEnd
+-->`, j.Wait))
+		case Ready:
+			hits := ""
+			if j.HaveHits {
+				hits = "\n<!--\nQBlastInfoBegin\n\tThereAreHits=yes\nQBlastInfoEnd\n-->"
+			}
+			writeStatus(w, Ready, hits)
+		case Failed:
+			msg := ""
+			if j.Message != "" {
+				msg = "\n<p class=\"error\">Error: " + html.EscapeString(j.Message) + "</p>"
+			}
+			writeStatus(w, Failed, msg)
+		default:
+			writeStatus(w, status, "")
+		}
+		return
+	}
+
+	if v.Get("FORMAT_TYPE") != "XML" && j.Text != "" {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, j.Text)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, j.Output)
+}
+
+func (s *Server) info(w http.ResponseWriter) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "<!--\nQBlastInfoBegin\n\tStatus=INFO_DB\n# Number of databases\n%d\n", len(s.Databases))
+	for _, exclusive := range []bool{true, false} {
+		if exclusive {
+			buf.WriteString("\n# exclusive databases\n\n")
+		} else {
+			buf.WriteString("\n# non-exclusive databases\n\n")
+		}
+		for _, db := range s.Databases {
+			if db.Exclusive == exclusive {
+				fmt.Fprintf(&buf, "%s\t%d\t%s\n", db.Name, db.ID, strings.ToUpper(strconv.FormatBool(db.Protein)))
+			}
+		}
+	}
+	buf.WriteString("\n# end of the file\n\nQBlastInfoEnd\n-->")
+	writeHTML(w, buf.String())
+}
+
+// writeStatus writes a SearchInfo page reporting status followed by extra.
+func writeStatus(w http.ResponseWriter, status, extra string) {
+	writeHTML(w, "<!--\nQBlastInfoBegin\n\tStatus="+status+"\nQBlastInfoEnd\n-->"+extra)
+}
+
+// writeHTML writes an HTML page with the given body.
+func writeHTML(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<body>\n%s\n</body>\n</html>\n", body)
+}
@@ -0,0 +1,146 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blasttest
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/blast"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestSearch(c *check.C) {
+	var gotQuery string
+	var gotParams url.Values
+	srv := NewServer(func(query string, params url.Values) (*Job, error) {
+		gotQuery, gotParams = query, params
+		return &Job{Waits: 1, HaveHits: true, Output: EmptyOutput}, nil
+	})
+	defer srv.Close()
+	cl := srv.Client()
+
+	r, err := cl.Put("ACGT", &blast.PutParameters{Program: "blastn", Database: "nr"})
+	c.Assert(err, check.Equals, nil)
+	c.Check(gotQuery, check.Equals, "ACGT")
+	c.Check(gotParams.Get("PROGRAM"), check.Equals, "blastn")
+	c.Check(r.TimeOfExecution(), check.Equals, time.Duration(0))
+
+	si, err := cl.SearchInfo(r)
+	c.Assert(err, check.Equals, nil)
+	c.Check(si.Status, check.Equals, Waiting)
+
+	si, err = cl.SearchInfo(r)
+	c.Assert(err, check.Equals, nil)
+	c.Check(si.Status, check.Equals, Ready)
+	c.Check(si.HaveHits, check.Equals, true)
+
+	o, err := cl.GetOutput(r, nil)
+	c.Assert(err, check.Equals, nil)
+	c.Check(o.Program, check.Equals, "blastn")
+	c.Check(srv.Polls(r.String()), check.Equals, 3)
+
+	c.Check(cl.Delete(r), check.Equals, nil)
+	_, err = cl.SearchInfo(r)
+	var e *blast.ExpiredError
+	c.Check(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+}
+
+func (s *S) TestFailed(c *check.C) {
+	srv := NewServer(func(query string, _ url.Values) (*Job, error) {
+		switch query {
+		case "cpu":
+			return &Job{Status: Failed, Message: "CPU usage limit was exceeded"}, nil
+		case "fail":
+			return &Job{Status: Failed, Message: "Internal error"}, nil
+		}
+		return nil, errors.New("Failed to read the Blast query")
+	})
+	defer srv.Close()
+	cl := srv.Client()
+
+	r, err := cl.Put("cpu", nil)
+	c.Assert(err, check.Equals, nil)
+	_, err = cl.SearchInfo(r)
+	var ce *blast.CPULimitError
+	c.Check(errors.As(err, &ce), check.Equals, true, check.Commentf("unexpected error: %v", err))
+
+	r, err = cl.Put("fail", nil)
+	c.Assert(err, check.Equals, nil)
+	_, err = cl.SearchInfo(r)
+	var se *blast.SearchError
+	c.Assert(errors.As(err, &se), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(se.Msg, check.Equals, "Internal error")
+
+	_, err = cl.Put("bad", nil)
+	_, ok := err.(blast.ErrBadRequest)
+	c.Check(ok, check.Equals, true, check.Commentf("unexpected error: %v", err))
+}
+
+func (s *S) TestPollLimit(c *check.C) {
+	srv := NewServer(nil)
+	defer srv.Close()
+	srv.PollLimit = 200 * time.Millisecond
+	cl := srv.Client()
+
+	r, err := cl.Put("ACGT", nil)
+	c.Assert(err, check.Equals, nil)
+	si, err := cl.SearchInfo(r)
+	c.Check(err, check.Equals, nil)
+	c.Check(si.HaveHits, check.Equals, false)
+	_, err = cl.SearchInfo(r)
+	var e *ncbi.RateLimitError
+	c.Check(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(srv.Violations(), check.Equals, 1)
+
+	// Status and output polls are limited separately.
+	_, err = cl.GetOutput(r, nil)
+	c.Check(err, check.Equals, nil)
+
+	time.Sleep(srv.PollLimit)
+	_, err = cl.SearchInfo(r)
+	c.Check(err, check.Equals, nil)
+	c.Check(srv.Violations(), check.Equals, 1)
+}
+
+func (s *S) TestClientPollLimit(c *check.C) {
+	srv := NewServer(nil)
+	defer srv.Close()
+	srv.PollLimit = 100 * time.Millisecond
+	cl := srv.Client()
+	cl.PollLimit = srv.PollLimit + 20*time.Millisecond
+
+	r, err := cl.Put("ACGT", nil)
+	c.Assert(err, check.Equals, nil)
+	for i := 0; i < 3; i++ {
+		_, err = cl.GetOutput(r, nil)
+		c.Check(err, check.Equals, nil)
+	}
+	c.Check(srv.Violations(), check.Equals, 0)
+}
+
+func (s *S) TestInfo(c *check.C) {
+	srv := NewServer(nil)
+	defer srv.Close()
+	srv.Databases = []blast.Database{
+		{Name: "nt_euk", ID: 1, Exclusive: true},
+		{Name: "swissprot", ID: 2, Protein: true},
+	}
+
+	info, err := srv.Client().RequestInfo("")
+	c.Assert(err, check.Equals, nil)
+	c.Check(info.Status(), check.Equals, "INFO_DB")
+	c.Check(info.Databases(), check.DeepEquals, srv.Databases)
+}
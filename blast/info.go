@@ -6,6 +6,8 @@ package blast
 
 import (
 	"io"
+	"strconv"
+	"strings"
 
 	"golang.org/x/net/html"
 )
@@ -25,3 +27,70 @@ func (i *Info) unmarshal(r io.Reader) error {
 		}
 	}
 }
+
+// Status returns the value of the Status field of i.
+func (i Info) Status() string {
+	for _, l := range strings.Split(string(i), "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "Status=") {
+			return strings.TrimPrefix(l, "Status=")
+		}
+	}
+	return ""
+}
+
+// Database describes a BLAST database listed by the BLAST server.
+type Database struct {
+	// Name is the name of the database used
+	// in the DATABASE parameter of a Put.
+	Name string
+
+	// ID is the server's index of the database.
+	ID int
+
+	// Protein indicates whether the database
+	// holds protein sequences.
+	Protein bool
+
+	// Exclusive indicates whether the database
+	// is listed as an exclusive database.
+	Exclusive bool
+}
+
+// Databases returns the databases listed in an Info obtained with the INFO_DB status.
+// Listing lines are of the form "name id TRUE|FALSE", where TRUE indicates a protein
+// database. Servers that list no databases, for example those reporting an UNKNOWN
+// status, return nil.
+func (i Info) Databases() []Database {
+	var (
+		dbs       []Database
+		exclusive bool
+	)
+	for _, l := range strings.Split(string(i), "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "#") {
+			// The NCBI server misspells exclusive as "exlclusive".
+			l = strings.ToLower(l)
+			exclusive = strings.Contains(l, "clusive") && !strings.Contains(l, "non-")
+			continue
+		}
+		f := strings.Fields(l)
+		if len(f) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(f[1])
+		if err != nil {
+			continue
+		}
+		var protein bool
+		switch f[2] {
+		case "TRUE":
+			protein = true
+		case "FALSE":
+		default:
+			continue
+		}
+		dbs = append(dbs, Database{Name: f[0], ID: id, Protein: protein, Exclusive: exclusive})
+	}
+	return dbs
+}
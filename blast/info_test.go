@@ -136,3 +136,39 @@ QBlastInfoEnd
 		c.Check(info, check.DeepEquals, t.info, check.Commentf("Test: %d", i))
 	}
 }
+
+func (s *S) TestInfoDatabases(c *check.C) {
+	info := Info(`
+QBlastInfoBegin
+	Status=INFO_DB
+# Number of databases
+5
+
+# exlclusive databases
+
+nr              1       TRUE
+nr              2       FALSE
+pataa		9	TRUE
+
+# non-exlclusive databases
+
+swissprot       11      TRUE
+month.nt        16      FALSE
+
+# end of the file
+
+QBlastInfoEnd
+`)
+	c.Check(info.Status(), check.Equals, "INFO_DB")
+	c.Check(info.Databases(), check.DeepEquals, []Database{
+		{Name: "nr", ID: 1, Protein: true, Exclusive: true},
+		{Name: "nr", ID: 2, Protein: false, Exclusive: true},
+		{Name: "pataa", ID: 9, Protein: true, Exclusive: true},
+		{Name: "swissprot", ID: 11, Protein: true, Exclusive: false},
+		{Name: "month.nt", ID: 16, Protein: false, Exclusive: false},
+	})
+
+	info = Info("\nQBlastInfoBegin\n\tStatus=UNKNOWN\nQBlastInfoEnd\n")
+	c.Check(info.Status(), check.Equals, "UNKNOWN")
+	c.Check(info.Databases(), check.IsNil)
+}
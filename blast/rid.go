@@ -6,10 +6,12 @@ package blast
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/biogo/ncbi"
@@ -26,6 +28,8 @@ type Rid struct {
 	rid   string
 	rtoe  time.Time
 	delay <-chan time.Time
+
+	mu    sync.Mutex // mu protects limit.
 	limit *ncbi.Limiter
 }
 
@@ -51,6 +55,46 @@ type ErrBadRequest string
 
 func (e ErrBadRequest) Error() string { return string(e) }
 
+// SearchError is returned when the BLAST server reports that a search has failed.
+type SearchError struct {
+	Rid string
+	Msg string
+}
+
+func (e *SearchError) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("blast: search %s failed", e.Rid)
+	}
+	return fmt.Sprintf("blast: search %s failed: %s", e.Rid, e.Msg)
+}
+
+// CPULimitError is returned when a search has been terminated by the BLAST server
+// for exceeding the CPU usage limit. Searches that exceed the limit may succeed if
+// resubmitted with a smaller query or a more restrictive set of parameters.
+type CPULimitError struct {
+	Rid string
+	Msg string
+}
+
+func (e *CPULimitError) Error() string {
+	return fmt.Sprintf("blast: search %s exceeded CPU usage limit: %s", e.Rid, e.Msg)
+}
+
+// ExpiredError is returned when the BLAST server does not hold the search corresponding
+// to a RID, either because the search results have expired or the RID is not valid.
+type ExpiredError struct {
+	Rid string
+}
+
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("blast: search %s unknown or expired", e.Rid)
+}
+
+// isCPULimit returns whether msg reports that a search exceeded the CPU usage limit.
+func isCPULimit(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "cpu usage limit")
+}
+
 func (rid *Rid) unmarshal(r io.Reader) error {
 	z := html.NewTokenizer(r)
 	for {
@@ -136,11 +180,27 @@ func (r *Rid) Ready() <-chan time.Time {
 	return r.delay
 }
 
+// wait blocks until the estimated time for the Put request to be satisfied has elapsed
+// or ctx is done. If ctx is done first, ctx.Err() is returned and r's delay is retained.
+func (r *Rid) wait(ctx context.Context) error {
+	select {
+	case <-r.delay:
+		r.setElapsedDelay()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // SearchInfo holds search status information.
 type SearchInfo struct {
 	*Rid
 	Status   string
 	HaveHits bool
+
+	// msg holds any error message
+	// included in the response.
+	msg string
 }
 
 func (s *SearchInfo) String() string {
@@ -183,6 +243,13 @@ func (s *SearchInfo) unmarshal(r io.Reader) error {
 			}
 		}
 
+		if tt == html.TextToken && s.msg == "" {
+			text := bytes.TrimSpace(z.Text())
+			if bytes.HasPrefix(text, errorBytes) {
+				s.msg = string(bytes.TrimSpace(text[len(errorBytes):]))
+			}
+		}
+
 		if tt == html.CommentToken {
 			d := z.Token().Data
 			if strings.Contains(d, "QBlastInfoBegin") {
@@ -208,3 +275,17 @@ func (s *SearchInfo) unmarshal(r io.Reader) error {
 	}
 	return nil
 }
+
+// err returns an error corresponding to a failed or unknown search status.
+func (s *SearchInfo) err() error {
+	switch s.Status {
+	case "FAILED":
+		if isCPULimit(s.msg) {
+			return &CPULimitError{Rid: s.Rid.String(), Msg: s.msg}
+		}
+		return &SearchError{Rid: s.Rid.String(), Msg: s.msg}
+	case "UNKNOWN":
+		return &ExpiredError{Rid: s.Rid.String()}
+	}
+	return nil
+}
@@ -5,6 +5,8 @@
 package blast
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -1874,3 +1876,53 @@ QBlastInfoEnd
 		c.Check(s.HaveHits, check.Equals, t.haveHits, check.Commentf("Test: %d", i))
 	}
 }
+
+func (s *S) TestSearchInfoContext(c *check.C) {
+	r := &Rid{rid: "XXXXXXXX01R", delay: time.After(time.Hour)}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := r.SearchInfoContext(ctx, tool, "")
+	c.Check(err, check.Equals, context.DeadlineExceeded)
+	c.Check(time.Since(start) < time.Second, check.Equals, true)
+	select {
+	case <-r.delay:
+		c.Error("unexpected elapsed RTOE delay")
+	default:
+	}
+}
+
+func (s *S) TestSearchInfoErr(c *check.C) {
+	const page = `<html><body>
+<!--QBlastInfoBegin
+	Status=%s
+QBlastInfoEnd
+-->
+<p class="error">%s</p>
+</body></html>`
+	rid := &Rid{rid: "XXXXXXXX01R"}
+	for i, t := range []struct {
+		status string
+		text   string
+		err    error
+	}{
+		{status: "READY"},
+		{status: "WAITING"},
+		{
+			status: "FAILED",
+			text:   "Error: CPU usage limit was exceeded, resulting in SIGXCPU (24).",
+			err:    &CPULimitError{Rid: "XXXXXXXX01R", Msg: "CPU usage limit was exceeded, resulting in SIGXCPU (24)."},
+		},
+		{
+			status: "FAILED",
+			text:   "Error: Database not found.",
+			err:    &SearchError{Rid: "XXXXXXXX01R", Msg: "Database not found."},
+		},
+		{status: "UNKNOWN", err: &ExpiredError{Rid: "XXXXXXXX01R"}},
+	} {
+		si := SearchInfo{Rid: rid}
+		err := si.unmarshal(strings.NewReader(fmt.Sprintf(page, t.status, t.text)))
+		c.Assert(err, check.Equals, nil, check.Commentf("Test: %d", i))
+		c.Check(si.err(), check.DeepEquals, t.err, check.Commentf("Test: %d", i))
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blast
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/url"
+)
+
+// OutputReader states.
+const (
+	stateHeader = iota
+	stateIterations
+	stateIteration
+	stateHits
+	stateTrailer
+	stateDone
+)
+
+// An OutputReader reads the results of a Get request one hit at a time, allowing
+// results holding large numbers of iterations and hits to be processed without holding
+// them in memory.
+//
+// Iterations are advanced with NextIteration and the hits of the current iteration
+// are advanced with Next:
+//
+//	for r.NextIteration() {
+//		it := r.Iteration()
+//		for r.Next() {
+//			hit := r.Hit()
+//			...
+//		}
+//	}
+//	if r.Err() != nil {
+//		...
+//	}
+type OutputReader struct {
+	r   io.Reader
+	dec *xml.Decoder
+	rid string
+
+	state int
+	out   Output
+	it    Iteration
+	hit   Hit
+	err   error
+}
+
+// NewOutputReader returns an OutputReader that reads BLAST XML output from r. If r
+// is an io.Closer, it is closed by the OutputReader's Close method.
+func NewOutputReader(r io.Reader) *OutputReader {
+	return &OutputReader{r: r, dec: xml.NewDecoder(r)}
+}
+
+// Output returns the BLAST output header fields. The Iterations field of the returned
+// Output is always nil and the MegaStatistics field is only filled once all iterations
+// have been read.
+func (r *OutputReader) Output() *Output {
+	if r.state == stateHeader {
+		r.readHeader()
+	}
+	return &r.out
+}
+
+// NextIteration advances the OutputReader to the next iteration, skipping any unread
+// hits of the current iteration. It returns false when there are no more iterations
+// or an error occurs.
+func (r *OutputReader) NextIteration() bool {
+	if r.state == stateHeader {
+		r.readHeader()
+	}
+	if r.state == stateIteration || r.state == stateHits {
+		r.skipIteration()
+	}
+	if r.err != nil || r.state != stateIterations {
+		return false
+	}
+	for {
+		t, ok := r.token()
+		if !ok {
+			return false
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "Iteration" {
+				r.skip()
+				continue
+			}
+			r.it = Iteration{}
+			r.state = stateIteration
+			r.readIteration()
+			return r.err == nil
+		case xml.EndElement:
+			r.state = stateTrailer
+			r.readTrailer()
+			return false
+		}
+	}
+}
+
+// Iteration returns the current iteration. The Hits field of the returned Iteration
+// is always nil and the Statistics and Message fields are only filled once Next has
+// returned false for the iteration.
+func (r *OutputReader) Iteration() *Iteration { return &r.it }
+
+// Next advances the OutputReader to the next hit of the current iteration, which
+// is then available through the Hit method. It returns false when there are no more
+// hits in the iteration or an error occurs.
+func (r *OutputReader) Next() bool {
+	return r.nextHit(true)
+}
+
+// Hit returns the current hit.
+func (r *OutputReader) Hit() Hit { return r.hit }
+
+// Err returns the first error encountered by the OutputReader. If the search was
+// terminated for exceeding the CPU usage limit, a *CPULimitError is returned.
+func (r *OutputReader) Err() error { return r.err }
+
+// Close closes the underlying stream. If all the iterations have been read, any
+// trailing data is read so that the response may be cached and the connection reused.
+func (r *OutputReader) Close() error {
+	var err error
+	if r.state == stateDone && r.err == nil {
+		_, err = io.Copy(ioutil.Discard, r.r)
+	}
+	r.state = stateDone
+	if c, ok := r.r.(io.Closer); ok {
+		cerr := c.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// token returns the next start or end element token from the stream.
+func (r *OutputReader) token() (xml.Token, bool) {
+	for {
+		t, err := r.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			r.err = err
+			r.state = stateDone
+			return nil, false
+		}
+		switch t.(type) {
+		case xml.StartElement, xml.EndElement:
+			return t, true
+		}
+	}
+}
+
+func (r *OutputReader) decode(v interface{}, start xml.StartElement) {
+	err := r.dec.DecodeElement(v, &start)
+	if err != nil {
+		r.err = err
+		r.state = stateDone
+	}
+}
+
+func (r *OutputReader) skip() {
+	err := r.dec.Skip()
+	if err != nil {
+		r.err = err
+		r.state = stateDone
+	}
+}
+
+// readHeader reads the BLAST output fields preceding the iterations.
+func (r *OutputReader) readHeader() {
+	var param struct {
+		Parameters Parameters `xml:"Parameters"`
+	}
+	fields := map[string]interface{}{
+		"BlastOutput_program":   &r.out.Program,
+		"BlastOutput_version":   &r.out.Version,
+		"BlastOutput_reference": &r.out.Reference,
+		"BlastOutput_db":        &r.out.Database,
+		"BlastOutput_query-ID":  &r.out.QueryId,
+		"BlastOutput_query-def": &r.out.QueryDef,
+		"BlastOutput_query-len": &r.out.QueryLen,
+		"BlastOutput_query-seq": &r.out.QuerSeq,
+		"BlastOutput_param":     &param,
+	}
+	defer func() { r.out.Parameters = param.Parameters }()
+	for r.state == stateHeader {
+		t, ok := r.token()
+		if !ok {
+			return
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch name := start.Name.Local; name {
+		case "BlastOutput":
+		case "BlastOutput_iterations":
+			r.state = stateIterations
+		default:
+			if f, ok := fields[name]; ok {
+				r.decode(f, start)
+			} else {
+				r.skip()
+			}
+		}
+	}
+}
+
+// readIteration reads the fields of the current iteration up to its hits or the
+// end of the iteration.
+func (r *OutputReader) readIteration() {
+	var stat struct {
+		Statistics *Statistics `xml:"Statistics"`
+	}
+	fields := map[string]interface{}{
+		"Iteration_iter-num":  &r.it.N,
+		"Iteration_query-ID":  &r.it.QueryId,
+		"Iteration_query-def": &r.it.QueryDef,
+		"Iteration_query-len": &r.it.QueryLen,
+		"Iteration_stat":      &stat,
+		"Iteration_message":   &r.it.Message,
+	}
+	defer func() {
+		if stat.Statistics != nil {
+			r.it.Statistics = stat.Statistics
+		}
+	}()
+	for r.state == stateIteration {
+		t, ok := r.token()
+		if !ok {
+			return
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Iteration_hits" {
+				r.state = stateHits
+				return
+			}
+			if f, ok := fields[t.Name.Local]; ok {
+				r.decode(f, t)
+			} else {
+				r.skip()
+			}
+		case xml.EndElement:
+			r.state = stateIterations
+			if r.it.Message != nil && isCPULimit(*r.it.Message) {
+				r.err = &CPULimitError{Rid: r.rid, Msg: *r.it.Message}
+			}
+		}
+	}
+}
+
+// nextHit advances to the next hit of the current iteration, decoding it if
+// decode is true.
+func (r *OutputReader) nextHit(decode bool) bool {
+	for r.state == stateHits {
+		t, ok := r.token()
+		if !ok {
+			return false
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if !decode || t.Name.Local != "Hit" {
+				r.skip()
+				continue
+			}
+			r.hit = Hit{}
+			r.decode(&r.hit, t)
+			return r.err == nil
+		case xml.EndElement:
+			r.state = stateIteration
+			r.readIteration()
+		}
+	}
+	return false
+}
+
+// skipIteration skips the remainder of the current iteration.
+func (r *OutputReader) skipIteration() {
+	if r.state == stateHits {
+		r.nextHit(false)
+	}
+	if r.state == stateIteration {
+		r.readIteration()
+	}
+}
+
+// readTrailer reads the BLAST output fields following the iterations.
+func (r *OutputReader) readTrailer() {
+	var stat struct {
+		Statistics *Statistics `xml:"Statistics"`
+	}
+	for r.state == stateTrailer {
+		t, ok := r.token()
+		if !ok {
+			break
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "BlastOutput_mbstat" {
+			r.decode(&stat, start)
+		} else {
+			r.skip()
+		}
+	}
+	r.out.MegaStatistics = stat.Statistics
+}
+
+// StreamOutput returns an OutputReader that reads the results of a Get request for the
+// request corresponding to r. It is the responsibility of the caller to close the returned
+// OutputReader.
+func (r *Rid) StreamOutput(p *GetParameters, tool, email string) (*OutputReader, error) {
+	return r.StreamOutputContext(context.Background(), p, tool, email)
+}
+
+// StreamOutputContext is like StreamOutput but uses ctx to cancel the request and the wait imposed by RidPollLimit.
+func (r *Rid) StreamOutputContext(ctx context.Context, p *GetParameters, tool, email string) (*OutputReader, error) {
+	return clientFor(tool, email).StreamOutputContext(ctx, r, p)
+}
+
+// StreamOutput returns an OutputReader that reads the results of a Get request for the
+// request corresponding to r. It is the responsibility of the caller to close the returned
+// OutputReader.
+func (c *Client) StreamOutput(r *Rid, p *GetParameters) (*OutputReader, error) {
+	return c.StreamOutputContext(context.Background(), r, p)
+}
+
+// StreamOutputContext is like StreamOutput but uses ctx to cancel the request and the wait imposed by the
+// Client's PollLimit.
+func (c *Client) StreamOutputContext(ctx context.Context, r *Rid, p *GetParameters) (*OutputReader, error) {
+	v := url.Values{}
+	if r.rid != "" {
+		v["RID"] = []string{r.rid}
+	} else {
+		return nil, ErrNoRidProvided
+	}
+	fillParams("Get", p, v)
+	v["FORMAT_TYPE"] = []string{"XML"}
+	err := c.pollWait(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := c.client().GetContext(ctx, URL, v)
+	if err != nil {
+		return nil, err
+	}
+	or := NewOutputReader(rc)
+	or.rid = r.rid
+	return or, nil
+}
@@ -0,0 +1,168 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blast
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+const streamOutput = `<?xml version="1.0"?>
+<!DOCTYPE BlastOutput PUBLIC "-//NCBI//NCBI BlastOutput/EN" "http://www.ncbi.nlm.nih.gov/dtd/NCBI_BlastOutput.dtd">
+<BlastOutput>
+  <BlastOutput_program>blastn</BlastOutput_program>
+  <BlastOutput_version>BLASTN 2.2.27+</BlastOutput_version>
+  <BlastOutput_db>nr</BlastOutput_db>
+  <BlastOutput_query-ID>Query_1</BlastOutput_query-ID>
+  <BlastOutput_query-def>first</BlastOutput_query-def>
+  <BlastOutput_query-len>32</BlastOutput_query-len>
+  <BlastOutput_param>
+    <Parameters>
+      <Parameters_expect>10</Parameters_expect>
+      <Parameters_gap-open>5</Parameters_gap-open>
+      <Parameters_gap-extend>2</Parameters_gap-extend>
+    </Parameters>
+  </BlastOutput_param>
+  <BlastOutput_iterations>
+    <Iteration>
+      <Iteration_iter-num>1</Iteration_iter-num>
+      <Iteration_query-ID>Query_1</Iteration_query-ID>
+      <Iteration_query-def>first</Iteration_query-def>
+      <Iteration_query-len>32</Iteration_query-len>
+      <Iteration_hits>
+        <Hit>
+          <Hit_num>1</Hit_num>
+          <Hit_id>gi|1</Hit_id>
+          <Hit_def>one</Hit_def>
+          <Hit_accession>A1</Hit_accession>
+          <Hit_len>100</Hit_len>
+          <Hit_hsps>
+            <Hsp>
+              <Hsp_num>1</Hsp_num>
+              <Hsp_bit-score>60.2</Hsp_bit-score>
+              <Hsp_score>32</Hsp_score>
+              <Hsp_evalue>1e-10</Hsp_evalue>
+              <Hsp_query-from>1</Hsp_query-from>
+              <Hsp_query-to>32</Hsp_query-to>
+              <Hsp_hit-from>11</Hsp_hit-from>
+              <Hsp_hit-to>42</Hsp_hit-to>
+              <Hsp_qseq>ACGT</Hsp_qseq>
+              <Hsp_hseq>ACGT</Hsp_hseq>
+            </Hsp>
+          </Hit_hsps>
+        </Hit>
+        <Hit>
+          <Hit_num>2</Hit_num>
+          <Hit_id>gi|2</Hit_id>
+          <Hit_def>two</Hit_def>
+          <Hit_accession>A2</Hit_accession>
+          <Hit_len>200</Hit_len>
+        </Hit>
+      </Iteration_hits>
+      <Iteration_stat>
+        <Statistics>
+          <Statistics_db-num>10</Statistics_db-num>
+          <Statistics_db-len>1000</Statistics_db-len>
+          <Statistics_hsp-len>0</Statistics_hsp-len>
+          <Statistics_eff-space>0</Statistics_eff-space>
+          <Statistics_kappa>0.41</Statistics_kappa>
+          <Statistics_lambda>0.625</Statistics_lambda>
+          <Statistics_entropy>0.78</Statistics_entropy>
+        </Statistics>
+      </Iteration_stat>
+    </Iteration>
+    <Iteration>
+      <Iteration_iter-num>2</Iteration_iter-num>
+      <Iteration_query-ID>Query_2</Iteration_query-ID>
+      <Iteration_query-def>second</Iteration_query-def>
+      <Iteration_query-len>20</Iteration_query-len>
+      <Iteration_message>No hits found</Iteration_message>
+    </Iteration>
+    <Iteration>
+      <Iteration_iter-num>3</Iteration_iter-num>
+      <Iteration_query-ID>Query_3</Iteration_query-ID>
+      <Iteration_hits>
+        <Hit>
+          <Hit_num>1</Hit_num>
+          <Hit_id>gi|3</Hit_id>
+          <Hit_def>three</Hit_def>
+          <Hit_accession>A3</Hit_accession>
+          <Hit_len>300</Hit_len>
+        </Hit>
+      </Iteration_hits>
+    </Iteration>
+  </BlastOutput_iterations>
+</BlastOutput>
+`
+
+func (s *S) TestOutputReader(c *check.C) {
+	var want Output
+	err := xml.Unmarshal([]byte(streamOutput), &want)
+	c.Assert(err, check.Equals, nil)
+
+	r := NewOutputReader(strings.NewReader(streamOutput))
+	got := *r.Output()
+	for r.NextIteration() {
+		var hits []Hit
+		for r.Next() {
+			hits = append(hits, r.Hit())
+		}
+		it := *r.Iteration()
+		it.Hits = hits
+		got.Iterations = append(got.Iterations, it)
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Check(got, check.DeepEquals, want)
+}
+
+func (s *S) TestOutputReaderSkip(c *check.C) {
+	r := NewOutputReader(strings.NewReader(streamOutput))
+	var (
+		iters []int
+		hits  []string
+	)
+	for r.NextIteration() {
+		iters = append(iters, r.Iteration().N)
+		if r.Iteration().N == 1 {
+			// Leave the second hit unread.
+			c.Check(r.Next(), check.Equals, true)
+			hits = append(hits, r.Hit().Id)
+			continue
+		}
+		for r.Next() {
+			hits = append(hits, r.Hit().Id)
+		}
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(iters, check.DeepEquals, []int{1, 2, 3})
+	c.Check(hits, check.DeepEquals, []string{"gi|1", "gi|3"})
+	c.Check(r.Output().Program, check.Equals, "blastn")
+}
+
+func (s *S) TestOutputReaderErr(c *check.C) {
+	cpu := strings.Replace(streamOutput, "No hits found", "CPU usage limit was exceeded", 1)
+	r := NewOutputReader(strings.NewReader(cpu))
+	n := 0
+	for r.NextIteration() {
+		n++
+		for r.Next() {
+		}
+	}
+	c.Check(n, check.Equals, 1)
+	var e *CPULimitError
+	c.Check(errors.As(r.Err(), &e), check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+
+	r = NewOutputReader(strings.NewReader(streamOutput[:strings.Index(streamOutput, "<Hit_num>2")]))
+	for r.NextIteration() {
+		for r.Next() {
+		}
+	}
+	_, ok := r.Err().(*xml.SyntaxError)
+	c.Check(ok, check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+}
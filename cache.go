@@ -0,0 +1,277 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL holds the times to live of responses held by a Cache with a nil
+// TTL, keyed by utility name.
+var DefaultCacheTTL = map[string]time.Duration{
+	"einfo":    24 * time.Hour,
+	"esummary": 7 * 24 * time.Hour,
+	"efetch":   7 * 24 * time.Hour,
+	"elink":    7 * 24 * time.Hour,
+}
+
+// Cache is an on-disk cache of successful responses. Responses are keyed on the
+// request URL and parameters, excluding the tool, email and api_key parameters.
+// Requests that refer to the Entrez history server with the webenv, query_key or
+// usehistory parameters are not cached since their results are session-specific.
+// Responses found to hold an error reported by the service may be removed from the
+// cache with Uncache.
+//
+// A Cache may be shared by Clients and by processes using the same directory.
+type Cache struct {
+	// Dir is the cache directory.
+	Dir string
+
+	// TTL holds the time to live of cached responses
+	// keyed by utility name, for example "esummary",
+	// as given by the Util field of a RequestInfo.
+	// Responses for utilities without a TTL are not
+	// cached. If TTL is nil, DefaultCacheTTL is used.
+	TTL map[string]time.Duration
+
+	// MaxSize is the maximum total size in bytes of
+	// the cached responses. When MaxSize is exceeded,
+	// the least recently used responses are evicted.
+	// If MaxSize is zero, the size is not limited.
+	MaxSize int64
+
+	mu sync.Mutex
+}
+
+// NewCache returns a Cache using the directory dir limited to maxSize bytes.
+func NewCache(dir string, maxSize int64) *Cache {
+	return &Cache{Dir: dir, MaxSize: maxSize}
+}
+
+// cacheMagic prefixes the header line of cache entries.
+const cacheMagic = "ncbi-cache "
+
+// bypass holds parameters that prevent a request being cached.
+var bypass = []string{"webenv", "query_key", "usehistory", "WebEnv"}
+
+// lookup returns the cache key and time to live for a request to ut with the prepared
+// URL u. If the request is not cacheable, the returned key is empty.
+func (c *Cache) lookup(ut Util, u *url.URL) (key string, ttl time.Duration) {
+	v := u.Query()
+	for _, p := range bypass {
+		if _, ok := v[p]; ok {
+			return "", 0
+		}
+	}
+	ttls := c.TTL
+	if ttls == nil {
+		ttls = DefaultCacheTTL
+	}
+	ttl, ok := ttls[utilName(ut, v)]
+	if !ok || ttl <= 0 {
+		return "", 0
+	}
+	for _, p := range []string{"tool", "email", "api_key"} {
+		delete(v, p)
+	}
+	cu := *u
+	cu.Scheme = ""
+	cu.RawQuery = v.Encode() // Encode sorts by key.
+	cu.Fragment = ""
+	sum := sha256.Sum256([]byte(cu.String()))
+	return hex.EncodeToString(sum[:]), ttl
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key)
+}
+
+// get returns a reader for the body of the response held for key if it is younger than ttl.
+func (c *Cache) get(key string, ttl time.Duration) (io.ReadCloser, bool) {
+	p := c.path(key)
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, false
+	}
+	r := bufio.NewReader(f)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, cacheMagic) {
+		f.Close()
+		os.Remove(p)
+		return nil, false
+	}
+	nsec, err := strconv.ParseInt(strings.TrimSpace(line[len(cacheMagic):]), 10, 64)
+	if err != nil || time.Since(time.Unix(0, nsec)) > ttl {
+		f.Close()
+		os.Remove(p)
+		return nil, false
+	}
+	// Record the use for least recently used eviction.
+	now := time.Now()
+	os.Chtimes(p, now, now)
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, true
+}
+
+// put returns a reader that reads from body and stores its content for key when
+// body has been read to EOF.
+func (c *Cache) put(key string, body io.ReadCloser) io.ReadCloser {
+	dir := filepath.Dir(c.path(key))
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return body
+	}
+	tmp, err := ioutil.TempFile(dir, key+".tmp")
+	if err != nil {
+		return body
+	}
+	_, err = fmt.Fprintf(tmp, "%s%d\n", cacheMagic, time.Now().UnixNano())
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return body
+	}
+	return &cacheWriter{ReadCloser: body, cache: c, key: key, tmp: tmp}
+}
+
+// Uncache prevents the response body r, returned by a Client with a Cache, from being
+// stored in the Cache, and removes the stored response if r has already been read to
+// the end. Uncache is used when a response with a 200 OK status is found to hold an
+// error reported by the service. It has no effect if r is not a response body being
+// stored in a Cache.
+func Uncache(r io.Reader) {
+	w, ok := r.(*cacheWriter)
+	if !ok {
+		return
+	}
+	switch {
+	case w.tmp != nil:
+		w.discard()
+	case w.committed:
+		os.Remove(w.cache.path(w.key))
+		w.committed = false
+	}
+}
+
+// cacheWriter is a response body that copies the response into a cache entry.
+type cacheWriter struct {
+	io.ReadCloser
+	cache     *Cache
+	key       string
+	tmp       *os.File
+	committed bool
+}
+
+func (w *cacheWriter) Read(p []byte) (int, error) {
+	n, err := w.ReadCloser.Read(p)
+	if w.tmp != nil && n > 0 {
+		_, werr := w.tmp.Write(p[:n])
+		if werr != nil {
+			w.discard()
+		}
+	}
+	if err == io.EOF && w.tmp != nil {
+		w.commit()
+	}
+	return n, err
+}
+
+func (w *cacheWriter) Close() error {
+	if w.tmp != nil {
+		w.discard()
+	}
+	return w.ReadCloser.Close()
+}
+
+func (w *cacheWriter) discard() {
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+	w.tmp = nil
+}
+
+func (w *cacheWriter) commit() {
+	name := w.tmp.Name()
+	err := w.tmp.Close()
+	w.tmp = nil
+	if err == nil {
+		err = os.Rename(name, w.cache.path(w.key))
+	}
+	if err != nil {
+		os.Remove(name)
+		return
+	}
+	w.committed = true
+	w.cache.evict()
+}
+
+// evict removes the least recently used entries until the total size of the cache
+// is no more than c.MaxSize.
+func (c *Cache) evict() {
+	if c.MaxSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var (
+		entries []entry
+		total   int64
+	)
+	filepath.Walk(c.Dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || strings.Contains(fi.Name(), ".tmp") {
+			return nil
+		}
+		entries = append(entries, entry{path: p, size: fi.Size(), used: fi.ModTime()})
+		total += fi.Size()
+		return nil
+	})
+	if total <= c.MaxSize {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= c.MaxSize {
+			break
+		}
+		if os.Remove(e.path) == nil {
+			total -= e.size
+		}
+	}
+}
+
+// cachedResponse returns a response holding a cached body for a request to u.
+func cachedResponse(body io.ReadCloser, u *url.URL) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"X-Ncbi-Cache": []string{"hit"}},
+		Body:       body,
+		Request:    &http.Request{Method: http.MethodGet, URL: u},
+	}
+}
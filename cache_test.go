@@ -0,0 +1,151 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCache(c *check.C) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		r.ParseForm()
+		if r.Form.Get("id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, "%s %d", r.Form.Get("id"), n)
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	cache := NewCache(c.MkDir(), 0)
+	cache.TTL = map[string]time.Duration{"esummary": time.Hour, "efetch": time.Millisecond}
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Retry:   NoRetry,
+		Cache:   cache,
+	}
+	get := func(util string, v url.Values) string {
+		r, err := cl.Get(Util(base+util), v)
+		if err != nil {
+			return err.Error()
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		c.Check(err, check.Equals, nil)
+		return string(b)
+	}
+
+	for i, t := range []struct {
+		util string
+		v    url.Values
+		want string
+	}{
+		{util: "esummary.fcgi", v: url.Values{"id": {"1"}, "tool": {"a"}}, want: "1 1"},
+		// Volatile parameters are ignored.
+		{util: "esummary.fcgi", v: url.Values{"id": {"1"}, "tool": {"b"}, "api_key": {"key"}}, want: "1 1"},
+		{util: "esummary.fcgi", v: url.Values{"id": {"2"}}, want: "2 2"},
+		// History requests bypass the cache.
+		{util: "esummary.fcgi", v: url.Values{"id": {"1"}, "webenv": {"x"}}, want: "1 3"},
+		{util: "esummary.fcgi", v: url.Values{"id": {"1"}, "webenv": {"x"}}, want: "1 4"},
+		// Utilities without a TTL are not cached.
+		{util: "esearch.fcgi", v: url.Values{"id": {"1"}}, want: "1 5"},
+		{util: "esearch.fcgi", v: url.Values{"id": {"1"}}, want: "1 6"},
+		// Error responses are not cached.
+		{util: "esummary.fcgi", v: url.Values{"id": {"missing"}}, want: "ncbi: 404 Not Found"},
+		{util: "esummary.fcgi", v: url.Values{"id": {"missing"}}, want: "ncbi: 404 Not Found"},
+		{util: "efetch.fcgi", v: url.Values{"id": {"1"}}, want: "1 9"},
+	} {
+		got := get(t.util, t.v)
+		c.Check(strings.HasPrefix(got, t.want), check.Equals, true, check.Commentf("Test %d: got %q want %q", i, got, t.want))
+	}
+
+	// Expired responses are refetched.
+	time.Sleep(5 * time.Millisecond)
+	c.Check(get("efetch.fcgi", url.Values{"id": {"1"}}), check.Equals, "1 10")
+
+	// Responses that are not read to EOF are not cached.
+	r, err := cl.Get(Util(base+"esummary.fcgi"), url.Values{"id": {"3"}})
+	c.Assert(err, check.Equals, nil)
+	r.Close()
+	c.Check(get("esummary.fcgi", url.Values{"id": {"3"}}), check.Equals, "3 12")
+	c.Check(get("esummary.fcgi", url.Values{"id": {"3"}}), check.Equals, "3 12")
+
+	// GetXML reads trailing data so that responses are cached.
+	var x struct{}
+	for i := 0; i < 2; i++ {
+		r, err := cl.GetResponse(Util(base+"esummary.fcgi"), url.Values{"id": {"<a/>"}})
+		c.Assert(err, check.Equals, nil)
+		r.Body.Close()
+		err = cl.GetXML(Util(base+"esummary.fcgi"), url.Values{"id": {"<x/>"}}, &x)
+		c.Check(err, check.Equals, nil)
+	}
+	c.Check(atomic.LoadInt32(&calls), check.Equals, int32(15))
+
+	// Uncached responses are refetched, whether
+	// Uncache is called before or after EOF.
+	for _, id := range []string{"4", "5"} {
+		r, err = cl.Get(Util(base+"esummary.fcgi"), url.Values{"id": {id}})
+		c.Assert(err, check.Equals, nil)
+		if id == "5" {
+			ioutil.ReadAll(r)
+		}
+		Uncache(r)
+		ioutil.ReadAll(r)
+		r.Close()
+		n := atomic.LoadInt32(&calls)
+		c.Check(get("esummary.fcgi", url.Values{"id": {id}}), check.Equals, fmt.Sprintf("%s %d", id, n+1))
+	}
+}
+
+func (s *S) TestCacheEvict(c *check.C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	dir := c.MkDir()
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Cache:   NewCache(dir, 300),
+	}
+	for i := 0; i < 5; i++ {
+		r, err := cl.Get(Util(base+"efetch.fcgi"), url.Values{"id": {fmt.Sprint(i)}})
+		c.Assert(err, check.Equals, nil)
+		ioutil.ReadAll(r)
+		r.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	var (
+		n    int
+		size int64
+	)
+	filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			n++
+			size += fi.Size()
+		}
+		return nil
+	})
+	c.Check(size <= 300, check.Equals, true, check.Commentf("cache size %d", size))
+	c.Check(n, check.Equals, 2)
+}
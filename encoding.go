@@ -0,0 +1,84 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EncodingError is returned when a response is sent with a content encoding that
+// was not requested.
+type EncodingError struct {
+	Encoding string
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("ncbi: unsupported content encoding %q", e.Encoding)
+}
+
+// acceptEncoding requests a gzip compressed response for req, or an uncompressed
+// response if the Client has compression disabled. It returns whether a compressed
+// response was requested.
+func (c *Client) acceptEncoding(req *http.Request) bool {
+	if req.Header.Get("Accept-Encoding") != "" {
+		return false
+	}
+	if c.DisableCompression {
+		// Setting the header prevents the http.Transport
+		// from requesting compression itself.
+		req.Header.Set("Accept-Encoding", "identity")
+		return false
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	return true
+}
+
+// decodeBody replaces the body of resp with a decompressing reader if resp is gzip
+// compressed. The gzip checksum and length are verified when the body is read to EOF.
+// An *EncodingError is returned if resp has any other content encoding.
+func decodeBody(resp *http.Response) error {
+	switch ce := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); ce {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		resp.Body = &gzipBody{body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return nil
+	default:
+		resp.Body.Close()
+		return &EncodingError{Encoding: ce}
+	}
+}
+
+// gzipBody is a response body that decompresses gzip data, reading the gzip header
+// on the first call to Read.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil {
+		if b.err == nil {
+			b.zr, b.err = gzip.NewReader(b.body)
+		}
+		if b.err != nil {
+			return 0, b.err
+		}
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,86 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCompression(c *check.C) {
+	const body = "<eSummaryResult></eSummaryResult>"
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(body))
+	zw.Close()
+	compressed := buf.Bytes()
+
+	var accept string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept-Encoding")
+		switch r.URL.Query().Get("id") {
+		case "corrupt":
+			w.Header().Set("Content-Encoding", "gzip")
+			b := append([]byte(nil), compressed...)
+			b[len(b)-5]++ // Alter the CRC-32.
+			w.Write(b)
+		case "br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(body))
+		default:
+			if accept == "gzip" {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(compressed)
+				return
+			}
+			w.Write([]byte(body))
+		}
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Retry:   NoRetry,
+	}
+	get := func(id string) (string, error) {
+		r, err := cl.Get(Util(base+"esummary.fcgi"), map[string][]string{"id": {id}})
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		return string(b), err
+	}
+
+	for _, disable := range []bool{false, true} {
+		cl.DisableCompression = disable
+		got, err := get("1")
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.Equals, body)
+		if disable {
+			c.Check(accept, check.Equals, "identity")
+		} else {
+			c.Check(accept, check.Equals, "gzip")
+		}
+	}
+	cl.DisableCompression = false
+
+	_, err := get("corrupt")
+	c.Check(err, check.Equals, gzip.ErrChecksum)
+
+	_, err = get("br")
+	var e *EncodingError
+	c.Assert(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(e.Encoding, check.Equals, "br")
+}
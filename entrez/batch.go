@@ -0,0 +1,264 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// DefaultWorkers is the number of concurrent requests made by the batch functions
+// when the Workers field of a Batch is zero.
+const DefaultWorkers = 3
+
+// Batch specifies how the batch functions split a list of ids into requests.
+// Requests with URLs longer than ncbi.GetMethodLimit are sent using POST.
+// Concurrent requests share the Client's Limiter, so the request rate limits
+// are respected.
+type Batch struct {
+	// Size is the maximum number of ids in each
+	// request. If Size is zero, DefaultBatch is used.
+	Size int
+
+	// Workers is the maximum number of concurrent
+	// requests. If Workers is zero, DefaultWorkers
+	// is used. BatchFetch holds no more than one
+	// response per worker in memory.
+	Workers int
+}
+
+// split returns id split into batches.
+func (b *Batch) split(id []int) [][]int {
+	size := DefaultBatch
+	if b != nil && b.Size > 0 {
+		size = b.Size
+	}
+	var batches [][]int
+	for len(id) > size {
+		batches = append(batches, id[:size:size])
+		id = id[size:]
+	}
+	if len(id) != 0 {
+		batches = append(batches, id)
+	}
+	return batches
+}
+
+func (b *Batch) workers() int {
+	if b == nil || b.Workers <= 0 {
+		return DefaultWorkers
+	}
+	return b.Workers
+}
+
+// BatchError is returned by the batch functions when the requests for some batches
+// failed. The results of the successful batches are returned with the BatchError.
+type BatchError struct {
+	// Util is the name of the E-utility, for example "esummary".
+	Util string
+
+	// Batches is the total number of batches.
+	Batches int
+
+	// Failures holds the failed batches in input order.
+	Failures []BatchFailure
+}
+
+// BatchFailure describes a failed batch.
+type BatchFailure struct {
+	// Index is the index of the batch.
+	Index int
+
+	// IDs holds the ids of the batch.
+	IDs []int
+
+	// Err is the error returned for the batch.
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("entrez: %s: %d of %d batches failed: %v", e.Util, len(e.Failures), e.Batches, e.Failures[0].Err)
+}
+
+// Unwrap returns the error of the first failed batch.
+func (e *BatchError) Unwrap() error { return e.Failures[0].Err }
+
+// runBatches calls fn for each batch using up to b.Workers goroutines and returns
+// the resulting errors indexed by batch. Batches are started in order. A worker is
+// not reused until fn returns. Once ctx is done no further batches are started, and
+// those batches fail with ctx.Err().
+func runBatches(ctx context.Context, b *Batch, batches [][]int, fn func(i int, ids []int) error) []error {
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, b.workers())
+	var wg sync.WaitGroup
+	for i, ids := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			for j := i; j < len(batches); j++ {
+				errs[j] = ctx.Err()
+			}
+			break
+		}
+		wg.Add(1)
+		go func(i int, ids []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(i, ids)
+		}(i, ids)
+	}
+	wg.Wait()
+	return errs
+}
+
+// batchErr returns a *BatchError describing the non-nil elements of errs, or nil
+// if there are none.
+func batchErr(util string, batches [][]int, errs []error) error {
+	e := &BatchError{Util: util, Batches: len(batches)}
+	for i, err := range errs {
+		if err != nil {
+			e.Failures = append(e.Failures, BatchFailure{Index: i, IDs: batches[i], Err: err})
+		}
+	}
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// BatchSummary returns a Summary filled with the responses from ESummary queries on
+// the specified id list split into batches as described by b. The documents are held
+// in the order of the batches. If the requests for some batches fail, the documents of
+// the successful batches are returned with a *BatchError.
+func BatchSummary(db string, p *Parameters, b *Batch, tool, email string, id ...int) (*Summary, error) {
+	return BatchSummaryContext(context.Background(), db, p, b, tool, email, id...)
+}
+
+// BatchSummaryContext is like BatchSummary but uses ctx to cancel the requests.
+func BatchSummaryContext(ctx context.Context, db string, p *Parameters, b *Batch, tool, email string, id ...int) (*Summary, error) {
+	return clientFor(tool, email).BatchSummaryContext(ctx, db, p, b, id...)
+}
+
+// BatchSummary returns a Summary filled with the responses from ESummary queries on
+// the specified id list split into batches as described by b. The documents are held
+// in the order of the batches. If the requests for some batches fail, the documents of
+// the successful batches are returned with a *BatchError.
+func (c *Client) BatchSummary(db string, p *Parameters, b *Batch, id ...int) (*Summary, error) {
+	return c.BatchSummaryContext(context.Background(), db, p, b, id...)
+}
+
+// BatchSummaryContext is like BatchSummary but uses ctx to cancel the requests.
+func (c *Client) BatchSummaryContext(ctx context.Context, db string, p *Parameters, b *Batch, id ...int) (*Summary, error) {
+	if len(id) == 0 {
+		return nil, ErrNoIdProvided
+	}
+	batches := b.split(id)
+	results := make([]*Summary, len(batches))
+	errs := runBatches(ctx, b, batches, func(i int, ids []int) error {
+		s, err := c.DoSummaryContext(ctx, db, p, nil, ids...)
+		results[i] = s
+		return err
+	})
+
+	s := Summary{Database: db}
+	if db == "" {
+		s.Database = defaultDb
+	}
+	for _, r := range results {
+		if r != nil {
+			s.Documents = append(s.Documents, r.Documents...)
+			s.Err = append(s.Err, r.Err...)
+		}
+	}
+	return &s, batchErr("esummary", batches, errs)
+}
+
+// BatchFetch writes the streams returned by EFetch requests for the specified id list
+// split into batches as described by b to w in the order of the batches. The number of
+// bytes written is returned. If the requests for some batches fail, the responses for
+// the successful batches are written and a *BatchError is returned. An error writing
+// to w stops further writes and is returned.
+func BatchFetch(w io.Writer, db string, p *Parameters, b *Batch, tool, email string, id ...int) (int64, error) {
+	return BatchFetchContext(context.Background(), w, db, p, b, tool, email, id...)
+}
+
+// BatchFetchContext is like BatchFetch but uses ctx to cancel the requests.
+func BatchFetchContext(ctx context.Context, w io.Writer, db string, p *Parameters, b *Batch, tool, email string, id ...int) (int64, error) {
+	return clientFor(tool, email).BatchFetchContext(ctx, w, db, p, b, id...)
+}
+
+// BatchFetch writes the streams returned by EFetch requests for the specified id list
+// split into batches as described by b to w in the order of the batches. The number of
+// bytes written is returned. If the requests for some batches fail, the responses for
+// the successful batches are written and a *BatchError is returned. An error writing
+// to w stops further writes and is returned.
+func (c *Client) BatchFetch(w io.Writer, db string, p *Parameters, b *Batch, id ...int) (int64, error) {
+	return c.BatchFetchContext(context.Background(), w, db, p, b, id...)
+}
+
+// BatchFetchContext is like BatchFetch but uses ctx to cancel the requests.
+func (c *Client) BatchFetchContext(ctx context.Context, w io.Writer, db string, p *Parameters, b *Batch, id ...int) (int64, error) {
+	if len(id) == 0 {
+		return 0, ErrNoIdProvided
+	}
+	batches := b.split(id)
+
+	// Each response is held by its worker until the
+	// responses for all preceding batches have been
+	// written or failed, so no more than one response
+	// per worker is held.
+	var (
+		mu   sync.Mutex
+		turn = sync.NewCond(&mu)
+		next int
+		n    int64
+		werr error
+	)
+	errs := runBatches(ctx, b, batches, func(i int, ids []int) error {
+		data, err := c.fetchBatch(ctx, db, p, ids)
+		mu.Lock()
+		defer mu.Unlock()
+		for next != i {
+			turn.Wait()
+		}
+		if werr == nil && data != nil {
+			var m int
+			m, werr = w.Write(data)
+			n += int64(m)
+		}
+		next++
+		turn.Broadcast()
+		return err
+	})
+	if werr != nil {
+		return n, werr
+	}
+	return n, batchErr("efetch", batches, errs)
+}
+
+// fetchBatch returns the complete response to an EFetch for ids.
+func (c *Client) fetchBatch(ctx context.Context, db string, p *Parameters, ids []int) ([]byte, error) {
+	r, err := c.FetchContext(ctx, db, p, nil, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	err = fetchError(data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,212 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biogo/ncbi"
+
+	"gopkg.in/check.v1"
+)
+
+// batchServer returns a server that responds to ESummary and EFetch requests and a
+// Client that directs its requests to the server. Requests for batches including
+// the id fail are rejected. Earlier batches are delayed so that responses complete
+// out of order. The returned function returns the maximum number of concurrent
+// requests and the methods used.
+func batchServer(fail int) (*httptest.Server, *Client, func() (int, map[string]int)) {
+	var (
+		mu      sync.Mutex
+		active  int
+		max     int
+		methods = make(map[string]int)
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		active++
+		if active > max {
+			max = active
+		}
+		methods[r.Method]++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+
+		var ids []int
+		for _, v := range r.Form["id"] {
+			for _, f := range strings.Split(v, ",") {
+				id, _ := strconv.Atoi(f)
+				ids = append(ids, id)
+			}
+		}
+		time.Sleep(time.Duration(20-ids[0]) * time.Millisecond)
+		for _, id := range ids {
+			if id == fail {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch path.Base(r.URL.Path) {
+		case "esummary.fcgi":
+			fmt.Fprint(w, "<eSummaryResult>")
+			for _, id := range ids {
+				fmt.Fprintf(w, "<DocSum><Id>%d</Id></DocSum>", id)
+			}
+			fmt.Fprint(w, "</eSummaryResult>")
+		case "efetch.fcgi":
+			for _, id := range ids {
+				fmt.Fprintf(w, ">%d\n", id)
+			}
+		}
+	}))
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+	}}
+	return srv, cl, func() (int, map[string]int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return max, methods
+	}
+}
+
+func (s *S) TestBatchSplit(c *check.C) {
+	for _, t := range []struct {
+		b    *Batch
+		n    int
+		want []int
+	}{
+		{b: nil, n: 1001, want: []int{500, 500, 1}},
+		{b: &Batch{Size: 3}, n: 6, want: []int{3, 3}},
+		{b: &Batch{Size: 3}, n: 7, want: []int{3, 3, 1}},
+		{b: &Batch{Size: 3}, n: 2, want: []int{2}},
+	} {
+		id := make([]int, t.n)
+		for i := range id {
+			id[i] = i
+		}
+		var got []int
+		var all []int
+		for _, b := range t.b.split(id) {
+			got = append(got, len(b))
+			all = append(all, b...)
+		}
+		c.Check(got, check.DeepEquals, t.want)
+		c.Check(all, check.DeepEquals, id)
+	}
+}
+
+func (s *S) TestBatchSummary(c *check.C) {
+	srv, cl, stats := batchServer(7)
+	defer srv.Close()
+
+	id := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	sum, err := cl.BatchSummary("protein", nil, &Batch{Size: 3, Workers: 2}, id...)
+	var be *BatchError
+	c.Assert(errors.As(err, &be), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(be.Util, check.Equals, "esummary")
+	c.Check(be.Batches, check.Equals, 4)
+	c.Assert(be.Failures, check.HasLen, 1)
+	c.Check(be.Failures[0].Index, check.Equals, 2)
+	c.Check(be.Failures[0].IDs, check.DeepEquals, []int{6, 7, 8})
+	var se *ncbi.StatusError
+	c.Check(errors.As(err, &se), check.Equals, true)
+
+	c.Assert(sum, check.NotNil)
+	c.Check(sum.Database, check.Equals, "protein")
+	var got []int
+	for _, d := range sum.Documents {
+		got = append(got, d.Id)
+	}
+	c.Check(got, check.DeepEquals, []int{0, 1, 2, 3, 4, 5, 9})
+	max, _ := stats()
+	c.Check(max <= 2, check.Equals, true, check.Commentf("max concurrent requests: %d", max))
+
+	_, err = cl.BatchSummary("protein", nil, nil)
+	c.Check(err, check.Equals, ErrNoIdProvided)
+}
+
+func (s *S) TestBatchFetch(c *check.C) {
+	defer func(limit int) { ncbi.GetMethodLimit = limit }(ncbi.GetMethodLimit)
+	ncbi.GetMethodLimit = 0
+
+	srv, cl, stats := batchServer(-1)
+	defer srv.Close()
+
+	id := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	var buf bytes.Buffer
+	n, err := cl.BatchFetch(&buf, "protein", &Parameters{RetType: "fasta"}, &Batch{Size: 2, Workers: 5}, id...)
+	c.Assert(err, check.Equals, nil)
+	var want string
+	for _, i := range id {
+		want += fmt.Sprintf(">%d\n", i)
+	}
+	c.Check(buf.String(), check.Equals, want)
+	c.Check(n, check.Equals, int64(len(want)))
+	max, methods := stats()
+	c.Check(max <= 5, check.Equals, true, check.Commentf("max concurrent requests: %d", max))
+	c.Check(methods, check.DeepEquals, map[string]int{"POST": 5})
+
+	srv, cl, _ = batchServer(4)
+	defer srv.Close()
+	buf.Reset()
+	_, err = cl.BatchFetch(&buf, "protein", nil, &Batch{Size: 2}, id...)
+	var be *BatchError
+	c.Assert(errors.As(err, &be), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Assert(be.Failures, check.HasLen, 1)
+	c.Check(be.Failures[0].IDs, check.DeepEquals, []int{4, 5})
+	c.Check(buf.String(), check.Equals, ">0\n>1\n>2\n>3\n>6\n>7\n>8\n>9\n")
+}
+
+// startsWriter records the number of requests started by the time of each write.
+type startsWriter struct {
+	bytes.Buffer
+	stats  func() (int, map[string]int)
+	starts []int
+}
+
+func (w *startsWriter) Write(p []byte) (int, error) {
+	_, methods := w.stats()
+	var n int
+	for _, m := range methods {
+		n += m
+	}
+	w.starts = append(w.starts, n)
+	return w.Buffer.Write(p)
+}
+
+func (s *S) TestBatchFetchWindow(c *check.C) {
+	srv, cl, stats := batchServer(-1)
+	defer srv.Close()
+
+	// The first batch is the slowest, so later batches
+	// complete while it is outstanding.
+	id := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	w := &startsWriter{stats: stats}
+	_, err := cl.BatchFetch(w, "protein", nil, &Batch{Size: 1, Workers: 2}, id...)
+	c.Assert(err, check.Equals, nil)
+	c.Check(w.String(), check.Equals, ">0\n>1\n>2\n>3\n>4\n>5\n>6\n>7\n")
+	for i, n := range w.starts {
+		c.Check(n <= i+2, check.Equals, true, check.Commentf("%d requests started before batch %d was written", n, i))
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/ncbi"
+)
+
+var (
+	// ErrCheckpointMismatch is returned by FetchAll when a checkpoint
+	// file exists for a different download.
+	ErrCheckpointMismatch = errors.New("entrez: checkpoint does not match download")
+
+	// ErrResultChanged is returned by FetchAll when a search that is
+	// re-run after the History server session expired returns a
+	// different number of records or different first or last UIDs.
+	ErrResultChanged = errors.New("entrez: search result changed")
+)
+
+// A BulkFetch describes the retrieval of the records of an ESearch result set to a
+// file by FetchAll.
+type BulkFetch struct {
+	// Database and Query specify the ESearch
+	// query. Params holds the search and EFetch
+	// parameters as described for NewPager.
+	Database string
+	Query    string
+	Params   *Parameters
+
+	// Path is the path of the output file. If the
+	// extension of Path has a registered Compressor,
+	// each batch of records is written as a separately
+	// compressed member of the file.
+	Path string
+
+	// Checkpoint is the path of the checkpoint file.
+	// If Checkpoint is empty, Path with the extension
+	// ".checkpoint" appended is used.
+	Checkpoint string
+
+	// Batch, Retry and Progress are used as described
+	// for the fields of a Pager.
+	Batch    int
+	Retry    *ncbi.Retry
+	Progress func(done, count int)
+}
+
+func (b *BulkFetch) checkpointPath() string {
+	if b.Checkpoint != "" {
+		return b.Checkpoint
+	}
+	return b.Path + ".checkpoint"
+}
+
+// Checkpoint records the progress of a BulkFetch.
+type Checkpoint struct {
+	// Database, Query and Params identify the
+	// download. Params holds the parameters
+	// that determine the result set, its order
+	// and the format of its records.
+	Database string     `json:"db"`
+	Query    string     `json:"query"`
+	Params   url.Values `json:"params,omitempty"`
+
+	// History is the History server session
+	// holding the result set.
+	History History `json:"history"`
+
+	// Count is the number of records in the
+	// result set.
+	Count int `json:"count"`
+
+	// First and Last are the first and last
+	// UIDs of the result set, used to check
+	// that a re-run search has the same result.
+	First int `json:"first"`
+	Last  int `json:"last"`
+
+	// Done holds the ranges of the result set
+	// that have been written to the output.
+	Done []Range `json:"done"`
+
+	// Offset is the size of the output file
+	// after the last completed range.
+	Offset int64 `json:"offset"`
+}
+
+// Range is a half-open range of offsets in a result set.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// next returns the offset of the first record that has not been written.
+func (cp *Checkpoint) next() int {
+	if len(cp.Done) == 0 || cp.Done[0].Start != 0 {
+		return 0
+	}
+	return cp.Done[0].End
+}
+
+// add records that the records in [start, end) have been written.
+func (cp *Checkpoint) add(start, end int) {
+	if n := len(cp.Done); n != 0 && cp.Done[n-1].End == start {
+		cp.Done[n-1].End = end
+		return
+	}
+	cp.Done = append(cp.Done, Range{Start: start, End: end})
+}
+
+// matches returns whether cp describes the download b with the parameters p.
+func (cp *Checkpoint) matches(b *BulkFetch, p *Parameters) bool {
+	return cp.Database == b.Database && cp.Query == b.Query && cp.Params.Encode() == checkpointParams(p).Encode()
+}
+
+// checkpointParams returns the parameters of p recorded in a Checkpoint. The paging
+// parameters and the API key do not affect the download and are not included.
+func checkpointParams(p *Parameters) url.Values {
+	q := *p
+	q.RetStart, q.RetMax = 0, 0
+	q.APIKey = ""
+	v := url.Values{}
+	fillParams(&q, v)
+	return v
+}
+
+// readCheckpoint returns the checkpoint held in the file at path, or nil if there
+// is no file.
+func readCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	err = json.Unmarshal(data, &cp)
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// write atomically writes cp to the file at path.
+func (cp *Checkpoint) write(path string) error {
+	data, err := json.MarshalIndent(cp, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	cerr := tmp.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// FetchAll retrieves the records of the result set of an ESearch query to a file as
+// described by b, recording its progress in a checkpoint file after each batch of
+// records is written. If a checkpoint file for b exists, FetchAll resumes the download
+// from the checkpoint, discarding any output written after it. ErrCheckpointMismatch
+// is returned if the checkpoint was written for a different database, query or
+// parameters. If the History server session recorded in the checkpoint has expired,
+// the search is re-run, and ErrResultChanged is returned if its result set differs
+// from the original. When the download is complete, the checkpoint file is removed and
+// the final Checkpoint is returned. An existing output file without a checkpoint is
+// replaced.
+func FetchAll(b *BulkFetch, tool, email string) (*Checkpoint, error) {
+	return FetchAllContext(context.Background(), b, tool, email)
+}
+
+// FetchAllContext is like FetchAll but uses ctx to cancel the download.
+func FetchAllContext(ctx context.Context, b *BulkFetch, tool, email string) (*Checkpoint, error) {
+	return clientFor(tool, email).FetchAllContext(ctx, b)
+}
+
+// FetchAll retrieves the records of the result set of an ESearch query to a file as
+// described by b, recording its progress in a checkpoint file after each batch of
+// records is written. If a checkpoint file for b exists, FetchAll resumes the download
+// from the checkpoint, discarding any output written after it. ErrCheckpointMismatch
+// is returned if the checkpoint was written for a different database, query or
+// parameters. If the History server session recorded in the checkpoint has expired,
+// the search is re-run, and ErrResultChanged is returned if its result set differs
+// from the original. When the download is complete, the checkpoint file is removed and
+// the final Checkpoint is returned. An existing output file without a checkpoint is
+// replaced.
+func (c *Client) FetchAll(b *BulkFetch) (*Checkpoint, error) {
+	return c.FetchAllContext(context.Background(), b)
+}
+
+// FetchAllContext is like FetchAll but uses ctx to cancel the download.
+func (c *Client) FetchAllContext(ctx context.Context, b *BulkFetch) (*Checkpoint, error) {
+	var p Parameters
+	if b.Params != nil {
+		p = *b.Params
+	}
+	cpPath := b.checkpointPath()
+	cp, err := readCheckpoint(cpPath)
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil && !cp.matches(b, &p) {
+		return nil, ErrCheckpointMismatch
+	}
+
+	var pg *Pager
+	if cp == nil {
+		pg, err = c.NewPagerContext(ctx, b.Database, b.Query, &p)
+		if err != nil {
+			return nil, err
+		}
+		cp = &Checkpoint{
+			Database: b.Database,
+			Query:    b.Query,
+			Params:   checkpointParams(&p),
+			History:  pg.history,
+			Count:    pg.count,
+		}
+		cp.First, cp.Last, err = resultBounds(pg)
+		if err != nil {
+			return nil, err
+		}
+		err = cp.write(cpPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		pg = &Pager{client: c, ctx: ctx, db: b.Database, params: p, history: cp.History, count: cp.Count}
+	}
+	pg.Batch = b.Batch
+	pg.Retry = b.Retry
+	pg.Start = cp.next()
+
+	flag := os.O_RDWR | os.O_CREATE
+	if cp.Offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(b.Path, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Discard any output written after the checkpoint.
+	err = f.Truncate(cp.Offset)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(cp.Offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	compress := Compressors[strings.ToLower(filepath.Ext(b.Path))]
+
+	renewed := false
+	for {
+		start := pg.Start
+		if !pg.NextRecords() {
+			err = pg.Err()
+			if err == nil {
+				break
+			}
+			if renewed || !historyExpired(err) {
+				return cp, err
+			}
+			// Re-establish the History server session
+			// and retry the failed batch.
+			renewed = true
+			err = c.renewHistory(ctx, b, cp, pg)
+			if err != nil {
+				return cp, err
+			}
+			continue
+		}
+		renewed = false
+
+		err = writeBatch(f, compress, pg.Records())
+		if err != nil {
+			return cp, err
+		}
+		cp.Offset, err = f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return cp, err
+		}
+		cp.add(start, pg.Start)
+		err = cp.write(cpPath)
+		if err != nil {
+			return cp, err
+		}
+		if b.Progress != nil {
+			b.Progress(pg.Start, pg.count)
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		return cp, err
+	}
+	return cp, os.Remove(cpPath)
+}
+
+// renewHistory re-runs the search described by b, updating cp and pg with the new
+// History server session. The new result set must have the count and first and last
+// UIDs recorded in cp.
+func (c *Client) renewHistory(ctx context.Context, b *BulkFetch, cp *Checkpoint, pg *Pager) error {
+	fresh, err := c.NewPagerContext(ctx, b.Database, b.Query, &pg.params)
+	if err != nil {
+		return err
+	}
+	if fresh.count != cp.Count {
+		return ErrResultChanged
+	}
+	first, last, err := resultBounds(fresh)
+	if err != nil {
+		return err
+	}
+	if first != cp.First || last != cp.Last {
+		return ErrResultChanged
+	}
+	pg.history = fresh.history
+	pg.err = nil
+	cp.History = fresh.history
+	return cp.write(b.checkpointPath())
+}
+
+// resultBounds returns the first and last UIDs of the result set paged by pg. If the
+// result set is empty, zero is returned for both.
+func resultBounds(pg *Pager) (first, last int, err error) {
+	if pg.count == 0 {
+		return 0, 0, nil
+	}
+	uid := func(i int) (int, error) {
+		p := pg.params
+		p.RetStart, p.RetMax = i, 1
+		p.RetType, p.RetMode = "uilist", "text"
+		data, _, err := pg.fetch(&p)
+		if err != nil {
+			return 0, err
+		}
+		ids, err := parseIDList(data)
+		if err != nil {
+			return 0, err
+		}
+		if len(ids) != 1 {
+			return 0, &Error{Util: "efetch", Msg: "no uid at position " + strconv.Itoa(i)}
+		}
+		return ids[0], nil
+	}
+	first, err = uid(0)
+	if err != nil {
+		return 0, 0, err
+	}
+	last, err = uid(pg.count - 1)
+	return first, last, err
+}
+
+// writeBatch writes data to f, compressing it with compress if it is not nil, and
+// syncs f.
+func writeBatch(f *os.File, compress Compressor, data []byte) error {
+	var err error
+	if compress == nil {
+		_, err = f.Write(data)
+	} else {
+		var w io.WriteCloser
+		w, err = compress(f)
+		if err == nil {
+			_, err = w.Write(data)
+			cerr := w.Close()
+			if err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// historyErrors matches the message reported by the E-utilities when the query_key
+// of a request is not held by its WebEnv, including when the WebEnv has expired.
+var historyErrors = regexp.MustCompile(`Unable to obtain query #\d+`)
+
+// historyExpired returns whether err indicates that the History server session used
+// for a request is no longer available.
+func historyExpired(err error) bool {
+	var e *Error
+	var se *ncbi.StatusError
+	switch {
+	case errors.As(err, &e):
+		return historyErrors.MatchString(e.Msg)
+	case errors.As(err, &se) && se.Code == http.StatusBadRequest:
+		return historyErrors.Match(se.Body)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/biogo/ncbi"
+
+	"gopkg.in/check.v1"
+)
+
+// bulkServer returns a server holding a result set of count records and a Client
+// that directs its requests to the server. The returned function expires the current
+// History server session; the result sets of later searches hold UIDs from base.
+func bulkServer(count int) (*httptest.Server, *Client, func(base int)) {
+	var (
+		mu       sync.Mutex
+		env      int
+		searches int
+		base     int
+		next     int
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		switch path.Base(r.URL.Path) {
+		case "esearch.fcgi":
+			searches++
+			env = searches
+			base = next
+			fmt.Fprintf(w, "<eSearchResult><Count>%d</Count><QueryKey>1</QueryKey><WebEnv>NCID_%d</WebEnv></eSearchResult>", count, env)
+		case "efetch.fcgi":
+			if r.Form.Get("webenv") != fmt.Sprintf("NCID_%d", env) {
+				fmt.Fprint(w, "<eFetchResult><ERROR>Unable to obtain query #1</ERROR></eFetchResult>")
+				return
+			}
+			start, _ := strconv.Atoi(r.Form.Get("retstart"))
+			max, _ := strconv.Atoi(r.Form.Get("retmax"))
+			for id := start; id < start+max && id < count; id++ {
+				if r.Form.Get("rettype") == "uilist" {
+					fmt.Fprintf(w, "%d\n", base+id)
+				} else {
+					fmt.Fprintf(w, ">%d\n", base+id)
+				}
+			}
+		}
+	}))
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+	}}
+	return srv, cl, func(b int) {
+		mu.Lock()
+		env = 0
+		next = b
+		mu.Unlock()
+	}
+}
+
+func (s *S) TestFetchAll(c *check.C) {
+	const count = 10
+	var want string
+	for id := 0; id < count; id++ {
+		want += fmt.Sprintf(">%d\n", id)
+	}
+
+	for _, name := range []string{"out.fa", "out.fa.gz"} {
+		srv, cl, expire := bulkServer(count)
+
+		b := &BulkFetch{
+			Database: "protein",
+			Query:    "hoxa1",
+			Params:   &Parameters{RetType: "fasta"},
+			Path:     filepath.Join(c.MkDir(), name),
+			Batch:    3,
+			Retry:    &ncbi.Retry{MaxAttempts: 1, MinBackoff: time.Millisecond},
+		}
+
+		// Interrupt the download after two batches.
+		ctx, cancel := context.WithCancel(context.Background())
+		b.Progress = func(done, total int) {
+			if done == 6 {
+				cancel()
+			}
+		}
+		cp, err := cl.FetchAllContext(ctx, b)
+		c.Check(err, check.Equals, context.Canceled)
+		c.Assert(cp, check.NotNil)
+		c.Check(cp.Done, check.DeepEquals, []Range{{Start: 0, End: 6}})
+		saved, err := readCheckpoint(b.Path + ".checkpoint")
+		c.Assert(err, check.Equals, nil)
+		c.Check(saved, check.DeepEquals, cp)
+
+		// Simulate a partial write after the checkpoint
+		// and the expiry of the History server session.
+		f, err := os.OpenFile(b.Path, os.O_WRONLY|os.O_APPEND, 0)
+		c.Assert(err, check.Equals, nil)
+		f.Write([]byte(">partial"))
+		f.Close()
+		expire(0)
+
+		var progress []int
+		b.Progress = func(done, total int) { progress = append(progress, done) }
+		b.Batch = 2
+		cp, err = cl.FetchAll(b)
+		c.Assert(err, check.Equals, nil)
+		c.Check(cp.Done, check.DeepEquals, []Range{{Start: 0, End: count}})
+		c.Check(cp.History.WebEnv, check.Equals, "NCID_2")
+		c.Check(progress, check.DeepEquals, []int{8, 10})
+		_, err = os.Stat(b.Path + ".checkpoint")
+		c.Check(os.IsNotExist(err), check.Equals, true)
+
+		f, err = os.Open(b.Path)
+		c.Assert(err, check.Equals, nil)
+		var got []byte
+		if filepath.Ext(name) == ".gz" {
+			zr, err := gzip.NewReader(f)
+			c.Assert(err, check.Equals, nil)
+			got, err = ioutil.ReadAll(zr)
+			c.Check(err, check.Equals, nil)
+		} else {
+			got, err = ioutil.ReadAll(f)
+			c.Check(err, check.Equals, nil)
+		}
+		f.Close()
+		c.Check(string(got), check.Equals, want, check.Commentf("%s", name))
+
+		srv.Close()
+	}
+}
+
+func (s *S) TestFetchAllMismatch(c *check.C) {
+	srv, cl, _ := bulkServer(1)
+	defer srv.Close()
+
+	b := &BulkFetch{
+		Database: "protein",
+		Query:    "hoxa1",
+		Params:   &Parameters{RetType: "fasta", Sort: "relevance"},
+		Path:     filepath.Join(c.MkDir(), "out"),
+	}
+	for i, cp := range []*Checkpoint{
+		{Database: "protein", Query: "hoxb1", Params: checkpointParams(b.Params)},
+		{Database: "protein", Query: "hoxa1", Params: checkpointParams(&Parameters{RetType: "fasta"})},
+		{Database: "protein", Query: "hoxa1", Params: checkpointParams(&Parameters{RetType: "fasta", Sort: "relevance", Field: "title"})},
+		{Database: "protein", Query: "hoxa1", Params: checkpointParams(&Parameters{RetType: "fasta", Sort: "relevance", MinDate: "2020"})},
+		{Database: "protein", Query: "hoxa1", Params: checkpointParams(&Parameters{RetType: "gb", Sort: "relevance"})},
+	} {
+		c.Assert(cp.write(b.Path+".checkpoint"), check.Equals, nil)
+		_, err := cl.FetchAll(b)
+		c.Check(err, check.Equals, ErrCheckpointMismatch, check.Commentf("Test %d", i))
+	}
+
+	// Paging parameters and the API key do not
+	// identify the download.
+	p := *b.Params
+	p.RetMax, p.APIKey = 10, "key"
+	cp := &Checkpoint{Database: "protein", Query: "hoxa1", Params: checkpointParams(b.Params)}
+	c.Check(cp.matches(b, &p), check.Equals, true)
+}
+
+func (s *S) TestFetchAllResultChanged(c *check.C) {
+	srv, cl, expire := bulkServer(10)
+	defer srv.Close()
+
+	b := &BulkFetch{
+		Database: "protein",
+		Query:    "hoxa1",
+		Params:   &Parameters{RetType: "fasta"},
+		Path:     filepath.Join(c.MkDir(), "out.fa"),
+		Batch:    3,
+		Retry:    &ncbi.Retry{MaxAttempts: 1, MinBackoff: time.Millisecond},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Progress = func(done, total int) { cancel() }
+	cp, err := cl.FetchAllContext(ctx, b)
+	c.Check(err, check.Equals, context.Canceled)
+	c.Assert(cp, check.NotNil)
+	c.Check([]int{cp.First, cp.Last}, check.DeepEquals, []int{0, 9})
+
+	// The re-run search has the same count
+	// but different UIDs.
+	expire(100)
+	b.Progress = nil
+	_, err = cl.FetchAll(b)
+	c.Check(err, check.Equals, ErrResultChanged)
+}
+
+func (s *S) TestHistoryExpired(c *check.C) {
+	for _, t := range []struct {
+		err  error
+		want bool
+	}{
+		{err: &Error{Util: "efetch", Msg: "Unable to obtain query #1"}, want: true},
+		{err: fmt.Errorf("page: %w", &Error{Util: "esummary", Msg: "Unable to obtain query #12"}), want: true},
+		{err: &ncbi.StatusError{Code: http.StatusBadRequest, Body: []byte("<ERROR>Unable to obtain query #1</ERROR>")}, want: true},
+		{err: &Error{Util: "efetch", Msg: "Query syntax error: unbalanced parentheses"}},
+		{err: &Error{Util: "efetch", Msg: "Invalid history request"}},
+		{err: &ncbi.StatusError{Code: http.StatusBadRequest, Body: []byte("webenv parameter too long")}},
+		{err: &ncbi.StatusError{Code: http.StatusInternalServerError, Body: []byte("Unable to obtain query #1")}},
+		{err: errors.New("query failed")},
+	} {
+		c.Check(historyExpired(t.err), check.Equals, t.want, check.Commentf("%v", t.err))
+	}
+}
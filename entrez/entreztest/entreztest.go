@@ -0,0 +1,810 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package entreztest provides an in-process fake of the Entrez Utility Programs for
+// testing code that uses the entrez package without network access.
+//
+// A Server emulates EInfo, ESearch, EPost, ESummary, EFetch, ELink, EGQuery, ESpell
+// and ECitMatch over a small set of user supplied records. The Server keeps a history
+// of posted and searched id sets that may be referred to by WebEnv and query_key, and
+// honours retstart and retmax paging parameters. The Client method returns an
+// entrez.Client that directs requests for the entrez package's E-utility URLs to the
+// Server.
+//
+// Searches are simplified. A query is split into words, ignoring field qualifiers,
+// parentheses and the AND operator, and a record matches if each word is one of its
+// Terms, ignoring case.
+package entreztest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/entrez"
+	"github.com/biogo/ncbi/entrez/summary"
+)
+
+// Record is a database record served by a Server.
+type Record struct {
+	// ID is the UID of the record.
+	ID int
+
+	// Terms holds the words that match the record
+	// in ESearch and EGQuery queries.
+	Terms []string
+
+	// Summary holds the items returned for the
+	// record by ESummary.
+	Summary []summary.Item
+
+	// Text holds the text returned for the record
+	// by EFetch, keyed by rettype. The text keyed
+	// by the empty string is returned for other
+	// rettypes.
+	Text map[string]string
+
+	// Links holds the UIDs of records linked from
+	// the record, keyed by database name.
+	Links map[string][]int
+
+	// Citation holds the citation matched to the
+	// record by ECitMatch.
+	Citation *entrez.CitQuery
+}
+
+// Database is a named set of Records.
+type Database struct {
+	Name        string
+	Description string
+	Records     []Record
+}
+
+// Server is a fake E-utilities server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	dbs       map[string]*database
+	errs      map[string]string
+	spellings map[string]string
+	calls     map[string]int
+	envs      map[string][]idSet
+	nextEnv   int
+}
+
+type database struct {
+	Database
+	byID map[int]*Record
+}
+
+// idSet is a set of ids held in a history.
+type idSet struct {
+	db  string
+	ids []int
+}
+
+// NewServer returns a started Server serving the given databases. The Server
+// must be closed after use.
+func NewServer(dbs ...Database) *Server {
+	s := &Server{
+		dbs:       make(map[string]*database),
+		errs:      make(map[string]string),
+		spellings: make(map[string]string),
+		calls:     make(map[string]int),
+		envs:      make(map[string][]idSet),
+	}
+	for _, db := range dbs {
+		d := &database{Database: db, byID: make(map[int]*Record)}
+		for i := range db.Records {
+			d.byID[db.Records[i].ID] = &db.Records[i]
+		}
+		s.dbs[db.Name] = d
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns an entrez.Client that sends requests for the entrez package's
+// E-utility URLs to the Server. Requests made by the Client are not rate limited
+// or retried.
+func (s *Server) Client() *entrez.Client {
+	return &entrez.Client{Client: ncbi.Client{
+		HTTP:    s.Server.Client(),
+		Bases:   map[string]string{entrez.Base: s.URL + "/"},
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+	}}
+}
+
+// SetError causes subsequent requests to the named E-utility, for example
+// "esearch", to be answered with an ERROR element holding msg. ECitMatch
+// requests are answered with a 400 status. If msg is empty, normal responses
+// are restored.
+func (s *Server) SetError(util, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if msg == "" {
+		delete(s.errs, util)
+		return
+	}
+	s.errs[util] = msg
+}
+
+// SetSpelling sets the correction suggested by ESpell for word.
+func (s *Server) SetSpelling(word, correction string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spellings[strings.ToLower(word)] = correction
+}
+
+// Calls returns the number of requests made to the named E-utility.
+func (s *Server) Calls(util string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[util]
+}
+
+// roots holds the root element names of E-utility responses.
+var roots = map[string]string{
+	"einfo":     "eInfoResult",
+	"esearch":   "eSearchResult",
+	"epost":     "ePostResult",
+	"esummary":  "eSummaryResult",
+	"efetch":    "eFetchResult",
+	"elink":     "eLinkResult",
+	"egquery":   "Result",
+	"espell":    "eSpellResult",
+	"ecitmatch": "",
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	util := path.Base(r.URL.Path)
+	util = util[:len(util)-len(path.Ext(util))]
+	root, ok := roots[util]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[util]++
+
+	if msg, ok := s.errs[util]; ok {
+		if util == "ecitmatch" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		writeXML(w, root, func(b *builder) { b.elem("ERROR", msg) })
+		return
+	}
+
+	q := query{r.Form}
+	switch util {
+	case "einfo":
+		s.info(w, q)
+	case "esearch":
+		s.search(w, q)
+	case "epost":
+		s.post(w, q)
+	case "esummary":
+		s.summary(w, q)
+	case "efetch":
+		s.fetch(w, q)
+	case "elink":
+		s.link(w, q)
+	case "egquery":
+		s.global(w, q)
+	case "espell":
+		s.spell(w, q)
+	case "ecitmatch":
+		s.citMatch(w, q)
+	}
+}
+
+func (s *Server) info(w http.ResponseWriter, q query) {
+	name := q.Get("db")
+	if name == "" {
+		writeXML(w, "eInfoResult", func(b *builder) {
+			b.open("DbList")
+			for _, n := range s.names() {
+				b.elem("DbName", n)
+			}
+			b.close("DbList")
+		})
+		return
+	}
+	db, ok := s.dbs[name]
+	if !ok {
+		writeXML(w, "eInfoResult", func(b *builder) { b.elem("ERROR", "Invalid db name specified: "+name) })
+		return
+	}
+	writeXML(w, "eInfoResult", func(b *builder) {
+		b.open("DbInfo")
+		b.elem("DbName", db.Name)
+		b.elem("MenuName", db.Name)
+		b.elem("Description", db.Description)
+		b.elem("Count", strconv.Itoa(len(db.Records)))
+		b.elem("LastUpdate", "2013/01/01 00:00")
+		b.close("DbInfo")
+	})
+}
+
+func (s *Server) search(w http.ResponseWriter, q query) {
+	db, ok := s.db(w, "eSearchResult", q.db())
+	if !ok {
+		return
+	}
+	term := q.Get("term")
+	if term == "" {
+		writeXML(w, "eSearchResult", func(b *builder) { b.elem("ERROR", "Empty term and query_key - nothing todo") })
+		return
+	}
+	ids, notFound := db.search(term)
+	var webEnv string
+	var key int
+	if q.Get("usehistory") == "y" {
+		webEnv, key = s.store(q.Get("webenv"), idSet{db: db.Name, ids: ids})
+	}
+	start, max := q.page(20)
+	writeXML(w, "eSearchResult", func(b *builder) {
+		b.elem("Count", strconv.Itoa(len(ids)))
+		page := paged(ids, start, max)
+		b.elem("RetMax", strconv.Itoa(len(page)))
+		b.elem("RetStart", strconv.Itoa(start))
+		if webEnv != "" {
+			b.elem("QueryKey", strconv.Itoa(key))
+			b.elem("WebEnv", webEnv)
+		}
+		b.open("IdList")
+		for _, id := range page {
+			b.elem("Id", strconv.Itoa(id))
+		}
+		b.close("IdList")
+		b.elem("QueryTranslation", term)
+		if len(notFound) != 0 {
+			b.open("ErrorList")
+			for _, p := range notFound {
+				b.elem("PhraseNotFound", p)
+			}
+			b.close("ErrorList")
+		}
+	})
+}
+
+func (s *Server) post(w http.ResponseWriter, q query) {
+	db, ok := s.db(w, "ePostResult", q.db())
+	if !ok {
+		return
+	}
+	ids, err := q.ids()
+	if err != nil {
+		writeXML(w, "ePostResult", func(b *builder) { b.elem("ERROR", err.Error()) })
+		return
+	}
+	var valid, invalid []int
+	for _, id := range ids {
+		if _, ok := db.byID[id]; ok {
+			valid = append(valid, id)
+		} else {
+			invalid = append(invalid, id)
+		}
+	}
+	writeXML(w, "ePostResult", func(b *builder) {
+		if len(invalid) != 0 {
+			b.open("InvalidIdList")
+			for _, id := range invalid {
+				b.elem("Id", strconv.Itoa(id))
+			}
+			b.close("InvalidIdList")
+		}
+		if len(valid) != 0 {
+			webEnv, key := s.store(q.Get("webenv"), idSet{db: db.Name, ids: valid})
+			b.elem("QueryKey", strconv.Itoa(key))
+			b.elem("WebEnv", webEnv)
+		}
+	})
+}
+
+func (s *Server) summary(w http.ResponseWriter, q query) {
+	db, ids, ok := s.request(w, "eSummaryResult", q)
+	if !ok {
+		return
+	}
+	writeXML(w, "eSummaryResult", func(b *builder) {
+		for i, id := range ids {
+			rec, ok := db.byID[id]
+			if !ok {
+				b.elem("ERROR", fmt.Sprintf("Invalid uid %d at position=%d", id, i))
+				continue
+			}
+			b.open("DocSum")
+			b.elem("Id", strconv.Itoa(id))
+			for _, it := range rec.Summary {
+				fmt.Fprintf(b, "<Item Name=%q Type=%q>", it.Name, it.Type)
+				b.text(it.Value)
+				b.close("Item")
+			}
+			b.close("DocSum")
+		}
+	})
+}
+
+func (s *Server) fetch(w http.ResponseWriter, q query) {
+	db, ids, ok := s.request(w, "eFetchResult", q)
+	if !ok {
+		return
+	}
+	typ := q.Get("rettype")
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	if typ == "uilist" {
+		for _, id := range ids {
+			fmt.Fprintln(w, id)
+		}
+		return
+	}
+	for _, id := range ids {
+		rec, ok := db.byID[id]
+		if !ok {
+			continue
+		}
+		text, ok := rec.Text[typ]
+		if !ok {
+			text = rec.Text[""]
+		}
+		fmt.Fprint(w, text)
+	}
+}
+
+func (s *Server) link(w http.ResponseWriter, q query) {
+	from, ok := s.db(w, "eLinkResult", q.GetDefault("dbfrom", "pubmed"))
+	if !ok {
+		return
+	}
+	to := q.Get("db")
+	if to != "" {
+		if _, ok := s.db(w, "eLinkResult", to); !ok {
+			return
+		}
+	}
+	cmd := q.GetDefault("cmd", "neighbor")
+	if cmd != "neighbor" && cmd != "neighbor_history" {
+		writeXML(w, "eLinkResult", func(b *builder) { b.elem("ERROR", "Command not supported: "+cmd) })
+		return
+	}
+
+	var groups [][]int
+	if q.Get("query_key") != "" {
+		set, err := s.history(q)
+		if err != nil {
+			writeXML(w, "eLinkResult", func(b *builder) { b.elem("ERROR", err.Error()) })
+			return
+		}
+		groups = [][]int{set.ids}
+	} else {
+		for _, v := range q.Form["id"] {
+			ids, err := parseIDs(v)
+			if err != nil {
+				writeXML(w, "eLinkResult", func(b *builder) { b.elem("ERROR", err.Error()) })
+				return
+			}
+			groups = append(groups, ids)
+		}
+	}
+
+	webEnv := q.Get("webenv")
+	writeXML(w, "eLinkResult", func(b *builder) {
+		for _, ids := range groups {
+			b.open("LinkSet")
+			b.elem("DbFrom", from.Name)
+			b.open("IdList")
+			for _, id := range ids {
+				b.elem("Id", strconv.Itoa(id))
+			}
+			b.close("IdList")
+
+			var errs []string
+			links := make(map[string][]int)
+			seen := make(map[string]map[int]bool)
+			for _, id := range ids {
+				rec, ok := from.byID[id]
+				if !ok {
+					errs = append(errs, fmt.Sprintf("Invalid uid %d", id))
+					continue
+				}
+				for db, l := range rec.Links {
+					if to != "" && db != to {
+						continue
+					}
+					if seen[db] == nil {
+						seen[db] = make(map[int]bool)
+					}
+					for _, lid := range l {
+						if !seen[db][lid] {
+							seen[db][lid] = true
+							links[db] = append(links[db], lid)
+						}
+					}
+				}
+			}
+			dbs := make([]string, 0, len(links))
+			for db := range links {
+				dbs = append(dbs, db)
+			}
+			sort.Strings(dbs)
+
+			for _, db := range dbs {
+				name := from.Name + "_" + db
+				if cmd == "neighbor_history" {
+					var key int
+					webEnv, key = s.store(webEnv, idSet{db: db, ids: links[db]})
+					b.open("LinkSetDbHistory")
+					b.elem("DbTo", db)
+					b.elem("LinkName", name)
+					b.elem("QueryKey", strconv.Itoa(key))
+					b.close("LinkSetDbHistory")
+					continue
+				}
+				b.open("LinkSetDb")
+				b.elem("DbTo", db)
+				b.elem("LinkName", name)
+				for _, id := range links[db] {
+					b.open("Link")
+					b.elem("Id", strconv.Itoa(id))
+					b.close("Link")
+				}
+				b.close("LinkSetDb")
+			}
+			if cmd == "neighbor_history" && webEnv != "" {
+				b.elem("WebEnv", webEnv)
+			}
+			for _, e := range errs {
+				b.elem("ERROR", e)
+			}
+			b.close("LinkSet")
+		}
+	})
+}
+
+func (s *Server) global(w http.ResponseWriter, q query) {
+	term := q.Get("term")
+	writeXML(w, "Result", func(b *builder) {
+		b.elem("Term", term)
+		b.open("eGQueryResult")
+		for _, n := range s.names() {
+			ids, _ := s.dbs[n].search(term)
+			b.open("ResultItem")
+			b.elem("DbName", n)
+			b.elem("MenuName", n)
+			b.elem("Count", strconv.Itoa(len(ids)))
+			b.elem("Status", "Ok")
+			b.close("ResultItem")
+		}
+		b.close("eGQueryResult")
+	})
+}
+
+func (s *Server) spell(w http.ResponseWriter, q query) {
+	term := q.Get("term")
+	words := strings.Fields(term)
+	var corrected []string
+	changed := false
+	for _, word := range words {
+		if c, ok := s.spellings[strings.ToLower(word)]; ok {
+			corrected = append(corrected, c)
+			changed = true
+		} else {
+			corrected = append(corrected, word)
+		}
+	}
+	writeXML(w, "eSpellResult", func(b *builder) {
+		b.elem("Database", q.db())
+		b.elem("Query", term)
+		if changed {
+			b.elem("CorrectedQuery", strings.Join(corrected, " "))
+		} else {
+			b.elem("CorrectedQuery", "")
+		}
+		b.open("SpelledQuery")
+		for i, word := range words {
+			if i != 0 {
+				b.elem("Original", " ")
+			}
+			if corrected[i] != word {
+				b.elem("Replaced", corrected[i])
+			} else {
+				b.elem("Original", word)
+			}
+		}
+		b.close("SpelledQuery")
+	})
+}
+
+func (s *Server) citMatch(w http.ResponseWriter, q query) {
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	db := s.dbs["pubmed"]
+	for _, line := range strings.FieldsFunc(q.Get("bdata"), func(r rune) bool { return r == '\r' || r == '\n' }) {
+		f := strings.Split(line, "|")
+		if len(f) < 6 {
+			continue
+		}
+		cit := entrez.CitQuery{
+			JournalTitle: f[0],
+			Year:         f[1],
+			Volume:       f[2],
+			FirstPage:    f[3],
+			AuthorName:   f[4],
+		}
+		result := "NOT_FOUND"
+		if db != nil {
+			for _, rec := range db.Records {
+				if rec.Citation != nil && citMatches(*rec.Citation, cit) {
+					result = strconv.Itoa(rec.ID)
+					break
+				}
+			}
+		}
+		fmt.Fprintf(w, "%s|%s|%s|%s|%s|%s|%s\n", f[0], f[1], f[2], f[3], f[4], f[5], result)
+	}
+}
+
+// citMatches returns whether the non-empty fields of q match those of c.
+func citMatches(c, q entrez.CitQuery) bool {
+	for _, f := range [][2]string{
+		{c.JournalTitle, q.JournalTitle},
+		{c.Year, q.Year},
+		{c.Volume, q.Volume},
+		{c.FirstPage, q.FirstPage},
+		{c.AuthorName, q.AuthorName},
+	} {
+		if f[1] != "" && !strings.EqualFold(f[0], f[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// names returns the sorted names of the Server's databases.
+func (s *Server) names() []string {
+	names := make([]string, 0, len(s.dbs))
+	for n := range s.dbs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// db returns the named database, writing an ERROR response with the given root
+// element if it does not exist.
+func (s *Server) db(w http.ResponseWriter, root, name string) (*database, bool) {
+	db, ok := s.dbs[name]
+	if !ok {
+		writeXML(w, root, func(b *builder) { b.elem("ERROR", "Invalid db name specified: "+name) })
+	}
+	return db, ok
+}
+
+// request returns the database and the page of ids specified by the id or the
+// webenv and query_key parameters of q, writing an ERROR response with the given
+// root element if they are not valid.
+func (s *Server) request(w http.ResponseWriter, root string, q query) (*database, []int, bool) {
+	var (
+		name = q.db()
+		ids  []int
+		err  error
+	)
+	if q.Get("query_key") != "" {
+		var set idSet
+		set, err = s.history(q)
+		name = set.db
+		ids = set.ids
+		if err == nil {
+			start, max := q.page(len(ids))
+			ids = paged(ids, start, max)
+		}
+	} else {
+		ids, err = q.ids()
+	}
+	if err != nil {
+		writeXML(w, root, func(b *builder) { b.elem("ERROR", err.Error()) })
+		return nil, nil, false
+	}
+	db, ok := s.db(w, root, name)
+	return db, ids, ok
+}
+
+// store adds set to the history for webEnv, creating a new history if webEnv is
+// empty or unknown, and returns the WebEnv and query key for set.
+func (s *Server) store(webEnv string, set idSet) (string, int) {
+	if _, ok := s.envs[webEnv]; !ok {
+		s.nextEnv++
+		webEnv = fmt.Sprintf("MCID_entreztest_%d", s.nextEnv)
+	}
+	s.envs[webEnv] = append(s.envs[webEnv], set)
+	return webEnv, len(s.envs[webEnv])
+}
+
+// history returns the id set specified by the webenv and query_key parameters of q.
+func (s *Server) history(q query) (idSet, error) {
+	env, ok := s.envs[q.Get("webenv")]
+	if !ok {
+		return idSet{}, fmt.Errorf("Unable to obtain query #%s", q.Get("query_key"))
+	}
+	key, err := strconv.Atoi(q.Get("query_key"))
+	if err != nil || key < 1 || key > len(env) {
+		return idSet{}, fmt.Errorf("Unable to obtain query #%s", q.Get("query_key"))
+	}
+	return env[key-1], nil
+}
+
+// search returns the ids of records in db matching term and the words of term
+// that match no record.
+func (db *database) search(term string) (ids []int, notFound []string) {
+	var words []string
+	for _, w := range strings.Fields(term) {
+		if i := strings.Index(w, "["); i >= 0 {
+			w = w[:i]
+		}
+		w = strings.Trim(w, `()"`)
+		if w == "" || strings.EqualFold(w, "AND") {
+			continue
+		}
+		found := false
+		for _, rec := range db.Records {
+			if hasTerm(rec, w) {
+				found = true
+				break
+			}
+		}
+		if found {
+			words = append(words, w)
+		} else {
+			notFound = append(notFound, w)
+		}
+	}
+	if len(words) == 0 {
+		return nil, notFound
+	}
+outer:
+	for _, rec := range db.Records {
+		for _, w := range words {
+			if !hasTerm(rec, w) {
+				continue outer
+			}
+		}
+		ids = append(ids, rec.ID)
+	}
+	return ids, notFound
+}
+
+func hasTerm(rec Record, word string) bool {
+	for _, t := range rec.Terms {
+		if strings.EqualFold(t, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// query wraps request parameters.
+type query struct {
+	Form map[string][]string
+}
+
+func (q query) Get(key string) string {
+	v := q.Form[key]
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (q query) GetDefault(key, def string) string {
+	v := q.Get(key)
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// db returns the database parameter, defaulting to pubmed.
+func (q query) db() string {
+	return q.GetDefault("db", "pubmed")
+}
+
+// ids returns the ids specified by the id parameters.
+func (q query) ids() ([]int, error) {
+	var ids []int
+	for _, v := range q.Form["id"] {
+		p, err := parseIDs(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, p...)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("Empty id list - nothing todo")
+	}
+	return ids, nil
+}
+
+// page returns the retstart and retmax parameters with the given default retmax.
+func (q query) page(defaultMax int) (start, max int) {
+	start, _ = strconv.Atoi(q.Get("retstart"))
+	max = defaultMax
+	if v := q.Get("retmax"); v != "" {
+		max, _ = strconv.Atoi(v)
+	}
+	return start, max
+}
+
+func parseIDs(s string) ([]int, error) {
+	var ids []int
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid uid %s", f)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func paged(ids []int, start, max int) []int {
+	if start < 0 {
+		start = 0
+	}
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + max
+	if max < 0 || end > len(ids) {
+		end = len(ids)
+	}
+	return ids[start:end]
+}
+
+// builder accumulates an XML document.
+type builder struct {
+	strings.Builder
+}
+
+func (b *builder) open(name string)  { b.WriteString("<" + name + ">") }
+func (b *builder) close(name string) { b.WriteString("</" + name + ">\n") }
+func (b *builder) text(s string)     { xml.EscapeText(b, []byte(s)) }
+func (b *builder) elem(name, value string) {
+	b.open(name)
+	b.text(value)
+	b.close(name)
+}
+
+// writeXML writes an XML document with the given root element and content
+// written by fn.
+func writeXML(w http.ResponseWriter, root string, fn func(*builder)) {
+	var b builder
+	b.WriteString(xml.Header)
+	b.open(root)
+	b.WriteString("\n")
+	fn(&b)
+	b.close(root)
+	w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+	w.Write([]byte(b.String()))
+}
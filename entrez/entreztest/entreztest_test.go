@@ -0,0 +1,269 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entreztest
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/biogo/ncbi/entrez"
+	"github.com/biogo/ncbi/entrez/summary"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func testDatabases() []Database {
+	return []Database{
+		{
+			Name:        "protein",
+			Description: "Protein sequence record",
+			Records: []Record{
+				{
+					ID:      1,
+					Terms:   []string{"hoxa1", "human"},
+					Summary: []summary.Item{{Name: "Caption", Type: "String", Value: "NP_1"}},
+					Text:    map[string]string{"fasta": ">NP_1\nMDN\n", "": "NP_1\n"},
+					Links:   map[string][]int{"pubmed": {10, 11}},
+				},
+				{
+					ID:      2,
+					Terms:   []string{"hoxa1", "mouse"},
+					Summary: []summary.Item{{Name: "Caption", Type: "String", Value: "NP_2"}},
+					Text:    map[string]string{"fasta": ">NP_2\nMDS\n"},
+					Links:   map[string][]int{"pubmed": {11}},
+				},
+				{
+					ID:    3,
+					Terms: []string{"hoxb1", "human"},
+					Text:  map[string]string{"fasta": ">NP_3\nMSS\n"},
+				},
+			},
+		},
+		{
+			Name: "pubmed",
+			Records: []Record{
+				{ID: 10, Terms: []string{"hoxa1"}, Citation: &entrez.CitQuery{JournalTitle: "proc natl acad sci u s a", Year: "1991", Volume: "88", FirstPage: "3509", AuthorName: "mann bj"}},
+				{ID: 11, Terms: []string{"hox"}},
+			},
+		},
+	}
+}
+
+func (s *S) TestInfo(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+
+	i, err := cl.DoInfo("")
+	c.Assert(err, check.Equals, nil)
+	c.Check(i.DbList, check.DeepEquals, []string{"protein", "pubmed"})
+
+	i, err = cl.DoInfo("protein")
+	c.Assert(err, check.Equals, nil)
+	c.Check(i.DbInfo.DbName, check.Equals, "protein")
+	c.Check(i.DbInfo.Count, check.Equals, 3)
+
+	_, err = cl.DoInfo("nucleotide")
+	var e *entrez.Error
+	c.Check(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+}
+
+func (s *S) TestSearch(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+
+	for i, t := range []struct {
+		query string
+		p     *entrez.Parameters
+		count int
+		ids   []int
+	}{
+		{query: "hoxa1", count: 2, ids: []int{1, 2}},
+		{query: "HOXA1[gene] AND human[orgn]", count: 1, ids: []int{1}},
+		{query: "human", p: &entrez.Parameters{RetStart: 1}, count: 2, ids: []int{3}},
+		{query: "hoxa1", p: &entrez.Parameters{RetMax: 1}, count: 2, ids: []int{1}},
+	} {
+		sr, err := cl.DoSearch("protein", t.query, t.p, nil)
+		c.Assert(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(sr.Count, check.Equals, t.count, check.Commentf("Test %d", i))
+		c.Check(sr.IdList, check.DeepEquals, t.ids, check.Commentf("Test %d", i))
+	}
+
+	cl.Strict = true
+	_, err := cl.DoSearch("protein", "hoxa1 fish", nil, nil)
+	var e *entrez.PartialError
+	c.Check(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+}
+
+func (s *S) TestHistory(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+
+	h := &entrez.History{}
+	sr, err := cl.DoSearch("protein", "human", nil, h)
+	c.Assert(err, check.Equals, nil)
+	c.Check(h.WebEnv, check.Not(check.Equals), "")
+	c.Check(h.QueryKey, check.Equals, 1)
+	c.Check(sr.History, check.Equals, h)
+
+	sum, err := cl.DoSummary("protein", nil, h)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(sum.Documents), check.Equals, 2)
+	c.Check(sum.Documents[0].Items, check.DeepEquals, []summary.Item{{Name: "Caption", Type: "String", Value: "NP_1"}})
+
+	r, err := cl.Fetch("protein", &entrez.Parameters{RetType: "fasta", RetStart: 1, RetMax: 1}, h)
+	c.Assert(err, check.Equals, nil)
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	c.Check(err, check.Equals, nil)
+	c.Check(string(b), check.Equals, ">NP_3\nMSS\n")
+
+	p, err := cl.DoPost("protein", &entrez.History{WebEnv: h.WebEnv}, 2)
+	c.Assert(err, check.Equals, nil)
+	c.Check(p.History.WebEnv, check.Equals, h.WebEnv)
+	c.Check(p.History.QueryKey, check.Equals, 2)
+
+	_, err = cl.DoPost("protein", nil, 2, 7)
+	var ie *entrez.InvalidIDError
+	c.Check(errors.As(err, &ie), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(ie.IDs, check.DeepEquals, []int{7})
+
+	_, err = cl.DoSummary("protein", nil, &entrez.History{WebEnv: h.WebEnv, QueryKey: 5})
+	var e *entrez.Error
+	c.Check(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+}
+
+func (s *S) TestPager(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+
+	pg, err := srv.Client().NewPager("protein", "human", &entrez.Parameters{RetType: "fasta"})
+	c.Assert(err, check.Equals, nil)
+	c.Check(pg.Count(), check.Equals, 2)
+	pg.Batch = 1
+	var ids []int
+	for pg.NextIDs() {
+		ids = append(ids, pg.IDs()...)
+	}
+	c.Check(pg.Err(), check.Equals, nil)
+	c.Check(ids, check.DeepEquals, []int{1, 3})
+
+	pg.Start = 0
+	var recs string
+	for pg.NextRecords() {
+		recs += string(pg.Records())
+	}
+	c.Check(pg.Err(), check.Equals, nil)
+	c.Check(recs, check.Equals, ">NP_1\nMDN\n>NP_3\nMSS\n")
+}
+
+func (s *S) TestSummaryInvalid(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+	cl.Strict = true
+
+	sum, err := cl.DoSummary("protein", nil, nil, 1, 9)
+	c.Check(len(sum.Documents), check.Equals, 1)
+	var e *entrez.PartialError
+	c.Assert(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(e.InvalidIDs, check.DeepEquals, []int{9})
+}
+
+func (s *S) TestLink(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+
+	l, err := cl.DoLink("protein", "pubmed", "", "", nil, nil, []int{1, 2}, []int{2})
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(l.LinkSets), check.Equals, 2)
+	var got [][]int
+	for _, ls := range l.LinkSets {
+		c.Assert(len(ls.Neighbor), check.Equals, 1)
+		c.Check(ls.Neighbor[0].LinkName, check.Equals, "protein_pubmed")
+		var ids []int
+		for _, l := range ls.Neighbor[0].Link {
+			ids = append(ids, l.Id.Id)
+		}
+		got = append(got, ids)
+	}
+	c.Check(got, check.DeepEquals, [][]int{{10, 11}, {11}})
+
+	l, err = cl.DoLink("protein", "pubmed", "neighbor_history", "", nil, nil, []int{1})
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(l.LinkSets), check.Equals, 1)
+	ls := l.LinkSets[0]
+	c.Assert(len(ls.LinkSetDbHistory), check.Equals, 1)
+	c.Assert(ls.WebEnv, check.NotNil)
+	h := &entrez.History{WebEnv: *ls.WebEnv, QueryKey: *ls.LinkSetDbHistory[0].QueryKey}
+	sum, err := cl.DoSummary("", nil, h)
+	c.Assert(err, check.Equals, nil)
+	c.Check(len(sum.Documents), check.Equals, 2)
+}
+
+func (s *S) TestGlobal(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+
+	g, err := srv.Client().DoGlobal("hoxa1")
+	c.Assert(err, check.Equals, nil)
+	c.Check(g.Query, check.Equals, "hoxa1")
+	c.Assert(len(g.Results), check.Equals, 2)
+	c.Check(g.Results[0].Count, check.Equals, 2)
+	c.Check(g.Results[1].Count, check.Equals, 1)
+}
+
+func (s *S) TestSpell(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	srv.SetSpelling("asthmaa", "asthma")
+
+	sp, err := srv.Client().DoSpell("pubmed", "asthmaa treatment")
+	c.Assert(err, check.Equals, nil)
+	c.Check(sp.Corrected, check.Equals, "asthma treatment")
+	var got string
+	for _, r := range sp.Replace {
+		got += r.Type() + ":" + r.String() + ";"
+	}
+	c.Check(got, check.Equals, "Replaced:asthma;Original: ;Original:treatment;")
+}
+
+func (s *S) TestCitMatch(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+
+	res, err := srv.Client().DoCitMatch(map[string]entrez.CitQuery{
+		"found": {JournalTitle: "Proc Natl Acad Sci U S A", Year: "1991", FirstPage: "3509"},
+	})
+	c.Assert(err, check.Equals, nil)
+	c.Check(res, check.DeepEquals, map[string]int{"found": 10})
+}
+
+func (s *S) TestSetError(c *check.C) {
+	srv := NewServer(testDatabases()...)
+	defer srv.Close()
+	cl := srv.Client()
+
+	srv.SetError("esearch", "Search Backend failed")
+	_, err := cl.DoSearch("protein", "hoxa1", nil, nil)
+	var e *entrez.Error
+	c.Assert(errors.As(err, &e), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(e.Msg, check.Equals, "Search Backend failed")
+
+	srv.SetError("esearch", "")
+	_, err = cl.DoSearch("protein", "hoxa1", nil, nil)
+	c.Check(err, check.Equals, nil)
+	c.Check(srv.Calls("esearch"), check.Equals, 2)
+}
@@ -0,0 +1,85 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import "fmt"
+
+// Error is returned when an E-utility response holds an ERROR element. When the
+// response holds partial results they are returned with the Error.
+type Error struct {
+	// Util is the name of the E-utility, for example "esearch".
+	Util string
+
+	// Msg is the content of the ERROR element.
+	Msg string
+}
+
+func (e *Error) Error() string { return "entrez: " + e.Util + ": " + e.Msg }
+
+// InvalidIDError is returned when an E-utility reports that some of the requested
+// ids are not valid for the database. Results for the valid ids are returned with
+// the InvalidIDError.
+type InvalidIDError struct {
+	// Util is the name of the E-utility, for example "epost".
+	Util string
+
+	// IDs holds the invalid ids.
+	IDs []int
+}
+
+func (e *InvalidIDError) Error() string {
+	if len(e.IDs) == 1 {
+		return fmt.Sprintf("entrez: %s: invalid id: %d", e.Util, e.IDs[0])
+	}
+	return fmt.Sprintf("entrez: %s: %d invalid ids: %v", e.Util, len(e.IDs), e.IDs)
+}
+
+// PartialError is returned by a strict Client when an E-utility response holds errors
+// or warnings for parts of a request. The partial result is returned with the
+// PartialError.
+type PartialError struct {
+	// Util is the name of the E-utility, for example "esummary".
+	Util string
+
+	// Errors holds the errors reported for parts of the request.
+	Errors []string
+
+	// Warnings holds the warnings reported for the request.
+	Warnings []string
+
+	// InvalidIDs holds the ids reported as invalid.
+	InvalidIDs []int
+}
+
+func (e *PartialError) Error() string {
+	var msg string
+	switch {
+	case len(e.Errors) != 0:
+		msg = e.Errors[0]
+	case len(e.Warnings) != 0:
+		msg = e.Warnings[0]
+	}
+	return fmt.Sprintf("entrez: %s: %d errors, %d warnings: %s", e.Util, len(e.Errors), len(e.Warnings), msg)
+}
+
+// err returns e if it holds any errors or warnings and nil otherwise.
+func (e *PartialError) err() error {
+	if len(e.Errors) == 0 && len(e.Warnings) == 0 && len(e.InvalidIDs) == 0 {
+		return nil
+	}
+	return e
+}
+
+// prefixed returns a copy of msgs with each message prefixed by prefix.
+func prefixed(prefix string, msgs []string) []string {
+	if len(msgs) == 0 {
+		return nil
+	}
+	p := make([]string, len(msgs))
+	for i, m := range msgs {
+		p[i] = prefix + m
+	}
+	return p
+}
@@ -0,0 +1,227 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/entrez/search"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestErrors(c *check.C) {
+	for i, t := range []struct {
+		status int
+		body   string
+		do     func(*Client) (interface{}, error)
+
+		wantErr     error
+		wantPartial bool
+	}{
+		{
+			body:    "<eInfoResult><ERROR>Invalid db name specified: nope</ERROR></eInfoResult>",
+			do:      func(cl *Client) (interface{}, error) { return cl.DoInfo("nope") },
+			wantErr: &Error{Util: "einfo", Msg: "Invalid db name specified: nope"},
+		},
+		{
+			body:    "<eSearchResult><ERROR>Empty term and query_key - nothing todo</ERROR></eSearchResult>",
+			do:      func(cl *Client) (interface{}, error) { return cl.DoSearch("pubmed", "", nil, nil) },
+			wantErr: &Error{Util: "esearch", Msg: "Empty term and query_key - nothing todo"},
+		},
+		{
+			body: "<ePostResult><InvalidIdList><Id>0</Id></InvalidIdList><QueryKey>1</QueryKey><WebEnv>env</WebEnv></ePostResult>",
+			do: func(cl *Client) (interface{}, error) {
+				return cl.DoPost("pubmed", &History{}, 0, 1)
+			},
+			wantErr:     &InvalidIDError{Util: "epost", IDs: []int{0}},
+			wantPartial: true,
+		},
+		{
+			body: "<eSummaryResult><ERROR>Invalid uid 0 at position=0</ERROR></eSummaryResult>",
+			do: func(cl *Client) (interface{}, error) {
+				return cl.DoSummary("pubmed", nil, nil, 0)
+			},
+			wantErr: &Error{Util: "esummary", Msg: "Invalid uid 0 at position=0"},
+		},
+		{
+			body: "<eSummaryResult><DocSum><Id>1</Id></DocSum><ERROR>Invalid uid 0 at position=1</ERROR></eSummaryResult>",
+			do: func(cl *Client) (interface{}, error) {
+				return cl.DoSummary("pubmed", nil, nil, 1, 0)
+			},
+		},
+		{
+			body:    "<eLinkResult><ERROR>Invalid command</ERROR></eLinkResult>",
+			do:      func(cl *Client) (interface{}, error) { return cl.DoLink("pubmed", "", "nope", "", nil, nil, []int{1}) },
+			wantErr: &Error{Util: "elink", Msg: "Invalid command"},
+		},
+		{
+			body:    "<eSpellResult><Database>pubmed</Database><ERROR>Database not supported</ERROR></eSpellResult>",
+			do:      func(cl *Client) (interface{}, error) { return cl.DoSpell("pubmed", "asthmaa") },
+			wantErr: &Error{Util: "espell", Msg: "Database not supported"},
+		},
+		{
+			status: http.StatusBadRequest,
+			body:   "bad request",
+			do: func(cl *Client) (interface{}, error) {
+				r, err := cl.Fetch("pubmed", nil, nil, 1)
+				c.Check(r, check.IsNil)
+				return nil, err
+			},
+			wantErr: &ncbi.StatusError{Code: http.StatusBadRequest, Status: "400 Bad Request", Body: []byte("bad request")},
+		},
+	} {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t.status != 0 {
+				w.WriteHeader(t.status)
+			}
+			w.Write([]byte(t.body))
+		}))
+		cl := &Client{Client: ncbi.Client{
+			HTTP:    srv.Client(),
+			Bases:   map[string]string{Base: srv.URL + "/"},
+			Limiter: ncbi.NewLimiter(0),
+			Retry:   ncbi.NoRetry,
+		}}
+
+		res, err := t.do(cl)
+		c.Check(err, check.DeepEquals, t.wantErr, check.Commentf("Test %d", i))
+		if t.wantErr == nil || t.wantPartial {
+			c.Check(res, check.NotNil, check.Commentf("Test %d", i))
+		}
+		srv.Close()
+	}
+}
+
+func (s *S) TestStrict(c *check.C) {
+	for i, t := range []struct {
+		body string
+		do   func(*Client) (interface{}, error)
+
+		wantErr error
+	}{
+		{
+			body: `<eSearchResult><Count>1</Count><IdList><Id>1</Id></IdList>
+<ErrorList><PhraseNotFound>asthmaa</PhraseNotFound><FieldNotFound>nofield</FieldNotFound></ErrorList>
+<WarningList><PhraseIgnored>and</PhraseIgnored><OutputMessage>No items found.</OutputMessage></WarningList>
+</eSearchResult>`,
+			do: func(cl *Client) (interface{}, error) { return cl.DoSearch("pubmed", "asthmaa and", nil, nil) },
+			wantErr: &PartialError{
+				Util:     "esearch",
+				Errors:   []string{"phrase not found: asthmaa", "field not found: nofield"},
+				Warnings: []string{"phrase ignored: and", "No items found."},
+			},
+		},
+		{
+			body: "<eSearchResult><Count>1</Count><IdList><Id>1</Id></IdList></eSearchResult>",
+			do:   func(cl *Client) (interface{}, error) { return cl.DoSearch("pubmed", "asthma", nil, nil) },
+		},
+		{
+			body: "<eSummaryResult><DocSum><Id>1</Id></DocSum><ERROR>Invalid uid 0 at position=1</ERROR></eSummaryResult>",
+			do: func(cl *Client) (interface{}, error) {
+				return cl.DoSummary("pubmed", nil, nil, 1, 0)
+			},
+			wantErr: &PartialError{
+				Util:       "esummary",
+				Errors:     []string{"Invalid uid 0 at position=1"},
+				InvalidIDs: []int{0},
+			},
+		},
+		{
+			body: "<eLinkResult><LinkSet><DbFrom>pubmed</DbFrom><ERROR>Invalid uid</ERROR></LinkSet></eLinkResult>",
+			do: func(cl *Client) (interface{}, error) {
+				return cl.DoLink("pubmed", "protein", "", "", nil, nil, []int{0})
+			},
+			wantErr: &PartialError{Util: "elink", Errors: []string{"Invalid uid"}},
+		},
+	} {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(t.body))
+		}))
+		cl := &Client{Client: ncbi.Client{
+			HTTP:    srv.Client(),
+			Bases:   map[string]string{Base: srv.URL + "/"},
+			Limiter: ncbi.NewLimiter(0),
+		}}
+
+		res, err := t.do(cl)
+		c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(res, check.NotNil, check.Commentf("Test %d", i))
+
+		cl.Strict = true
+		res, err = t.do(cl)
+		c.Check(err, check.DeepEquals, t.wantErr, check.Commentf("Test %d", i))
+		c.Check(res, check.NotNil, check.Commentf("Test %d", i))
+		srv.Close()
+	}
+}
+
+func (s *S) TestErrorNotCached(c *check.C) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			w.Write([]byte("<eSummaryResult><ERROR>Unable to obtain query #1</ERROR></eSummaryResult>"))
+			return
+		}
+		w.Write([]byte("<eSummaryResult><DocSum><Id>1</Id></DocSum></eSummaryResult>"))
+	}))
+	defer srv.Close()
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+		Cache:   ncbi.NewCache(c.MkDir(), 0),
+	}}
+
+	_, err := cl.DoSummary("pubmed", nil, nil, 1)
+	c.Check(err, check.DeepEquals, &Error{Util: "esummary", Msg: "Unable to obtain query #1"})
+	for i := 0; i < 2; i++ {
+		s, err := cl.DoSummary("pubmed", nil, nil, 1)
+		c.Check(err, check.Equals, nil)
+		c.Check(s.Documents, check.HasLen, 1)
+	}
+	c.Check(atomic.LoadInt32(&calls), check.Equals, int32(2))
+
+	r, err := cl.StreamSummary("pubmed", nil, nil, 2)
+	c.Assert(err, check.Equals, nil)
+	for r.Next() {
+	}
+	c.Check(r.Err(), check.DeepEquals, &Error{Util: "esummary", Msg: "Unable to obtain query #1"})
+	r.Close()
+	for i := 0; i < 2; i++ {
+		r, err = cl.StreamSummary("pubmed", nil, nil, 2)
+		c.Assert(err, check.Equals, nil)
+		n := 0
+		for r.Next() {
+			n++
+		}
+		c.Check(r.Err(), check.Equals, nil)
+		c.Check(n, check.Equals, 1)
+		r.Close()
+	}
+	c.Check(atomic.LoadInt32(&calls), check.Equals, int32(4))
+}
+
+func (s *S) TestReportsError(c *check.C) {
+	msg := "error"
+	for i, t := range []struct {
+		ok, err errorReporter
+	}{
+		{ok: &Info{}, err: &Info{Err: msg}},
+		{ok: &Search{}, err: &Search{Err: &msg}},
+		{ok: &Search{}, err: &Search{NotFound: &search.NotFound{Phrase: []string{msg}}}},
+		{ok: &Post{}, err: &Post{Err: &msg}},
+		{ok: &Summary{}, err: &Summary{Err: []string{msg}}},
+		{ok: &Link{}, err: &Link{Err: &msg}},
+		{ok: &Spell{}, err: &Spell{Err: msg}},
+	} {
+		c.Check(t.ok.reportsError(), check.Equals, false, check.Commentf("Test %d", i))
+		c.Check(t.err.reportsError(), check.Equals, true, check.Commentf("Test %d", i))
+	}
+}
@@ -25,16 +25,45 @@
 //
 //  email  E-mail address of the E-utility user. Its value must be a string with no internal
 //         spaces, and should be a valid e-mail address.
+//
+// Requests that include an NCBI API key are permitted at a higher rate. The API key is taken
+// from the NCBI_API_KEY environment variable or the APIKey variable, and may be specified for
+// a Client or with the APIKey field of a Parameters.
+//
+// The package level E-utility functions make requests using the ncbi package's default HTTP
+// client. A Client may be used to specify the HTTP client, service base URLs, tool and email
+// parameters, and request Limiter for a set of requests. The Bases and AllowHTTP fields of a
+// Client direct requests to mirrors or local servers, which are not subject to the package
+// level limits. Responses to EInfo, ESummary, EFetch and ELink requests that do not use the
+// history server may be cached on disk between runs by setting the Cache field of a Client;
+// responses holding an ERROR element are not cached.
+// EFetch responses may be written to compressed files with byte counts and checksums by
+// FetchFile. Only gzip compression is provided; zstd and other formats are out of scope
+// for the package and must be registered by the caller in Compressors. The UIDs or records
+// of an ESearch result set may be paged through using the History server with a Pager.
+// FetchAll downloads a result set to a file, recording its progress in a checkpoint file
+// so that an interrupted download may be resumed.
+// Summaries and records for long UID lists may be retrieved with BatchSummary and BatchFetch,
+// which split the list into batches that are requested concurrently within the rate limits.
+//
+// An ERROR element in an E-utility response is returned as an *Error, and ids reported as
+// invalid by EPost are returned as an *InvalidIDError. In both cases any partial result is
+// returned with the error. Failed HTTP requests are reported with the error types defined
+// in the ncbi package. Errors and warnings for parts of a request, such as ids that could
+// not be summarised or search phrases that were not found, are returned as a *PartialError
+// by a Client with Strict set.
 package entrez
 
 import (
 	"bytes"
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"net/url"
+	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -43,6 +72,9 @@ import (
 	"github.com/biogo/ncbi"
 )
 
+// host is the host of the NCBI E-utilities server.
+const host = "eutils.ncbi.nlm.nih.gov"
+
 // The E-utilities default to "pubmed". Some functions mark which db was used because E-utilities
 // don't, so this is needed.
 const defaultDb = "pubmed"
@@ -51,8 +83,33 @@ const defaultDb = "pubmed"
 // limit is mandated by chapter 2 of the E-utilities manual. Limit is exported to allow reuse
 // of http.Requests provided by NewRequest without overrunning the Entrez request limit.
 // Changing the the value of Limit to allow more frequent requests may result in IP blocking
-// by the Entrez servers.
-var Limit = ncbi.NewLimiter(time.Second / 3)
+// by the Entrez servers. Limit is nested within KeyedLimit, so anonymous requests also count
+// toward the limit on requests with an API key.
+//
+// Limit is registered with ncbi.RegisterLimiter as the Limiter for the E-utilities host, and
+// anonymous requests are subject to the Limiter registered for that host. Assigning to Limit
+// has no effect. To share the limit between processes, register a Limiter returned by
+// ncbi.NewFileLimiter for the host with ncbi.RegisterLimiter; a registered Limiter is not
+// nested within KeyedLimit.
+var Limit = ncbi.NewNestedLimiter(KeyedLimit, time.Second/3, 1)
+
+// KeyedLimit is a package level limit on requests that can be sent to the Entrez server
+// with an API key. Requests that include an API key are permitted at a higher rate than
+// anonymous requests. Since a site's requests share a single limit whether or not they
+// include an API key, anonymous requests are also subject to KeyedLimit. Changing the
+// value of KeyedLimit to allow more frequent requests may result in the API key being
+// blocked by the Entrez servers.
+var KeyedLimit = ncbi.NewLimiter(time.Second / 10)
+
+func init() {
+	ncbi.RegisterLimiter(host, Limit)
+}
+
+// APIKey is the NCBI API key sent with E-utility requests made by the package level
+// E-utility functions and by Clients without an API key. APIKey is initialised from
+// the NCBI_API_KEY environment variable. See the E-utilities usage policy at
+// http://www.ncbi.nlm.nih.gov/books/n/helpeutils/chapter2/ for details.
+var APIKey = os.Getenv("NCBI_API_KEY")
 
 var (
 	ErrNoIdProvided = errors.New("entrez: no id provided")
@@ -139,8 +196,90 @@ type unmarshaler interface {
 	Unmarshal(io.Reader) error
 }
 
-func get(ut ncbi.Util, v url.Values, tool, email string, d interface{}) error {
-	return ut.GetXML(v, tool, email, Limit, d)
+// Client is an E-utility client. The E-utility methods of a Client make requests using
+// the embedded ncbi.Client. Requests made by a Client with a nil Limiter are subject to
+// the package level Limit, or KeyedLimit if the request includes an API key, unless they
+// are sent to a host other than the NCBI E-utilities host by the Client's Bases. Those
+// requests are subject only to any Limiter registered for their host.
+type Client struct {
+	ncbi.Client
+
+	// APIKey is the NCBI API key sent with all requests made
+	// by the Client. If APIKey is empty, the package level
+	// APIKey is used. An api_key specified in a Parameters
+	// takes precedence over APIKey.
+	APIKey string
+
+	// Strict specifies that errors and warnings reported
+	// by ESearch, ESummary and ELink for parts of a request
+	// are returned as a *PartialError with the partial
+	// result.
+	Strict bool
+}
+
+// clientFor returns a Client based on ncbi.DefaultClient with the given tool and email,
+// for use by the package level E-utility functions.
+func clientFor(tool, email string) *Client {
+	c := Client{Client: *ncbi.DefaultClient}
+	c.Tool = tool
+	c.Email = email
+	c.Limiter = nil
+	return &c
+}
+
+// client returns the ncbi.Client used to make a request to ut with the parameters in v,
+// adding the Client's API key to v if it is not already present.
+func (c *Client) client(ut ncbi.Util, v url.Values) *ncbi.Client {
+	key := c.APIKey
+	if key == "" {
+		key = APIKey
+	}
+	if key != "" && v.Get("api_key") == "" {
+		v["api_key"] = []string{key}
+	}
+	nc := c.Client
+	if nc.Limiter == nil && isEutils(nc.Resolve(ut)) {
+		if v.Get("api_key") != "" {
+			nc.Limiter = KeyedLimit
+		} else {
+			nc.Limiter = ncbi.LimiterFor(host)
+		}
+	}
+	return &nc
+}
+
+// isEutils returns whether requests to ut are sent to the NCBI E-utilities host.
+func isEutils(ut ncbi.Util) bool {
+	u, err := url.Parse(string(ut))
+	return err == nil && strings.EqualFold(u.Hostname(), host)
+}
+
+// get decodes the response to a request to ut with the parameters in v into d. If the
+// decoded result holds an ERROR element or an ErrorList, the response is not cached.
+func (c *Client) get(ctx context.Context, ut ncbi.Util, v url.Values, d interface{}) error {
+	rc, err := c.client(ut, v).GetContext(ctx, ut, v)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	err = xml.NewDecoder(rc).Decode(d)
+	if err != nil {
+		return err
+	}
+	if e, ok := d.(errorReporter); ok && e.reportsError() {
+		ncbi.Uncache(rc)
+		return nil
+	}
+	// Read any trailing data so that the response may be cached
+	// and the connection reused.
+	_, err = io.Copy(ioutil.Discard, rc)
+	return err
+}
+
+// errorReporter is implemented by E-utility results that may report errors. Responses
+// holding results that report errors are not cached.
+type errorReporter interface {
+	reportsError() bool
 }
 
 // fillParams adds elements to v based on the "param" tag of p if the value is not the
@@ -179,17 +318,33 @@ func fillParams(p *Parameters, v url.Values) {
 // DoInfo returns an Info filled with data obtained from an EInfo query of the specified
 // db or all databases if db is an empty string.
 func DoInfo(db, tool, email string) (*Info, error) {
+	return DoInfoContext(context.Background(), db, tool, email)
+}
+
+// DoInfoContext is like DoInfo but uses ctx to cancel the request.
+func DoInfoContext(ctx context.Context, db, tool, email string) (*Info, error) {
+	return clientFor(tool, email).DoInfoContext(ctx, db)
+}
+
+// DoInfo returns an Info filled with data obtained from an EInfo query of the specified
+// db or all databases if db is an empty string.
+func (c *Client) DoInfo(db string) (*Info, error) {
+	return c.DoInfoContext(context.Background(), db)
+}
+
+// DoInfoContext is like DoInfo but uses ctx to cancel the request.
+func (c *Client) DoInfoContext(ctx context.Context, db string) (*Info, error) {
 	v := url.Values{}
 	if db != "" {
 		v["db"] = []string{db}
 	}
 	i := Info{}
-	err := get(InfoURL, v, tool, email, &i)
+	err := c.get(ctx, InfoURL, v, &i)
 	if err != nil {
 		return nil, err
 	}
 	if i.Err != "" {
-		return &i, errors.New(i.Err)
+		return &i, &Error{Util: "einfo", Msg: i.Err}
 	}
 	return &i, nil
 }
@@ -200,6 +355,25 @@ func DoInfo(db, tool, email string) (*Info, error) {
 // it will be passed to ESearch as the web environment and if h.QueryKey is not zero,
 // it will be passed as the query key.
 func DoSearch(db, query string, p *Parameters, h *History, tool, email string) (*Search, error) {
+	return DoSearchContext(context.Background(), db, query, p, h, tool, email)
+}
+
+// DoSearchContext is like DoSearch but uses ctx to cancel the request.
+func DoSearchContext(ctx context.Context, db, query string, p *Parameters, h *History, tool, email string) (*Search, error) {
+	return clientFor(tool, email).DoSearchContext(ctx, db, query, p, h)
+}
+
+// DoSearch returns a Search filled with data obtained from an ESearch query of the
+// specified db. If h is not nil the search will use the Entrez history server and will
+// be filled with the history results of the ESearch query. If h.WebEnv is not empty,
+// it will be passed to ESearch as the web environment and if h.QueryKey is not zero,
+// it will be passed as the query key.
+func (c *Client) DoSearch(db, query string, p *Parameters, h *History) (*Search, error) {
+	return c.DoSearchContext(context.Background(), db, query, p, h)
+}
+
+// DoSearchContext is like DoSearch but uses ctx to cancel the request.
+func (c *Client) DoSearchContext(ctx context.Context, db, query string, p *Parameters, h *History) (*Search, error) {
 	v := url.Values{}
 	if db != "" {
 		v["db"] = []string{db}
@@ -220,10 +394,16 @@ func DoSearch(db, query string, p *Parameters, h *History, tool, email string) (
 			}
 		}
 	}
-	err := get(SearchURL, v, tool, email, &s)
+	err := c.get(ctx, SearchURL, v, &s)
 	if err != nil {
 		return nil, err
 	}
+	if s.Err != nil {
+		return &s, &Error{Util: "esearch", Msg: *s.Err}
+	}
+	if c.Strict {
+		return &s, s.partialErr()
+	}
 	return &s, nil
 }
 
@@ -231,6 +411,23 @@ func DoSearch(db, query string, p *Parameters, h *History, tool, email string) (
 // id list. If h is not nil, its WebEnv field is passed as the E-utilies webenv parameter,
 // and if h.QueryKey is zero, h will be filled with the history result from the EPost request.
 func DoPost(db, tool, email string, h *History, id ...int) (*Post, error) {
+	return DoPostContext(context.Background(), db, tool, email, h, id...)
+}
+
+// DoPostContext is like DoPost but uses ctx to cancel the request.
+func DoPostContext(ctx context.Context, db, tool, email string, h *History, id ...int) (*Post, error) {
+	return clientFor(tool, email).DoPostContext(ctx, db, h, id...)
+}
+
+// DoPost returns a Post filled with the response from an EPost action on the specified
+// id list. If h is not nil, its WebEnv field is passed as the E-utilies webenv parameter,
+// and if h.QueryKey is zero, h will be filled with the history result from the EPost request.
+func (c *Client) DoPost(db string, h *History, id ...int) (*Post, error) {
+	return c.DoPostContext(context.Background(), db, h, id...)
+}
+
+// DoPostContext is like DoPost but uses ctx to cancel the request.
+func (c *Client) DoPostContext(ctx context.Context, db string, h *History, id ...int) (*Post, error) {
 	if len(id) == 0 {
 		return nil, ErrNoIdProvided
 	}
@@ -248,17 +445,40 @@ func DoPost(db, tool, email string, h *History, id ...int) (*Post, error) {
 	} else if h != nil && h.QueryKey == 0 {
 		p.History = h
 	}
-	err := get(PostURL, v, tool, email, &p)
+	err := c.get(ctx, PostURL, v, &p)
 	if err != nil {
 		return nil, err
 	}
+	if p.Err != nil {
+		return &p, &Error{Util: "epost", Msg: *p.Err}
+	}
+	if len(p.InvalidIds) != 0 {
+		return &p, &InvalidIDError{Util: "epost", IDs: p.InvalidIds}
+	}
 	return &p, nil
 }
 
 // Fetch returns an io.ReadCloser that reads from the stream returned by an EFetch of the
 // the given id list or history. It is the responsibility of the caller to close this if it
-// is not nil. A non-nil error is returned for any http status code other than 200.
+// is not nil. A *ncbi.StatusError is returned for any http status code other than 200.
 func Fetch(db string, p *Parameters, tool, email string, h *History, id ...int) (io.ReadCloser, error) {
+	return FetchContext(context.Background(), db, p, tool, email, h, id...)
+}
+
+// FetchContext is like Fetch but uses ctx to cancel the request.
+func FetchContext(ctx context.Context, db string, p *Parameters, tool, email string, h *History, id ...int) (io.ReadCloser, error) {
+	return clientFor(tool, email).FetchContext(ctx, db, p, h, id...)
+}
+
+// Fetch returns an io.ReadCloser that reads from the stream returned by an EFetch of the
+// the given id list or history. It is the responsibility of the caller to close this if it
+// is not nil. A *ncbi.StatusError is returned for any http status code other than 200.
+func (c *Client) Fetch(db string, p *Parameters, h *History, id ...int) (io.ReadCloser, error) {
+	return c.FetchContext(context.Background(), db, p, h, id...)
+}
+
+// FetchContext is like Fetch but uses ctx to cancel the request.
+func (c *Client) FetchContext(ctx context.Context, db string, p *Parameters, h *History, id ...int) (io.ReadCloser, error) {
 	if len(id) == 0 && h == nil {
 		return nil, ErrNoIdProvided
 	}
@@ -277,20 +497,92 @@ func Fetch(db string, p *Parameters, tool, email string, h *History, id ...int)
 	} else if len(id) == 0 {
 		return nil, ErrNoIdProvided
 	}
-	resp, err := FetchURL.GetResponse(v, tool, email, Limit)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		err = errors.New(resp.Status)
-	}
-	return resp.Body, err
+	return c.client(FetchURL, v).GetContext(ctx, FetchURL, v)
 }
 
 // DoSummary returns a Summary filled with the response from an ESummary query on the specified
 // id list. If h is not nil and its fields are non-zero, its field values are passed to ESummary.
 // DoSummary returns an error if both h is nil and id has length zero.
 func DoSummary(db string, p *Parameters, tool, email string, h *History, id ...int) (*Summary, error) {
+	return DoSummaryContext(context.Background(), db, p, tool, email, h, id...)
+}
+
+// DoSummaryContext is like DoSummary but uses ctx to cancel the request.
+func DoSummaryContext(ctx context.Context, db string, p *Parameters, tool, email string, h *History, id ...int) (*Summary, error) {
+	return clientFor(tool, email).DoSummaryContext(ctx, db, p, h, id...)
+}
+
+// DoSummary returns a Summary filled with the response from an ESummary query on the specified
+// id list. If h is not nil and its fields are non-zero, its field values are passed to ESummary.
+// DoSummary returns an error if both h is nil and id has length zero.
+func (c *Client) DoSummary(db string, p *Parameters, h *History, id ...int) (*Summary, error) {
+	return c.DoSummaryContext(context.Background(), db, p, h, id...)
+}
+
+// DoSummaryContext is like DoSummary but uses ctx to cancel the request.
+func (c *Client) DoSummaryContext(ctx context.Context, db string, p *Parameters, h *History, id ...int) (*Summary, error) {
+	v, err := summaryValues(db, p, h, id)
+	if err != nil {
+		return nil, err
+	}
+	if db == "" {
+		db = defaultDb
+	}
+	s := Summary{Database: db}
+	err = c.get(ctx, SummaryURL, v, &s)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Documents) == 0 && len(s.Err) != 0 {
+		return &s, &Error{Util: "esummary", Msg: strings.Join(s.Err, "; ")}
+	}
+	if c.Strict {
+		return &s, s.partialErr()
+	}
+	return &s, nil
+}
+
+// DoLink returns a Link filled with the response from an ELink action on the specified
+// ids list. If h is not nil and its fields are non-zero, its field values are passed to
+// ESummary. DoSummary returns an error if both h is nil and ids has length zero.
+func DoLink(fromDb, toDb, cmd, query string, p *Parameters, tool, email string, h *History, ids ...[]int) (*Link, error) {
+	return DoLinkContext(context.Background(), fromDb, toDb, cmd, query, p, tool, email, h, ids...)
+}
+
+// DoLinkContext is like DoLink but uses ctx to cancel the request.
+func DoLinkContext(ctx context.Context, fromDb, toDb, cmd, query string, p *Parameters, tool, email string, h *History, ids ...[]int) (*Link, error) {
+	return clientFor(tool, email).DoLinkContext(ctx, fromDb, toDb, cmd, query, p, h, ids...)
+}
+
+// DoLink returns a Link filled with the response from an ELink action on the specified
+// ids list. If h is not nil and its fields are non-zero, its field values are passed to
+// ELink. DoLink returns an error if both h is nil and ids has length zero.
+func (c *Client) DoLink(fromDb, toDb, cmd, query string, p *Parameters, h *History, ids ...[]int) (*Link, error) {
+	return c.DoLinkContext(context.Background(), fromDb, toDb, cmd, query, p, h, ids...)
+}
+
+// DoLinkContext is like DoLink but uses ctx to cancel the request.
+func (c *Client) DoLinkContext(ctx context.Context, fromDb, toDb, cmd, query string, p *Parameters, h *History, ids ...[]int) (*Link, error) {
+	v, err := linkValues(fromDb, toDb, cmd, query, p, h, ids)
+	if err != nil {
+		return nil, err
+	}
+	l := Link{}
+	err = c.get(ctx, LinkURL, v, &l)
+	if err != nil {
+		return nil, err
+	}
+	if l.Err != nil {
+		return &l, &Error{Util: "elink", Msg: *l.Err}
+	}
+	if c.Strict {
+		return &l, l.partialErr()
+	}
+	return &l, nil
+}
+
+// summaryValues returns the parameters for an ESummary request.
+func summaryValues(db string, p *Parameters, h *History, id []int) (url.Values, error) {
 	if len(id) == 0 && h == nil {
 		return nil, ErrNoIdProvided
 	}
@@ -301,8 +593,6 @@ func DoSummary(db string, p *Parameters, tool, email string, h *History, id ...i
 	v := url.Values{"id": []string{strings.Join(ids, ",")}}
 	if db != "" {
 		v["db"] = []string{db}
-	} else {
-		db = defaultDb
 	}
 	fillParams(p, v)
 	if h != nil && h.WebEnv != "" && h.QueryKey != 0 {
@@ -311,18 +601,11 @@ func DoSummary(db string, p *Parameters, tool, email string, h *History, id ...i
 	} else if len(id) == 0 {
 		return nil, ErrNoIdProvided
 	}
-	s := Summary{Database: db}
-	err := get(SummaryURL, v, tool, email, &s)
-	if err != nil {
-		return nil, err
-	}
-	return &s, nil
+	return v, nil
 }
 
-// DoLink returns a Link filled with the response from an ELink action on the specified
-// ids list. If h is not nil and its fields are non-zero, its field values are passed to
-// ESummary. DoSummary returns an error if both h is nil and ids has length zero.
-func DoLink(fromDb, toDb, cmd, query string, p *Parameters, tool, email string, h *History, ids ...[]int) (*Link, error) {
+// linkValues returns the parameters for an ELink request.
+func linkValues(fromDb, toDb, cmd, query string, p *Parameters, h *History, ids [][]int) (url.Values, error) {
 	if len(ids) == 0 && h == nil {
 		return nil, ErrNoIdProvided
 	}
@@ -359,22 +642,32 @@ func DoLink(fromDb, toDb, cmd, query string, p *Parameters, tool, email string,
 	} else if len(ids) == 0 {
 		return nil, ErrNoIdProvided
 	}
-	l := Link{}
-	err := get(LinkURL, v, tool, email, &l)
-	if err != nil {
-		return nil, err
-	}
-	return &l, nil
+	return v, nil
 }
 
 // DoGlobal returns a Global filled with the response from an EGQuery query.
 func DoGlobal(query, tool, email string) (*Global, error) {
+	return DoGlobalContext(context.Background(), query, tool, email)
+}
+
+// DoGlobalContext is like DoGlobal but uses ctx to cancel the request.
+func DoGlobalContext(ctx context.Context, query, tool, email string) (*Global, error) {
+	return clientFor(tool, email).DoGlobalContext(ctx, query)
+}
+
+// DoGlobal returns a Global filled with the response from an EGQuery query.
+func (c *Client) DoGlobal(query string) (*Global, error) {
+	return c.DoGlobalContext(context.Background(), query)
+}
+
+// DoGlobalContext is like DoGlobal but uses ctx to cancel the request.
+func (c *Client) DoGlobalContext(ctx context.Context, query string) (*Global, error) {
 	if query == "" {
 		return nil, ErrNoQuery
 	}
 	v := url.Values{"term": []string{query}}
 	g := Global{}
-	err := get(GlobalURL, v, tool, email, &g)
+	err := c.get(ctx, GlobalURL, v, &g)
 	if err != nil {
 		return nil, err
 	}
@@ -383,6 +676,21 @@ func DoGlobal(query, tool, email string) (*Global, error) {
 
 // DoSpell returns a Spell filled with the response from an ESpell query.
 func DoSpell(db, query string, tool, email string) (*Spell, error) {
+	return DoSpellContext(context.Background(), db, query, tool, email)
+}
+
+// DoSpellContext is like DoSpell but uses ctx to cancel the request.
+func DoSpellContext(ctx context.Context, db, query string, tool, email string) (*Spell, error) {
+	return clientFor(tool, email).DoSpellContext(ctx, db, query)
+}
+
+// DoSpell returns a Spell filled with the response from an ESpell query.
+func (c *Client) DoSpell(db, query string) (*Spell, error) {
+	return c.DoSpellContext(context.Background(), db, query)
+}
+
+// DoSpellContext is like DoSpell but uses ctx to cancel the request.
+func (c *Client) DoSpellContext(ctx context.Context, db, query string) (*Spell, error) {
 	v := url.Values{}
 	if db != "" {
 		v["db"] = []string{db}
@@ -391,10 +699,13 @@ func DoSpell(db, query string, tool, email string) (*Spell, error) {
 		v["term"] = []string{query}
 	}
 	sp := Spell{}
-	err := get(SpellURL, v, tool, email, &sp)
+	err := c.get(ctx, SpellURL, v, &sp)
 	if err != nil {
 		return nil, err
 	}
+	if sp.Err != "" {
+		return &sp, &Error{Util: "espell", Msg: sp.Err}
+	}
 	return &sp, nil
 }
 
@@ -411,6 +722,23 @@ type CitQuery struct {
 // to the citations requested in the query. If email is set, the response will
 // also be sent to that address.
 func DoCitMatch(query map[string]CitQuery, tool, email string) (map[string]int, error) {
+	return DoCitMatchContext(context.Background(), query, tool, email)
+}
+
+// DoCitMatchContext is like DoCitMatch but uses ctx to cancel the request.
+func DoCitMatchContext(ctx context.Context, query map[string]CitQuery, tool, email string) (map[string]int, error) {
+	return clientFor(tool, email).DoCitMatchContext(ctx, query)
+}
+
+// DoCitMatch returns a map[string]int associating keys provided in the query
+// to the citations requested in the query. If the Client's Email is set, the
+// response will also be sent to that address.
+func (c *Client) DoCitMatch(query map[string]CitQuery) (map[string]int, error) {
+	return c.DoCitMatchContext(context.Background(), query)
+}
+
+// DoCitMatchContext is like DoCitMatch but uses ctx to cancel the request.
+func (c *Client) DoCitMatchContext(ctx context.Context, query map[string]CitQuery) (map[string]int, error) {
 	v := url.Values{"db": []string{"pubmed"}, "retmode": []string{"xml"}}
 	if query != nil {
 		var buf bytes.Buffer
@@ -426,7 +754,7 @@ func DoCitMatch(query map[string]CitQuery, tool, email string) (map[string]int,
 		}
 		v["bdata"] = []string{buf.String()}
 	}
-	r, err := CitMatchURL.Get(v, tool, email, Limit)
+	r, err := c.client(CitMatchURL, v).GetContext(ctx, CitMatchURL, v)
 	if err != nil {
 		return nil, err
 	}
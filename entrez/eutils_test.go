@@ -0,0 +1,121 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/ncbitest"
+
+	"gopkg.in/check.v1"
+)
+
+// testServer returns a server responding with a minimal eInfoResult and a Client
+// that directs its requests to the server. The returned function reports the form
+// values of the most recent request.
+func testServer() (*httptest.Server, *Client, func() url.Values) {
+	var (
+		mu   sync.Mutex
+		last url.Values
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		last = r.Form
+		mu.Unlock()
+		w.Write([]byte("<eInfoResult><DbList><DbName>pubmed</DbName></DbList></eInfoResult>"))
+	}))
+	c := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+	}}
+	return srv, c, func() url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+func (s *S) TestAPIKey(c *check.C) {
+	defer func(key string) { APIKey = key }(APIKey)
+
+	srv, cl, last := testServer()
+	defer srv.Close()
+
+	for i, t := range []struct {
+		global string
+		client string
+		param  string
+
+		wantInfo   string
+		wantSearch string
+	}{
+		{},
+		{global: "global", wantInfo: "global", wantSearch: "global"},
+		{global: "global", client: "client", wantInfo: "client", wantSearch: "client"},
+		{client: "client", param: "param", wantInfo: "client", wantSearch: "param"},
+	} {
+		APIKey = t.global
+		cl.APIKey = t.client
+		_, err := cl.DoInfo("")
+		c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(last().Get("api_key"), check.Equals, t.wantInfo, check.Commentf("Test %d", i))
+		c.Check(last().Get("tool"), check.Equals, tool, check.Commentf("Test %d", i))
+
+		_, err = cl.DoSearch("pubmed", "query", &Parameters{APIKey: t.param}, nil)
+		c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(last().Get("api_key"), check.Equals, t.wantSearch, check.Commentf("Test %d", i))
+	}
+}
+
+func (s *S) TestKeyedLimit(c *check.C) {
+	defer func(key string) { APIKey = key }(APIKey)
+
+	APIKey = ""
+	cl := &Client{}
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.Equals, Limit)
+	c.Check(cl.client(InfoURL, url.Values{"api_key": []string{"param"}}).Limiter, check.Equals, KeyedLimit)
+	cl.APIKey = "client"
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.Equals, KeyedLimit)
+	l := ncbi.NewLimiter(0)
+	cl.Limiter = l
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.Equals, l)
+
+	cl.Limiter = nil
+	cl.Bases = map[string]string{Base: "http://localhost/eutils/"}
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.IsNil)
+
+	// Anonymous requests use the registered Limiter.
+	cl = &Client{}
+	ncbi.RegisterLimiter(host, l)
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.Equals, l)
+	ncbi.RegisterLimiter(host, Limit)
+	c.Check(cl.client(InfoURL, url.Values{}).Limiter, check.Equals, Limit)
+}
+
+func (s *S) TestReplay(c *check.C) {
+	rec := ncbitest.NewRecorder("testdata/cassette", ncbitest.Replay)
+	cl := &Client{Client: ncbi.Client{HTTP: rec.Client(), Tool: tool, Limiter: ncbi.NewLimiter(0)}}
+
+	sr, err := cl.DoSearch("protein", "hoxa1", nil, nil)
+	c.Assert(err, check.Equals, nil)
+	c.Check(sr.Count, check.Equals, 2)
+	c.Check(sr.IdList, check.DeepEquals, []int{4504463, 4504465})
+
+	r, err := cl.Fetch("protein", &Parameters{RetType: "fasta", RetMode: "text"}, nil, sr.IdList...)
+	c.Assert(err, check.Equals, nil)
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	c.Check(err, check.Equals, nil)
+	c.Check(bytes.Count(b, []byte{'>'}), check.Equals, 2)
+}
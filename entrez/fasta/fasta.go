@@ -0,0 +1,229 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fasta provides a reader for FASTA records, as returned by EFetch with
+// rettype=fasta for the nucleotide and protein databases.
+//
+// Records may be fetched and read one at a time with Fetch:
+//
+//	r, err := fasta.Fetch("nuccore", nil, tool, email, ids...)
+//	...
+//	defer r.Close()
+//	for r.Next() {
+//		rec := r.Record()
+//		...
+//	}
+//	if r.Err() != nil {
+//		...
+//	}
+//	missing, err := r.Missing()
+//
+// Deflines are parsed into seq-ids and a title, and sequences are checked against
+// the alphabet of the requested database.
+package fasta
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Record is a FASTA record.
+type Record struct {
+	// ID is the first word of the defline.
+	ID string
+
+	// SeqIDs holds the seq-ids given in ID.
+	SeqIDs []SeqID
+
+	// Title is the remainder of the defline.
+	Title string
+
+	Seq []byte
+}
+
+// Accession returns the accession.version of the record, or the empty string if
+// the record has no accession seq-id.
+func (r *Record) Accession() string {
+	for _, id := range r.SeqIDs {
+		if !local[id.Type] && id.Value != "" {
+			return id.Value
+		}
+	}
+	return ""
+}
+
+// GI returns the GenInfo identifier of the record, or zero if the record has no gi
+// seq-id.
+func (r *Record) GI() int {
+	for _, id := range r.SeqIDs {
+		if id.Type == "gi" {
+			gi, err := strconv.Atoi(id.Value)
+			if err == nil {
+				return gi
+			}
+		}
+	}
+	return 0
+}
+
+// SeqID is a seq-id of a FASTA defline, for example gi|1234, ref|NM_000546.6| or
+// lcl|seq1. A defline ID with no type, as used by current NCBI deflines, is held
+// as a SeqID with an empty Type.
+type SeqID struct {
+	Type string
+
+	// Value is the first field following the
+	// type: an accession.version, a gi number
+	// or a local identifier.
+	Value string
+
+	// Name holds any further fields joined by
+	// '|': the locus name of sp and similar ids,
+	// the tag of gnl ids or the chain of pdb ids.
+	Name string
+}
+
+func (id SeqID) String() string {
+	if id.Type == "" {
+		return id.Value
+	}
+	s := id.Type + "|" + id.Value
+	if id.Name != "" || fields(id.Type) > 1 {
+		s += "|" + id.Name
+	}
+	return s
+}
+
+// local holds the seq-id types that do not hold an accession.
+var local = map[string]bool{
+	"gi":  true,
+	"lcl": true,
+	"gnl": true,
+	"bbs": true,
+	"bbm": true,
+	"gim": true,
+	"pat": true,
+}
+
+// fields returns the number of fields following a seq-id type.
+func fields(typ string) int {
+	switch typ {
+	case "gi", "lcl", "bbs", "bbm", "gim":
+		return 1
+	case "pat":
+		return 3
+	}
+	return 2
+}
+
+// parseDefline parses a defline without its leading '>'.
+func parseDefline(line string) (id string, ids []SeqID, title string) {
+	line = strings.TrimSpace(line)
+	id = line
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		id, title = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return id, parseSeqIDs(id), title
+}
+
+// parseSeqIDs parses the seq-ids of a defline ID.
+func parseSeqIDs(id string) []SeqID {
+	if !strings.Contains(id, "|") {
+		return []SeqID{{Value: id}}
+	}
+	var ids []SeqID
+	f := strings.Split(id, "|")
+	for len(f) != 0 {
+		typ := f[0]
+		f = f[1:]
+		n := fields(typ)
+		if n > len(f) {
+			n = len(f)
+		}
+		s := SeqID{Type: typ}
+		if n != 0 {
+			s.Value = f[0]
+			s.Name = strings.Join(f[1:n], "|")
+		}
+		ids = append(ids, s)
+		f = f[n:]
+	}
+	return ids
+}
+
+// Alphabet is a sequence alphabet.
+type Alphabet int
+
+const (
+	// Any accepts any sequence letter.
+	Any Alphabet = iota
+
+	// Nucleotide is the IUPAC nucleotide
+	// alphabet.
+	Nucleotide
+
+	// Protein is the IUPAC amino acid
+	// alphabet including stops.
+	Protein
+)
+
+func (a Alphabet) String() string {
+	switch a {
+	case Nucleotide:
+		return "nucleotide"
+	case Protein:
+		return "protein"
+	}
+	return "any"
+}
+
+// valid returns whether b is a letter of the alphabet, ignoring case. A gap, '-',
+// is a letter of every alphabet.
+func (a Alphabet) valid(b byte) bool {
+	switch {
+	case a == Any, b == '-':
+		return true
+	case a == Protein && b == '*':
+		return true
+	}
+	b &^= 'a' - 'A'
+	if a == Nucleotide {
+		return strings.IndexByte("ACGTURYKMSWBDHVN", b) >= 0
+	}
+	return 'A' <= b && b <= 'Z'
+}
+
+// AlphabetOf returns the alphabet of the sequences held by the named Entrez
+// database. It returns Any for databases that do not hold sequences.
+func AlphabetOf(db string) Alphabet {
+	switch db {
+	case "nuccore", "nucleotide", "nucest", "nucgss", "popset":
+		return Nucleotide
+	case "protein":
+		return Protein
+	}
+	return Any
+}
+
+// AlphabetError is returned by a Reader when a sequence holds a letter that is not
+// in the Reader's alphabet.
+type AlphabetError struct {
+	Line     int
+	ID       string
+	Alphabet Alphabet
+	Letter   byte
+}
+
+func (e *AlphabetError) Error() string {
+	return fmt.Sprintf("fasta: line %d: invalid %v letter %q in %s", e.Line, e.Alphabet, e.Letter, e.ID)
+}
+
+// SyntaxError is returned by a Reader when a stream is malformed.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string { return fmt.Sprintf("fasta: line %d: %s", e.Line, e.Msg) }
@@ -0,0 +1,204 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fasta
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/biogo/ncbi/entrez"
+	"github.com/biogo/ncbi/entrez/entreztest"
+	"github.com/biogo/ncbi/entrez/summary"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestParseDefline(c *check.C) {
+	for _, t := range []struct {
+		in    string
+		id    string
+		ids   []SeqID
+		title string
+		acc   string
+		gi    int
+	}{
+		{
+			in:    "NM_000546.6 Homo sapiens tumor protein p53 (TP53), mRNA",
+			id:    "NM_000546.6",
+			ids:   []SeqID{{Value: "NM_000546.6"}},
+			title: "Homo sapiens tumor protein p53 (TP53), mRNA",
+			acc:   "NM_000546.6",
+		},
+		{
+			in:    "gi|120407068|ref|NM_000546.4| Homo sapiens tumor protein p53",
+			id:    "gi|120407068|ref|NM_000546.4|",
+			ids:   []SeqID{{Type: "gi", Value: "120407068"}, {Type: "ref", Value: "NM_000546.4"}},
+			title: "Homo sapiens tumor protein p53",
+			acc:   "NM_000546.4",
+			gi:    120407068,
+		},
+		{
+			in:  "sp|P04637|P53_HUMAN",
+			id:  "sp|P04637|P53_HUMAN",
+			ids: []SeqID{{Type: "sp", Value: "P04637", Name: "P53_HUMAN"}},
+			acc: "P04637",
+		},
+		{
+			in:    "lcl|NM_000546.6_cds_NP_000537.3_1 [gene=TP53] [protein_id=NP_000537.3]",
+			id:    "lcl|NM_000546.6_cds_NP_000537.3_1",
+			ids:   []SeqID{{Type: "lcl", Value: "NM_000546.6_cds_NP_000537.3_1"}},
+			title: "[gene=TP53] [protein_id=NP_000537.3]",
+		},
+		{
+			in:    "gnl|SRA|SRR001666.1\tpaired",
+			id:    "gnl|SRA|SRR001666.1",
+			ids:   []SeqID{{Type: "gnl", Value: "SRA", Name: "SRR001666.1"}},
+			title: "paired",
+		},
+	} {
+		id, ids, title := parseDefline(t.in)
+		c.Check(id, check.Equals, t.id)
+		c.Check(ids, check.DeepEquals, t.ids)
+		c.Check(title, check.Equals, t.title)
+		rec := Record{SeqIDs: ids}
+		c.Check(rec.Accession(), check.Equals, t.acc)
+		c.Check(rec.GI(), check.Equals, t.gi)
+
+		var parts []string
+		for _, s := range ids {
+			parts = append(parts, s.String())
+		}
+		c.Check(strings.Join(parts, "|"), check.Equals, t.id)
+	}
+}
+
+const records = `>NM_000546.6 Homo sapiens tumor protein p53 (TP53), mRNA
+CTCAAAAGTCTAGAGCCACCGTCCAGGGAGCAGGTAGCTGCTGGGCTCCGGGGACACTTTGCGTTCGGGC
+TGGGAGCGTGCTTTCCACGACGGTGACACGCTTCCCTGGATTG
+
+>gi|2|ref|NM_000002.1| partial
+acgtnNRY-
+
+>NM_000003.1
+`
+
+func (s *S) TestReader(c *check.C) {
+	r := NewReader(strings.NewReader(records), Nucleotide)
+	var recs []*Record
+	for r.Next() {
+		recs = append(recs, r.Record())
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+	c.Assert(recs, check.HasLen, 3)
+	c.Check(recs[0].ID, check.Equals, "NM_000546.6")
+	c.Check(recs[0].Title, check.Equals, "Homo sapiens tumor protein p53 (TP53), mRNA")
+	c.Check(recs[0].Seq, check.HasLen, 113)
+	c.Check(string(recs[0].Seq[len(recs[0].Seq)-6:]), check.Equals, "GGATTG")
+	c.Check(recs[1].GI(), check.Equals, 2)
+	c.Check(string(recs[1].Seq), check.Equals, "acgtnNRY-")
+	c.Check(recs[2].Accession(), check.Equals, "NM_000003.1")
+	c.Check(recs[2].Seq, check.HasLen, 0)
+
+	m, err := r.Missing()
+	c.Check(err, check.Equals, nil)
+	c.Check(m, check.HasLen, 0)
+}
+
+func (s *S) TestReaderErr(c *check.C) {
+	for _, t := range []struct {
+		in   string
+		a    Alphabet
+		want string
+	}{
+		{in: ">NP_000537.3\nMEEPQSDPSV*\n", a: Nucleotide, want: `fasta: line 2: invalid nucleotide letter 'E' in NP_000537.3`},
+		{in: ">NP_000537.3\nMEEPQSDPSV*\n", a: Protein},
+		{in: ">NP_000537.3\nMEEP1\n", a: Protein, want: `fasta: line 2: invalid protein letter '1' in NP_000537.3`},
+		{in: ">NP_000537.3\nMEEP1\n", a: Any},
+		{in: "\nACGT\n", a: Any, want: `fasta: line 2: expected defline`},
+	} {
+		r := NewReader(strings.NewReader(t.in), t.a)
+		for r.Next() {
+		}
+		if t.want == "" {
+			c.Check(r.Err(), check.Equals, nil)
+		} else {
+			c.Check(r.Err(), check.ErrorMatches, t.want)
+		}
+	}
+
+	r := NewReader(strings.NewReader(records), Nucleotide)
+	r.Next()
+	_, err := r.Missing()
+	c.Check(err, check.ErrorMatches, "fasta: Missing called before the end of the records")
+}
+
+func (s *S) TestFetch(c *check.C) {
+	srv := entreztest.NewServer(entreztest.Database{Name: "nuccore", Records: []entreztest.Record{
+		{
+			ID:      1,
+			Summary: []summary.Item{{Name: "AccessionVersion", Value: "NM_000546.6"}},
+			Text: map[string]string{
+				"fasta":        ">NM_000546.6 Homo sapiens tumor protein p53\nACGT\n",
+				"fasta_cds_aa": ">lcl|NM_000546.6_prot_NP_000537.3_1 [gene=TP53]\nMEEPQSDPSV\n",
+			},
+		},
+		{
+			ID:      2,
+			Summary: []summary.Item{{Name: "AccessionVersion", Value: "NM_000002.1"}},
+			Text:    map[string]string{"fasta": ">gi|2|ref|NM_000002.1| partial\nACGT\n"},
+		},
+		{
+			ID:      3,
+			Summary: []summary.Item{{Name: "AccessionVersion", Value: "NM_000003.1"}},
+		},
+	}})
+	defer srv.Close()
+	cl := srv.Client()
+
+	r, err := FetchClient(context.Background(), cl, "nuccore", nil, 1, 2, 3, 4)
+	c.Assert(err, check.Equals, nil)
+	var ids []string
+	for r.Next() {
+		ids = append(ids, r.Record().ID)
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Check(ids, check.DeepEquals, []string{"NM_000546.6", "gi|2|ref|NM_000002.1|"})
+	m, err := r.Missing()
+	c.Check(err, check.Equals, nil)
+	c.Check(m, check.DeepEquals, []int{3, 4})
+	c.Check(srv.Calls("esummary"), check.Equals, 1)
+
+	r, err = FetchClient(context.Background(), cl, "nuccore", nil, 2)
+	c.Assert(err, check.Equals, nil)
+	for r.Next() {
+	}
+	m, err = r.Missing()
+	c.Check(err, check.Equals, nil)
+	c.Check(m, check.HasLen, 0)
+	c.Check(srv.Calls("esummary"), check.Equals, 1)
+
+	r, err = FetchClient(context.Background(), cl, "nuccore", &entrez.Parameters{RetType: "fasta_cds_aa"}, 1)
+	c.Assert(err, check.Equals, nil)
+	c.Check(r.alphabet, check.Equals, Protein)
+	c.Assert(r.Next(), check.Equals, true)
+	c.Check(string(r.Record().Seq), check.Equals, "MEEPQSDPSV")
+	c.Check(r.Next(), check.Equals, false)
+	c.Check(r.Err(), check.Equals, nil)
+	m, err = r.Missing()
+	c.Check(err, check.Equals, nil)
+	c.Check(m, check.HasLen, 0)
+
+	r, err = FetchClient(context.Background(), cl, "nuccore", nil)
+	c.Check(err, check.NotNil)
+	c.Check(r, check.IsNil)
+}
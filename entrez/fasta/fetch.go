@@ -0,0 +1,125 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fasta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/biogo/ncbi/entrez"
+)
+
+// Fetch retrieves the records with the given UIDs from db in FASTA format using
+// EFetch and returns a Reader for the response. The Reader checks sequences against
+// the alphabet of db and records the UIDs for Missing. The RetMode of p is set to
+// text and its RetType is set to fasta unless it names a FASTA format such as
+// fasta_cds_na. Fetch returns an error if id has length zero.
+func Fetch(db string, p *entrez.Parameters, tool, email string, id ...int) (*Reader, error) {
+	return FetchContext(context.Background(), db, p, tool, email, id...)
+}
+
+// FetchContext is like Fetch but uses ctx to cancel the requests made by the
+// function and the Reader.
+func FetchContext(ctx context.Context, db string, p *entrez.Parameters, tool, email string, id ...int) (*Reader, error) {
+	return fetch(ctx, db, p, id,
+		func(p *entrez.Parameters) (io.ReadCloser, error) {
+			return entrez.FetchContext(ctx, db, p, tool, email, nil, id...)
+		},
+		func(ctx context.Context, p *entrez.Parameters, id ...int) (*entrez.Summary, error) {
+			return entrez.DoSummaryContext(ctx, db, p, tool, email, nil, id...)
+		},
+	)
+}
+
+// FetchClient is like FetchContext but makes its requests with c.
+func FetchClient(ctx context.Context, c *entrez.Client, db string, p *entrez.Parameters, id ...int) (*Reader, error) {
+	return fetch(ctx, db, p, id,
+		func(p *entrez.Parameters) (io.ReadCloser, error) {
+			return c.FetchContext(ctx, db, p, nil, id...)
+		},
+		func(ctx context.Context, p *entrez.Parameters, id ...int) (*entrez.Summary, error) {
+			return c.DoSummaryContext(ctx, db, p, nil, id...)
+		},
+	)
+}
+
+func fetch(ctx context.Context, db string, p *entrez.Parameters, id []int,
+	get func(*entrez.Parameters) (io.ReadCloser, error),
+	summarize func(context.Context, *entrez.Parameters, ...int) (*entrez.Summary, error),
+) (*Reader, error) {
+	if len(id) == 0 {
+		return nil, entrez.ErrNoIdProvided
+	}
+	var q entrez.Parameters
+	if p != nil {
+		q = *p
+	}
+	q.RetMode = "text"
+	if !strings.HasPrefix(q.RetType, "fasta") {
+		q.RetType = "fasta"
+	}
+	rc, err := get(&q)
+	if err != nil {
+		return nil, err
+	}
+
+	a := AlphabetOf(db)
+	if q.RetType == "fasta_cds_aa" {
+		a = Protein
+	}
+	r := NewReader(rc, a)
+	r.ctx = ctx
+	r.uids = append([]int(nil), id...)
+	sp := &entrez.Parameters{APIKey: q.APIKey}
+	r.summarize = func(ctx context.Context, id ...int) (*entrez.Summary, error) {
+		return summarize(ctx, sp, id...)
+	}
+	return r, nil
+}
+
+// Missing returns the UIDs requested by Fetch for which no record was read. Records
+// are matched to UIDs by their gi seq-id or, for deflines without one, by accession,
+// using ESummary to find the accessions of the unmatched UIDs. Missing must be called
+// after Next has returned false. It returns nil for a Reader returned by NewReader.
+func (r *Reader) Missing() ([]int, error) {
+	if !r.done {
+		return nil, errors.New("fasta: Missing called before the end of the records")
+	}
+	var missing []int
+	for _, id := range r.uids {
+		if !r.gis[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 || len(r.accs) == 0 || r.summarize == nil {
+		return missing, nil
+	}
+
+	// Errors reported by ESummary for invalid
+	// UIDs are returned with a Summary, and
+	// those UIDs remain missing.
+	sum, err := r.summarize(r.ctx, missing...)
+	if sum == nil {
+		return nil, err
+	}
+	found := make(map[int]bool)
+	for _, doc := range sum.Documents {
+		for _, it := range doc.Items {
+			if (it.Name == "AccessionVersion" || it.Name == "Caption") && r.accs[it.Value] {
+				found[doc.Id] = true
+			}
+		}
+	}
+	n := 0
+	for _, id := range missing {
+		if !found[id] {
+			missing[n] = id
+			n++
+		}
+	}
+	return missing[:n], nil
+}
@@ -0,0 +1,183 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fasta
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/biogo/ncbi/entrez"
+)
+
+// A Reader reads FASTA records one at a time from a stream of records, such as an
+// EFetch response.
+type Reader struct {
+	r        io.Reader
+	br       *bufio.Reader
+	line     int
+	alphabet Alphabet
+
+	// defline is the defline of the next
+	// record, read with the previous one.
+	defline string
+
+	rec  *Record
+	err  error
+	done bool
+
+	// gis and accs hold the identifiers
+	// of the records read, for Missing.
+	gis  map[int]bool
+	accs map[string]bool
+
+	// ctx, uids and summarize are set by
+	// FetchClient.
+	ctx       context.Context
+	uids      []int
+	summarize func(ctx context.Context, id ...int) (*entrez.Summary, error)
+}
+
+// NewReader returns a Reader that reads FASTA records from r, checking sequences
+// against the given alphabet. If r is an io.Closer, it is closed by the Reader's
+// Close method.
+func NewReader(r io.Reader, a Alphabet) *Reader {
+	return &Reader{
+		r:        r,
+		br:       bufio.NewReader(r),
+		alphabet: a,
+		gis:      make(map[int]bool),
+		accs:     make(map[string]bool),
+	}
+}
+
+// Next advances the Reader to the next record, which is then available through the
+// Record method. It returns false when there are no more records or an error occurs.
+func (r *Reader) Next() bool {
+	r.rec = nil
+	if r.done {
+		return false
+	}
+	rec, err := r.record()
+	if err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		r.err = err
+		r.done = true
+		return false
+	}
+	r.rec = rec
+	r.seen(rec)
+	return true
+}
+
+// Record returns the current record.
+func (r *Reader) Record() *Record { return r.rec }
+
+// Err returns the first error encountered by the Reader.
+func (r *Reader) Err() error { return r.err }
+
+// Close closes the underlying stream.
+func (r *Reader) Close() error {
+	r.done = true
+	if c, ok := r.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// readLine returns the next line of the stream without its line ending.
+func (r *Reader) readLine() (string, error) {
+	line, err := r.br.ReadString('\n')
+	if err != nil {
+		if err != io.EOF || line == "" {
+			return "", err
+		}
+	}
+	r.line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// record reads the next record. It returns io.EOF if there are no more records.
+func (r *Reader) record() (*Record, error) {
+	def := r.defline
+	r.defline = ""
+	for def == "" {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, ">") {
+			return nil, &SyntaxError{Line: r.line, Msg: "expected defline"}
+		}
+		def = line
+	}
+
+	rec := &Record{}
+	rec.ID, rec.SeqIDs, rec.Title = parseDefline(def[1:])
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return rec, nil
+			}
+			return nil, err
+		}
+		if strings.HasPrefix(line, ">") {
+			r.defline = line
+			return rec, nil
+		}
+		for i := 0; i < len(line); i++ {
+			b := line[i]
+			if b == ' ' || b == '\t' {
+				continue
+			}
+			if !r.alphabet.valid(b) {
+				return nil, &AlphabetError{Line: r.line, ID: rec.ID, Alphabet: r.alphabet, Letter: b}
+			}
+			rec.Seq = append(rec.Seq, b)
+		}
+	}
+}
+
+// seen records the identifiers of rec.
+func (r *Reader) seen(rec *Record) {
+	if gi := rec.GI(); gi != 0 {
+		r.gis[gi] = true
+	}
+	for _, acc := range accessions(rec) {
+		r.accs[acc] = true
+		if i := strings.LastIndexByte(acc, '.'); i >= 0 {
+			r.accs[acc[:i]] = true
+		}
+	}
+}
+
+// accessions returns the accessions of the record that may identify the requested
+// record. The local ids of coding sequences returned by EFetch with rettype
+// fasta_cds_na or fasta_cds_aa, for example lcl|NM_000546.6_cds_NP_000537.3_1,
+// begin with the accession of the requested record.
+func accessions(rec *Record) []string {
+	var accs []string
+	if acc := rec.Accession(); acc != "" {
+		accs = append(accs, acc)
+	}
+	for _, id := range rec.SeqIDs {
+		if id.Type != "lcl" {
+			continue
+		}
+		for _, sep := range []string{"_cds_", "_prot_"} {
+			if i := strings.Index(id.Value, sep); i > 0 {
+				accs = append(accs, id.Value[:i])
+			}
+		}
+	}
+	return accs
+}
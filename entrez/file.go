@@ -0,0 +1,248 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Compressor returns an io.WriteCloser that writes compressed data to w. Closing the
+// returned writer must flush any buffered data to w without closing w.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// Gzip is a Compressor that writes gzip compressed data.
+func Gzip(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// Compressors holds the Compressors used by NewFileWriter and FetchFile keyed by file
+// name extension. Only gzip is provided by the package, which does not depend on a
+// zstd implementation. Other compression formats, such as zstd for the ".zst"
+// extension, may be registered by the caller.
+var Compressors = map[string]Compressor{
+	".gz": Gzip,
+}
+
+// FileStats describes the data written by a FileWriter.
+type FileStats struct {
+	// Path is the path of the file.
+	Path string
+
+	// Bytes is the number of uncompressed bytes
+	// written and SHA256 is their hex encoded
+	// SHA-256 checksum.
+	Bytes  int64
+	SHA256 string
+
+	// FileBytes is the size of the file and
+	// FileSHA256 is the hex encoded SHA-256
+	// checksum of its contents. For uncompressed
+	// files, they are equal to Bytes and SHA256.
+	FileBytes  int64
+	FileSHA256 string
+}
+
+// A FileWriter writes data to a file, compressing it according to the file's name
+// extension and counting and checksumming the data and the file contents. The file
+// is written to a temporary file in the same directory and is only moved to its
+// final path when the FileWriter is successfully closed.
+type FileWriter struct {
+	path string
+	tmp  *os.File
+
+	file    countingHash
+	data    countingHash
+	compr   io.WriteCloser
+	w       io.Writer
+	err     error
+	closed  bool
+	discard bool
+}
+
+// countingHash is an io.Writer that counts and hashes the bytes written to it.
+type countingHash struct {
+	n int64
+	h hash.Hash
+}
+
+func (c *countingHash) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return c.h.Write(p)
+}
+
+// NewFileWriter returns a FileWriter that writes to the file at path. If the extension
+// of path has a registered Compressor, data written to the FileWriter is compressed.
+// The file is created with the permissions of an existing file at path, or with mode
+// 0666 before the umask is applied.
+func NewFileWriter(path string) (*FileWriter, error) {
+	tmp, err := createTemp(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &FileWriter{
+		path: path,
+		tmp:  tmp,
+		file: countingHash{h: sha256.New()},
+		data: countingHash{h: sha256.New()},
+	}
+	fw := io.MultiWriter(tmp, &w.file)
+	if compress, ok := Compressors[strings.ToLower(filepath.Ext(path))]; ok {
+		w.compr, err = compress(fw)
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, err
+		}
+		fw = w.compr
+	}
+	w.w = io.MultiWriter(fw, &w.data)
+	return w, nil
+}
+
+// createTemp creates a new file in the directory of path to be renamed to path. Unlike
+// ioutil.TempFile, which creates files with mode 0600, the file is created with mode
+// 0666 before the umask is applied, or is given the mode of an existing file at path.
+func createTemp(path string) (*os.File, error) {
+	fi, statErr := os.Stat(path)
+	r := uint32(time.Now().UnixNano()) + uint32(os.Getpid())
+	for i := 0; i < 10000; i++ {
+		r = r*1664525 + 1013904223
+		f, err := os.OpenFile(path+".tmp"+strconv.FormatUint(uint64(r), 10), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if statErr == nil {
+			err = f.Chmod(fi.Mode().Perm())
+			if err != nil {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: path + ".tmp*", Err: os.ErrExist}
+}
+
+// Write writes p to the file.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Abort closes the FileWriter and removes the temporary file without writing
+// the file to its final path.
+func (w *FileWriter) Abort() error {
+	w.discard = true
+	return w.Close()
+}
+
+// Close flushes any compressed data and moves the written file to its final path.
+// If an error occurred while writing, the file is removed and the error is returned.
+func (w *FileWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.compr != nil {
+		err := w.compr.Close()
+		if w.err == nil {
+			w.err = err
+		}
+	}
+	err := w.tmp.Sync()
+	if w.err == nil {
+		w.err = err
+	}
+	err = w.tmp.Close()
+	if w.err == nil {
+		w.err = err
+	}
+	if w.err != nil || w.discard {
+		os.Remove(w.tmp.Name())
+		return w.err
+	}
+	w.err = os.Rename(w.tmp.Name(), w.path)
+	if w.err != nil {
+		os.Remove(w.tmp.Name())
+	}
+	return w.err
+}
+
+// Stats returns the counts and checksums of the data written so far. The file size
+// and checksum of a compressed file are only complete after Close has been called.
+func (w *FileWriter) Stats() FileStats {
+	return FileStats{
+		Path:       w.path,
+		Bytes:      w.data.n,
+		SHA256:     hex.EncodeToString(w.data.h.Sum(nil)),
+		FileBytes:  w.file.n,
+		FileSHA256: hex.EncodeToString(w.file.h.Sum(nil)),
+	}
+}
+
+// FetchFile writes the stream returned by an EFetch of the given id list or history to
+// the file at path, compressing the data according to the extension of path as described
+// for NewFileWriter. The file is only created if the complete response is received. The
+// counts and checksums of the data written are returned.
+func FetchFile(path, db string, p *Parameters, tool, email string, h *History, id ...int) (*FileStats, error) {
+	return FetchFileContext(context.Background(), path, db, p, tool, email, h, id...)
+}
+
+// FetchFileContext is like FetchFile but uses ctx to cancel the request.
+func FetchFileContext(ctx context.Context, path, db string, p *Parameters, tool, email string, h *History, id ...int) (*FileStats, error) {
+	return clientFor(tool, email).FetchFileContext(ctx, path, db, p, h, id...)
+}
+
+// FetchFile writes the stream returned by an EFetch of the given id list or history to
+// the file at path, compressing the data according to the extension of path as described
+// for NewFileWriter. The file is only created if the complete response is received. The
+// counts and checksums of the data written are returned.
+func (c *Client) FetchFile(path, db string, p *Parameters, h *History, id ...int) (*FileStats, error) {
+	return c.FetchFileContext(context.Background(), path, db, p, h, id...)
+}
+
+// FetchFileContext is like FetchFile but uses ctx to cancel the request.
+func (c *Client) FetchFileContext(ctx context.Context, path, db string, p *Parameters, h *History, id ...int) (*FileStats, error) {
+	r, err := c.FetchContext(ctx, db, p, h, id...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	w, err := NewFileWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(w, r)
+	if err != nil {
+		w.Abort()
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	s := w.Stats()
+	return &s, nil
+}
@@ -0,0 +1,117 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"gopkg.in/check.v1"
+)
+
+func sha(b []byte) string {
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
+
+func (s *S) TestFileWriter(c *check.C) {
+	const data = ">seq\nACGTACGTACGTACGTACGTACGTACGTACGT\n"
+	dir := c.MkDir()
+
+	for _, name := range []string{"seq.fa", "seq.fa.gz"} {
+		path := filepath.Join(dir, name)
+		w, err := NewFileWriter(path)
+		c.Assert(err, check.Equals, nil)
+		for i := 0; i < 10; i++ {
+			_, err = w.Write([]byte(data))
+			c.Check(err, check.Equals, nil)
+		}
+		_, err = os.Stat(path)
+		c.Check(os.IsNotExist(err), check.Equals, true, check.Commentf("file created before close"))
+		c.Assert(w.Close(), check.Equals, nil)
+
+		file, err := ioutil.ReadFile(path)
+		c.Assert(err, check.Equals, nil)
+		content := file
+		if filepath.Ext(name) == ".gz" {
+			f, err := os.Open(path)
+			c.Assert(err, check.Equals, nil)
+			zr, err := gzip.NewReader(f)
+			c.Assert(err, check.Equals, nil)
+			content, err = ioutil.ReadAll(zr)
+			c.Assert(err, check.Equals, nil)
+			f.Close()
+			c.Check(len(file) < len(content), check.Equals, true)
+		}
+
+		st := w.Stats()
+		c.Check(st.Path, check.Equals, path)
+		c.Check(st.Bytes, check.Equals, int64(10*len(data)))
+		c.Check(st.SHA256, check.Equals, sha(content))
+		c.Check(st.FileBytes, check.Equals, int64(len(file)))
+		c.Check(st.FileSHA256, check.Equals, sha(file))
+	}
+
+	path := filepath.Join(dir, "aborted.fa")
+	w, err := NewFileWriter(path)
+	c.Assert(err, check.Equals, nil)
+	w.Write([]byte(data))
+	c.Check(w.Abort(), check.Equals, nil)
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.Equals, nil)
+	c.Check(len(entries), check.Equals, 2)
+}
+
+func (s *S) TestFileWriterMode(c *check.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("file modes not supported")
+	}
+	dir := c.MkDir()
+	f, err := os.OpenFile(filepath.Join(dir, "new"), os.O_CREATE|os.O_WRONLY, 0666)
+	c.Assert(err, check.Equals, nil)
+	fi, err := f.Stat()
+	c.Assert(err, check.Equals, nil)
+	f.Close()
+	want := fi.Mode().Perm()
+
+	path := filepath.Join(dir, "seq.fa")
+	for _, mode := range []os.FileMode{0, 0640} {
+		if mode != 0 {
+			c.Assert(os.Chmod(path, mode), check.Equals, nil)
+			want = mode
+		}
+		w, err := NewFileWriter(path)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(w.Close(), check.Equals, nil)
+		fi, err = os.Stat(path)
+		c.Assert(err, check.Equals, nil)
+		c.Check(fi.Mode().Perm(), check.Equals, want)
+	}
+}
+
+func (s *S) TestFetchFile(c *check.C) {
+	srv, cl, last := testServer()
+	defer srv.Close()
+
+	path := filepath.Join(c.MkDir(), "info.xml.gz")
+	st, err := cl.FetchFile(path, "protein", nil, nil, 1, 2)
+	c.Assert(err, check.Equals, nil)
+	c.Check(last()["id"], check.DeepEquals, []string{"1", "2"})
+
+	const want = "<eInfoResult><DbList><DbName>pubmed</DbName></DbList></eInfoResult>"
+	c.Check(st.Bytes, check.Equals, int64(len(want)))
+	c.Check(st.SHA256, check.Equals, sha([]byte(want)))
+	fi, err := os.Stat(path)
+	c.Assert(err, check.Equals, nil)
+	c.Check(st.FileBytes, check.Equals, fi.Size())
+
+	_, err = cl.FetchFile(path, "protein", nil, nil)
+	c.Check(err, check.Equals, ErrNoIdProvided)
+}
@@ -0,0 +1,184 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package genbank provides a reader and writer for GenBank and GenPept flat files,
+// as returned by EFetch with rettype=gb or rettype=gp.
+//
+// Records may be read one at a time from an EFetch response with a Reader:
+//
+//	rc, err := entrez.Fetch("nuccore", &entrez.Parameters{RetType: "gb"}, tool, email, nil, ids...)
+//	...
+//	r := genbank.NewReader(rc)
+//	defer r.Close()
+//	for r.Next() {
+//		rec := r.Record()
+//		...
+//	}
+//	if r.Err() != nil {
+//		...
+//	}
+package genbank
+
+import (
+	"fmt"
+
+	"github.com/biogo/ncbi/entrez/location"
+)
+
+// Record is a GenBank or GenPept record.
+type Record struct {
+	Locus Locus
+
+	Definition string
+
+	// Accessions holds the primary accession
+	// followed by any secondary accessions.
+	Accessions []string
+
+	// Version is the accession.version of
+	// the record.
+	Version string
+
+	// GI is the GenInfo identifier given
+	// on the VERSION line of older records.
+	GI int
+
+	DBLink   []DBLink
+	DBSource string
+	Keywords []string
+	Segment  string
+
+	// Source is the common name of the
+	// source organism.
+	Source   string
+	Organism string
+	Lineage  []string
+
+	References []Reference
+
+	// Comment holds the lines of the
+	// COMMENT field.
+	Comment []string
+
+	// Other holds fields not otherwise
+	// held by a Record.
+	Other []Field
+
+	Features []Feature
+
+	// Contig is the CONTIG field of records
+	// constructed from other records.
+	Contig string
+
+	// Sequence is the sequence given in the
+	// ORIGIN field.
+	Sequence []byte
+}
+
+// Accession returns the primary accession of the record.
+func (r *Record) Accession() string {
+	if len(r.Accessions) == 0 {
+		return ""
+	}
+	return r.Accessions[0]
+}
+
+// Extract returns the part of the record's sequence described by the location of f.
+// Parts of the location held in other records are taken from the sequence returned
+// by remote for their accession.
+func (r *Record) Extract(f *Feature, remote func(accession string) ([]byte, error)) ([]byte, error) {
+	if f.Location == nil {
+		return nil, fmt.Errorf("genbank: %s feature has no location", f.Key)
+	}
+	return location.Extract(f.Location, r.Sequence, remote)
+}
+
+// Locus holds the fields of a LOCUS line.
+type Locus struct {
+	Name   string
+	Length int
+
+	// Unit is "bp" for nucleotide records
+	// and "aa" for protein records.
+	Unit string
+
+	// Strand is "ss", "ds" or "ms" if the
+	// strandedness of the molecule is given.
+	Strand   string
+	Molecule string
+	Topology string
+	Division string
+
+	// Date is the modification date in the
+	// form 02-JAN-2006.
+	Date string
+}
+
+// DBLink is a cross-reference given in the DBLINK field.
+type DBLink struct {
+	Database string
+	IDs      []string
+}
+
+// Reference is a citation given in a REFERENCE field.
+type Reference struct {
+	Number int
+
+	// Range is the part of the sequence
+	// covered by the reference, for example
+	// "(bases 1 to 3021)".
+	Range string
+
+	Authors    string
+	Consortium string
+	Title      string
+	Journal    string
+	PubMed     int
+	Medline    int
+	Remark     string
+}
+
+// Field is a top-level field of a record.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Feature is an entry in the feature table of a record.
+type Feature struct {
+	Key        string
+	Location   location.Location
+	Qualifiers []Qualifier
+}
+
+// Get returns the value of the first qualifier of the feature with the given name
+// and whether it was found.
+func (f *Feature) Get(name string) (value string, ok bool) {
+	for _, q := range f.Qualifiers {
+		if q.Name == name {
+			return q.Value, true
+		}
+	}
+	return "", false
+}
+
+// Qualifier is a feature qualifier.
+type Qualifier struct {
+	Name  string
+	Value string
+
+	// Quoted is true if the value is written
+	// as a quoted string. Qualifiers with an
+	// empty value that is not quoted, such as
+	// /pseudo, have no value.
+	Quoted bool
+}
+
+// SyntaxError is returned by a Reader when a record is malformed.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string { return fmt.Sprintf("genbank: line %d: %s", e.Line, e.Msg) }
@@ -0,0 +1,262 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genbank
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/biogo/ncbi/entrez/location"
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+const nucleotide = `LOCUS       NM_000546               1230 bp    mRNA    linear   PRI 15-MAR-2020
+DEFINITION  Homo sapiens tumor protein p53 (TP53), transcript variant 1, mRNA.
+ACCESSION   NM_000546 XM_001
+VERSION     NM_000546.6
+DBLINK      BioProject: PRJNA168
+            BioSample: SAMN00000001, SAMN00000002
+KEYWORDS    RefSeq; MANE Select.
+SOURCE      Homo sapiens (human)
+  ORGANISM  Homo sapiens
+            Eukaryota; Metazoa; Chordata; Craniata; Vertebrata; Euteleostomi;
+            Mammalia; Eutheria; Euarchontoglires; Primates; Haplorrhini;
+            Catarrhini; Hominidae; Homo.
+REFERENCE   1  (bases 1 to 1230)
+  AUTHORS   Smith J, Doe A and Roe B.
+  TITLE     A study of p53
+  JOURNAL   Nature 1 (2), 3-4 (2020)
+   PUBMED   12345678
+  REMARK    GeneRIF: p53 matters.
+REFERENCE   2  (bases 1 to 1230)
+  CONSRTM   NCBI Genome Project
+  TITLE     Direct Submission
+  JOURNAL   Submitted (01-JAN-2020) National Center for Biotechnology
+            Information, NIH, Bethesda, MD 20894, USA
+COMMENT     REVIEWED REFSEQ: This record has been curated by NCBI staff.
+            
+            ##Evidence-Data-START##
+            Transcript exon combination :: AB082923.1 [ECO:0000332]
+            ##Evidence-Data-END##
+PRIMARY     REFSEQ_SPAN         PRIMARY_IDENTIFIER PRIMARY_SPAN        COMP
+            1-1230              AB082923.1         1-1230
+FEATURES             Location/Qualifiers
+     source          1..1230
+                     /organism="Homo sapiens"
+                     /mol_type="mRNA"
+                     /db_xref="taxon:9606"
+                     /chromosome="17"
+     gene            <1..>1230
+                     /gene="TP53"
+                     /note="tumor protein p53; a gene whose product is said to
+                     be the ""guardian of the genome"""
+                     /pseudo
+     CDS             join(12..30,40..120,130..200,300..400,410..450,460..500,
+                     510..520,AB082923.1:1..20)
+                     /gene="TP53"
+                     /codon_start=1
+                     /translation="MEEPQSDPSVEPPLSQETFSDLWKLLPENNVLSPLPSQAMDDLM
+                     LSPDDIEQWFTEDPGPDEAPRMPEAAPPVAPAPAAPTPAAPAPAPSWPLSSSVPSQKT
+                     YQGSYGFRL"
+     misc_feature    complement(order(600^601,700.710,>720))
+ORIGIN
+        1 gatgggattg gggttttccc ctcccatgtg ctcaagactg gcgctaaaag ttttgagctt
+       61 ctcaaaagtc tagagccacc
+//
+LOCUS       NP_000537                393 aa            linear   PRI 26-MAR-2023
+DEFINITION  cellular tumor antigen p53 isoform a [Homo sapiens].
+ACCESSION   NP_000537
+VERSION     NP_000537.3
+DBSOURCE    REFSEQ: accession NM_000546.6
+KEYWORDS    .
+SOURCE      Homo sapiens (human)
+  ORGANISM  Homo sapiens
+            Eukaryota; Metazoa; Chordata.
+FEATURES             Location/Qualifiers
+     Protein         1..393
+                     /product="cellular tumor antigen p53 isoform a"
+     Bond            bond(176,238)
+ORIGIN
+        1 meepqsdpsv epplsqetfs dlwkllpenn vlsplp
+//
+`
+
+func (s *S) TestReader(c *check.C) {
+	r := NewReader(strings.NewReader(nucleotide))
+	var recs []*Record
+	for r.Next() {
+		recs = append(recs, r.Record())
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Assert(recs, check.HasLen, 2)
+
+	rec := recs[0]
+	c.Check(rec.Locus, check.Equals, Locus{
+		Name:     "NM_000546",
+		Length:   1230,
+		Unit:     "bp",
+		Molecule: "mRNA",
+		Topology: "linear",
+		Division: "PRI",
+		Date:     "15-MAR-2020",
+	})
+	c.Check(rec.Definition, check.Equals, "Homo sapiens tumor protein p53 (TP53), transcript variant 1, mRNA.")
+	c.Check(rec.Accession(), check.Equals, "NM_000546")
+	c.Check(rec.Accessions, check.DeepEquals, []string{"NM_000546", "XM_001"})
+	c.Check(rec.Version, check.Equals, "NM_000546.6")
+	c.Check(rec.DBLink, check.DeepEquals, []DBLink{
+		{Database: "BioProject", IDs: []string{"PRJNA168"}},
+		{Database: "BioSample", IDs: []string{"SAMN00000001", "SAMN00000002"}},
+	})
+	c.Check(rec.Keywords, check.DeepEquals, []string{"RefSeq", "MANE Select"})
+	c.Check(rec.Source, check.Equals, "Homo sapiens (human)")
+	c.Check(rec.Organism, check.Equals, "Homo sapiens")
+	c.Check(rec.Lineage, check.HasLen, 14)
+	c.Check(rec.Lineage[13], check.Equals, "Homo")
+	c.Check(rec.References, check.DeepEquals, []Reference{
+		{
+			Number:  1,
+			Range:   "(bases 1 to 1230)",
+			Authors: "Smith J, Doe A and Roe B.",
+			Title:   "A study of p53",
+			Journal: "Nature 1 (2), 3-4 (2020)",
+			PubMed:  12345678,
+			Remark:  "GeneRIF: p53 matters.",
+		},
+		{
+			Number:     2,
+			Range:      "(bases 1 to 1230)",
+			Consortium: "NCBI Genome Project",
+			Title:      "Direct Submission",
+			Journal:    "Submitted (01-JAN-2020) National Center for Biotechnology Information, NIH, Bethesda, MD 20894, USA",
+		},
+	})
+	c.Check(rec.Comment, check.HasLen, 5)
+	c.Check(rec.Comment[1], check.Equals, "")
+	c.Check(rec.Other, check.DeepEquals, []Field{{
+		Name:  "PRIMARY",
+		Value: "REFSEQ_SPAN         PRIMARY_IDENTIFIER PRIMARY_SPAN        COMP\n1-1230              AB082923.1         1-1230",
+	}})
+
+	c.Assert(rec.Features, check.HasLen, 4)
+	gene := rec.Features[1]
+	c.Check(gene.Key, check.Equals, "gene")
+	c.Check(gene.Location, check.DeepEquals, &location.Range{
+		Start: location.Position{Pos: 1, Fuzz: location.Before},
+		End:   location.Position{Pos: 1230, Fuzz: location.After},
+	})
+	c.Check(gene.Qualifiers, check.DeepEquals, []Qualifier{
+		{Name: "gene", Value: "TP53", Quoted: true},
+		{Name: "note", Value: `tumor protein p53; a gene whose product is said to be the "guardian of the genome"`, Quoted: true},
+		{Name: "pseudo"},
+	})
+	cds := rec.Features[2]
+	c.Check(cds.Location.String(), check.Equals, "join(12..30,40..120,130..200,300..400,410..450,460..500,510..520,AB082923.1:1..20)")
+	op, ok := cds.Location.(*location.Operator)
+	c.Assert(ok, check.Equals, true)
+	c.Check(op.Name, check.Equals, "join")
+	c.Check(op.Args[7], check.DeepEquals, &location.Range{
+		Accession: "AB082923.1",
+		Start:     location.Position{Pos: 1},
+		End:       location.Position{Pos: 20},
+	})
+	codon, _ := cds.Get("codon_start")
+	c.Check(codon, check.Equals, "1")
+	translation, ok := cds.Get("translation")
+	c.Check(ok, check.Equals, true)
+	c.Check(translation, check.Equals, "MEEPQSDPSVEPPLSQETFSDLWKLLPENNVLSPLPSQAMDDLMLSPDDIEQWFTEDPGPDEAPRMPEAAPPVAPAPAAPTPAAPAPAPSWPLSSSVPSQKTYQGSYGFRL")
+	c.Check(rec.Features[3].Location.String(), check.Equals, "complement(order(600^601,700.710,>720))")
+	c.Check(string(rec.Sequence), check.Equals, "gatgggattggggttttcccctcccatgtgctcaagactggcgctaaaagttttgagcttctcaaaagtctagagccacc")
+
+	exon := Feature{Key: "exon", Location: &location.Operator{Name: "complement", Args: []location.Location{
+		&location.Range{Start: location.Position{Pos: 1}, End: location.Position{Pos: 10}},
+	}}}
+	seq, err := rec.Extract(&exon, nil)
+	c.Check(err, check.Equals, nil)
+	c.Check(string(seq), check.Equals, "caatcccatc")
+	exon.Location, err = location.Parse("join(1..3,AB082923.1:1..4)")
+	c.Assert(err, check.Equals, nil)
+	seq, err = rec.Extract(&exon, func(acc string) ([]byte, error) { return []byte("ACGT"), nil })
+	c.Check(err, check.Equals, nil)
+	c.Check(string(seq), check.Equals, "gatACGT")
+
+	prot := recs[1]
+	c.Check(prot.Locus, check.Equals, Locus{
+		Name:     "NP_000537",
+		Length:   393,
+		Unit:     "aa",
+		Topology: "linear",
+		Division: "PRI",
+		Date:     "26-MAR-2023",
+	})
+	c.Check(prot.DBSource, check.Equals, "REFSEQ: accession NM_000546.6")
+	c.Check(prot.Keywords, check.HasLen, 0)
+	c.Check(prot.Features[1].Location.String(), check.Equals, "bond(176,238)")
+	c.Check(string(prot.Sequence), check.Equals, "meepqsdpsvepplsqetfsdlwkllpennvlsplp")
+}
+
+func (s *S) TestRoundTrip(c *check.C) {
+	r := NewReader(strings.NewReader(nucleotide))
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	var recs []*Record
+	for r.Next() {
+		recs = append(recs, r.Record())
+		c.Assert(w.Write(r.Record()), check.Equals, nil)
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+
+	got, want := strings.Split(buf.String(), "\n"), strings.Split(nucleotide, "\n")
+	for i := 0; i < len(got) && i < len(want); i++ {
+		c.Check(got[i], check.Equals, want[i], check.Commentf("line %d", i+1))
+	}
+	c.Check(len(got), check.Equals, len(want))
+
+	r = NewReader(&buf)
+	for i := 0; r.Next(); i++ {
+		c.Check(r.Record(), check.DeepEquals, recs[i])
+	}
+	c.Check(r.Err(), check.Equals, nil)
+
+	// A record without a version is written with
+	// an empty VERSION line.
+	rec := *recs[0]
+	rec.Version, rec.GI = "", 0
+	buf.Reset()
+	c.Assert(w.Write(&rec), check.Equals, nil)
+	r = NewReader(&buf)
+	c.Assert(r.Next(), check.Equals, true)
+	c.Check(r.Record(), check.DeepEquals, &rec)
+	c.Check(r.Next(), check.Equals, false)
+	c.Check(r.Err(), check.Equals, nil)
+}
+
+func (s *S) TestReaderErr(c *check.C) {
+	for _, t := range []struct {
+		in   string
+		want string
+	}{
+		{in: nucleotide[:len(nucleotide)/2], want: io.ErrUnexpectedEOF.Error()},
+		{in: "DEFINITION  none\n", want: "genbank: line 1: expected LOCUS line"},
+		{
+			in:   "LOCUS       X 10 bp DNA\nFEATURES             Location/Qualifiers\n     gene            join(1..2\n//\n",
+			want: `genbank: line 3: location: invalid location "join\(1..2": missing \)`,
+		},
+	} {
+		r := NewReader(strings.NewReader(t.in))
+		for r.Next() {
+		}
+		c.Check(r.Err(), check.ErrorMatches, t.want)
+	}
+}
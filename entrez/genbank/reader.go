@@ -0,0 +1,488 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genbank
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/ncbi/entrez/location"
+)
+
+const (
+	// valueColumn is the column holding the
+	// values of top-level fields.
+	valueColumn = 12
+
+	// featureColumn is the column holding
+	// feature locations and qualifiers.
+	featureColumn = 21
+)
+
+// A Reader reads GenBank records one at a time from a stream of records, such as an
+// EFetch response.
+type Reader struct {
+	r    io.Reader
+	br   *bufio.Reader
+	line int
+
+	peeked bool
+	peek   string
+
+	rec  *Record
+	err  error
+	done bool
+}
+
+// NewReader returns a Reader that reads GenBank records from r. If r is an io.Closer,
+// it is closed by the Reader's Close method.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r, br: bufio.NewReader(r)}
+}
+
+// Next advances the Reader to the next record, which is then available through the
+// Record method. It returns false when there are no more records or an error occurs.
+func (r *Reader) Next() bool {
+	r.rec = nil
+	if r.done {
+		return false
+	}
+	rec, err := r.record()
+	if err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		r.err = err
+		r.done = true
+		return false
+	}
+	r.rec = rec
+	return true
+}
+
+// Record returns the current record.
+func (r *Reader) Record() *Record { return r.rec }
+
+// Err returns the first error encountered by the Reader.
+func (r *Reader) Err() error { return r.err }
+
+// Close closes the underlying stream.
+func (r *Reader) Close() error {
+	r.done = true
+	if c, ok := r.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// readLine returns the next line of the stream without its line ending.
+func (r *Reader) readLine() (string, error) {
+	if r.peeked {
+		r.peeked = false
+		r.line++
+		return r.peek, nil
+	}
+	line, err := r.br.ReadString('\n')
+	if err != nil {
+		if err != io.EOF || line == "" {
+			return "", err
+		}
+	}
+	r.line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// unreadLine returns line to the stream.
+func (r *Reader) unreadLine(line string) {
+	r.peeked = true
+	r.peek = line
+	r.line--
+}
+
+func (r *Reader) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: r.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// record reads the next record. It returns io.EOF if there are no more records.
+func (r *Reader) record() (*Record, error) {
+	var line string
+	var err error
+	for {
+		line, err = r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(line) != "" {
+			break
+		}
+	}
+	name, value := split(line)
+	if name != "LOCUS" {
+		return nil, r.errorf("expected LOCUS line")
+	}
+	rec := &Record{}
+	rec.Locus, err = parseLocus(value)
+	if err != nil {
+		return nil, r.errorf("%v", err)
+	}
+
+	for {
+		line, err = r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if line == "//" {
+			return rec, nil
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value := split(line)
+		if name == "" {
+			return nil, r.errorf("unexpected continuation line")
+		}
+		switch name {
+		case "FEATURES":
+			rec.Features, err = r.features()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case "ORIGIN":
+			rec.Sequence, err = r.sequence()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		lines, err := r.continuation(value)
+		if err != nil {
+			return nil, err
+		}
+		text := join(lines)
+		switch name {
+		case "DEFINITION":
+			rec.Definition = text
+		case "ACCESSION":
+			rec.Accessions = strings.Fields(text)
+		case "VERSION":
+			f := strings.Fields(text)
+			if len(f) == 0 {
+				break
+			}
+			rec.Version = f[0]
+			for _, s := range f[1:] {
+				if strings.HasPrefix(s, "GI:") {
+					rec.GI, err = strconv.Atoi(s[len("GI:"):])
+					if err != nil {
+						return nil, r.errorf("invalid GI: %q", s)
+					}
+				}
+			}
+		case "DBLINK":
+			for _, l := range lines {
+				l = strings.TrimSpace(l)
+				i := strings.Index(l, ":")
+				if i < 0 && len(rec.DBLink) != 0 {
+					last := &rec.DBLink[len(rec.DBLink)-1]
+					last.IDs = append(last.IDs, splitList(l)...)
+					continue
+				}
+				if i < 0 {
+					return nil, r.errorf("invalid DBLINK: %q", l)
+				}
+				rec.DBLink = append(rec.DBLink, DBLink{Database: l[:i], IDs: splitList(l[i+1:])})
+			}
+		case "DBSOURCE":
+			rec.DBSource = text
+		case "KEYWORDS":
+			rec.Keywords = parseKeywords(text)
+		case "SEGMENT":
+			rec.Segment = text
+		case "SOURCE":
+			rec.Source = text
+		case "ORGANISM":
+			rec.Organism = strings.TrimSpace(lines[0])
+			rec.Lineage = parseKeywords(join(lines[1:]))
+		case "REFERENCE":
+			var ref Reference
+			f := strings.SplitN(text, " ", 2)
+			ref.Number, err = strconv.Atoi(f[0])
+			if err != nil {
+				return nil, r.errorf("invalid reference number: %q", f[0])
+			}
+			if len(f) == 2 {
+				ref.Range = strings.TrimSpace(f[1])
+			}
+			rec.References = append(rec.References, ref)
+		case "AUTHORS", "CONSRTM", "TITLE", "JOURNAL", "PUBMED", "MEDLINE", "REMARK":
+			if len(rec.References) == 0 {
+				return nil, r.errorf("%s outside reference", name)
+			}
+			ref := &rec.References[len(rec.References)-1]
+			switch name {
+			case "AUTHORS":
+				ref.Authors = text
+			case "CONSRTM":
+				ref.Consortium = text
+			case "TITLE":
+				ref.Title = text
+			case "JOURNAL":
+				ref.Journal = text
+			case "PUBMED":
+				ref.PubMed, err = strconv.Atoi(text)
+			case "MEDLINE":
+				ref.Medline, err = strconv.Atoi(text)
+			case "REMARK":
+				ref.Remark = text
+			}
+			if err != nil {
+				return nil, r.errorf("invalid %s: %q", name, text)
+			}
+		case "COMMENT":
+			rec.Comment = lines
+		case "CONTIG":
+			rec.Contig = strings.Join(strings.Fields(strings.Join(lines, "")), "")
+		default:
+			rec.Other = append(rec.Other, Field{Name: name, Value: strings.Join(lines, "\n")})
+		}
+	}
+}
+
+// continuation returns value followed by the values of any continuation lines.
+func (r *Reader) continuation(value string) ([]string, error) {
+	lines := []string{value}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return lines, nil
+			}
+			return nil, err
+		}
+		// Continuation lines may be blank
+		// in the COMMENT field.
+		if len(line) < valueColumn || strings.TrimLeft(line[:valueColumn], " ") != "" {
+			r.unreadLine(line)
+			return lines, nil
+		}
+		lines = append(lines, strings.TrimRight(line[valueColumn:], " "))
+	}
+}
+
+// features reads a feature table.
+func (r *Reader) features() ([]Feature, error) {
+	var (
+		feats []Feature
+		loc   string
+		quals []string
+	)
+	flush := func() error {
+		if len(feats) == 0 {
+			return nil
+		}
+		f := &feats[len(feats)-1]
+		var err error
+		f.Location, err = location.Parse(loc)
+		if err != nil {
+			return r.errorf("%v", err)
+		}
+		for _, q := range quals {
+			f.Qualifiers = append(f.Qualifiers, parseQualifier(q))
+		}
+		loc, quals = "", nil
+		return nil
+	}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case indented(line, featureColumn):
+			text := strings.TrimSpace(line)
+			n := len(quals)
+			switch {
+			case n != 0 && open(quals[n-1]):
+				if strings.HasPrefix(quals[n-1], "/translation=") {
+					quals[n-1] += text
+				} else {
+					quals[n-1] += " " + text
+				}
+			case strings.HasPrefix(text, "/"):
+				quals = append(quals, text)
+			case n != 0:
+				quals[n-1] += " " + text
+			case len(feats) == 0:
+				return nil, r.errorf("feature location without key")
+			default:
+				loc += text
+			}
+		case indented(line, 5):
+			err = flush()
+			if err != nil {
+				return nil, err
+			}
+			f := strings.Fields(line)
+			feats = append(feats, Feature{Key: f[0]})
+			if len(f) > 1 {
+				loc = strings.Join(f[1:], "")
+			}
+		default:
+			r.unreadLine(line)
+			return feats, flush()
+		}
+	}
+}
+
+// sequence reads the sequence lines of an ORIGIN field.
+func (r *Reader) sequence() ([]byte, error) {
+	var seq []byte
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if line == "//" {
+			r.unreadLine(line)
+			return seq, nil
+		}
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case c == ' ' || '0' <= c && c <= '9':
+			case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '*' || c == '-':
+				seq = append(seq, c)
+			default:
+				return nil, r.errorf("invalid sequence character %q", c)
+			}
+		}
+	}
+}
+
+// split returns the field name and value of a line. The name is empty for continuation
+// lines.
+func split(line string) (name, value string) {
+	if len(line) <= valueColumn {
+		return strings.TrimSpace(line), ""
+	}
+	return strings.TrimSpace(line[:valueColumn]), strings.TrimRight(line[valueColumn:], " ")
+}
+
+// indented returns whether line starts with at least n spaces.
+func indented(line string, n int) bool {
+	return len(line) > n && strings.TrimLeft(line[:n], " ") == ""
+}
+
+// join returns the trimmed lines joined with spaces.
+func join(lines []string) string {
+	var f []string
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			f = append(f, l)
+		}
+	}
+	return strings.Join(f, " ")
+}
+
+// splitList returns the comma separated elements of s.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// parseKeywords returns the semicolon separated elements of a list terminated by
+// a period.
+func parseKeywords(s string) []string {
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	var list []string
+	for _, e := range strings.Split(s, ";") {
+		e = strings.TrimSpace(e)
+		if e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// parseLocus parses the value of a LOCUS line.
+func parseLocus(s string) (Locus, error) {
+	var l Locus
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return l, fmt.Errorf("invalid LOCUS line: %q", s)
+	}
+	l.Name = f[0]
+	var err error
+	l.Length, err = strconv.Atoi(f[1])
+	if err != nil {
+		return l, fmt.Errorf("invalid LOCUS length: %q", f[1])
+	}
+	l.Unit = f[2]
+	f = f[3:]
+	if n := len(f); n != 0 && len(f[n-1]) == len("02-JAN-2006") && strings.Count(f[n-1], "-") == 2 {
+		l.Date = f[n-1]
+		f = f[:n-1]
+	}
+	if n := len(f); n != 0 && len(f[n-1]) == 3 && strings.ToUpper(f[n-1]) == f[n-1] {
+		l.Division = f[n-1]
+		f = f[:n-1]
+	}
+	for _, s := range f {
+		switch {
+		case s == "linear" || s == "circular":
+			l.Topology = s
+		case len(s) > 3 && s[2] == '-':
+			l.Strand = s[:2]
+			l.Molecule = s[3:]
+		default:
+			l.Molecule = s
+		}
+	}
+	return l, nil
+}
+
+// open returns whether the qualifier text q holds an unterminated quoted value.
+func open(q string) bool {
+	i := strings.Index(q, "=")
+	return i >= 0 && strings.HasPrefix(q[i+1:], `"`) && strings.Count(q[i+1:], `"`)%2 == 1
+}
+
+// parseQualifier parses the text of a qualifier.
+func parseQualifier(q string) Qualifier {
+	q = strings.TrimPrefix(q, "/")
+	i := strings.Index(q, "=")
+	if i < 0 {
+		return Qualifier{Name: q}
+	}
+	v := q[i+1:]
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		return Qualifier{Name: q[:i], Value: strings.Replace(v[1:len(v)-1], `""`, `"`, -1), Quoted: true}
+	}
+	return Qualifier{Name: q[:i], Value: v}
+}
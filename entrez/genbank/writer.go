@@ -0,0 +1,254 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genbank
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// lineWidth is the maximum length of a line written by a Writer.
+const lineWidth = 79
+
+// A Writer writes GenBank records.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer that writes GenBank records to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes rec to the underlying writer. Text fields are wrapped to the width of
+// a GenBank line.
+func (w *Writer) Write(rec *Record) error {
+	var buf bytes.Buffer
+
+	l := rec.Locus
+	length := strconv.Itoa(l.Length)
+	pad := 28 - len(l.Name) - len(length)
+	if pad < 1 {
+		pad = 1
+	}
+	strand := "   "
+	if l.Strand != "" {
+		strand = l.Strand + "-"
+	}
+	locus := fmt.Sprintf("%s%*s%s %s %-3s%-7s %-8s %s %s", l.Name, pad, "", length, l.Unit, strand, l.Molecule, l.Topology, l.Division, l.Date)
+	field(&buf, "LOCUS", []string{strings.TrimRight(locus, " ")})
+
+	field(&buf, "DEFINITION", wrapWords(rec.Definition, lineWidth-valueColumn))
+	field(&buf, "ACCESSION", wrapWords(strings.Join(rec.Accessions, " "), lineWidth-valueColumn))
+	version := rec.Version
+	if rec.GI != 0 {
+		version += "  GI:" + strconv.Itoa(rec.GI)
+	}
+	field(&buf, "VERSION", []string{version})
+	if len(rec.DBLink) != 0 {
+		var lines []string
+		for _, d := range rec.DBLink {
+			lines = append(lines, d.Database+": "+strings.Join(d.IDs, ", "))
+		}
+		field(&buf, "DBLINK", lines)
+	}
+	if rec.DBSource != "" {
+		field(&buf, "DBSOURCE", wrapWords(rec.DBSource, lineWidth-valueColumn))
+	}
+	keywords := "."
+	if len(rec.Keywords) != 0 {
+		keywords = strings.Join(rec.Keywords, "; ") + "."
+	}
+	field(&buf, "KEYWORDS", wrapWords(keywords, lineWidth-valueColumn))
+	if rec.Segment != "" {
+		field(&buf, "SEGMENT", []string{rec.Segment})
+	}
+	field(&buf, "SOURCE", wrapWords(rec.Source, lineWidth-valueColumn))
+	organism := []string{rec.Organism}
+	if len(rec.Lineage) != 0 {
+		organism = append(organism, wrapWords(strings.Join(rec.Lineage, "; ")+".", lineWidth-valueColumn)...)
+	}
+	field(&buf, "  ORGANISM", organism)
+
+	for _, ref := range rec.References {
+		field(&buf, "REFERENCE", []string{strings.TrimRight(fmt.Sprintf("%-2d %s", ref.Number, ref.Range), " ")})
+		for _, f := range []struct {
+			name  string
+			value string
+		}{
+			{name: "  AUTHORS", value: ref.Authors},
+			{name: "  CONSRTM", value: ref.Consortium},
+			{name: "  TITLE", value: ref.Title},
+			{name: "  JOURNAL", value: ref.Journal},
+			{name: "   MEDLINE", value: id(ref.Medline)},
+			{name: "   PUBMED", value: id(ref.PubMed)},
+			{name: "  REMARK", value: ref.Remark},
+		} {
+			if f.value != "" {
+				field(&buf, f.name, wrapWords(f.value, lineWidth-valueColumn))
+			}
+		}
+	}
+
+	if len(rec.Comment) != 0 {
+		field(&buf, "COMMENT", rec.Comment)
+	}
+	for _, f := range rec.Other {
+		field(&buf, f.Name, strings.Split(f.Value, "\n"))
+	}
+
+	if len(rec.Features) != 0 {
+		fmt.Fprintf(&buf, "%-*sLocation/Qualifiers\n", featureColumn, "FEATURES")
+		for _, f := range rec.Features {
+			var loc string
+			if f.Location != nil {
+				loc = f.Location.String()
+			}
+			for i, line := range wrapAfter(loc, ',', lineWidth-featureColumn) {
+				if i == 0 {
+					fmt.Fprintf(&buf, "     %-*s%s\n", featureColumn-5, f.Key, line)
+				} else {
+					fmt.Fprintf(&buf, "%*s%s\n", featureColumn, "", line)
+				}
+			}
+			for _, q := range f.Qualifiers {
+				text := "/" + q.Name
+				switch {
+				case q.Quoted:
+					text += `="` + strings.Replace(q.Value, `"`, `""`, -1) + `"`
+				case q.Value != "":
+					text += "=" + q.Value
+				}
+				var lines []string
+				if q.Name == "translation" {
+					lines = hardWrap(text, lineWidth-featureColumn)
+				} else {
+					lines = wrapWords(text, lineWidth-featureColumn)
+				}
+				for _, line := range lines {
+					fmt.Fprintf(&buf, "%*s%s\n", featureColumn, "", line)
+				}
+			}
+		}
+	}
+
+	if rec.Contig != "" {
+		field(&buf, "CONTIG", wrapAfter(rec.Contig, ',', lineWidth-valueColumn))
+	}
+	if len(rec.Sequence) != 0 {
+		buf.WriteString("ORIGIN\n")
+		for i := 0; i < len(rec.Sequence); i += 60 {
+			fmt.Fprintf(&buf, "%9d", i+1)
+			for j := i; j < i+60 && j < len(rec.Sequence); j += 10 {
+				end := j + 10
+				if end > len(rec.Sequence) {
+					end = len(rec.Sequence)
+				}
+				buf.WriteByte(' ')
+				buf.Write(rec.Sequence[j:end])
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteString("//\n")
+
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+// field writes a field with the given name and value lines.
+func field(buf *bytes.Buffer, name string, lines []string) {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	for i, line := range lines {
+		if i == 0 {
+			fmt.Fprintf(buf, "%-*s", valueColumn, name)
+		} else {
+			fmt.Fprintf(buf, "%*s", valueColumn, "")
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
+
+// id returns the decimal representation of a non-zero id or the empty string.
+func id(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// wrapWords breaks text into lines of at most width bytes at spaces. Words longer
+// than width are broken.
+func wrapWords(text string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+	for _, w := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = w
+		case len(line)+1+len(w) <= width:
+			line += " " + w
+		default:
+			lines = append(lines, line)
+			line = w
+		}
+		for len(line) > width {
+			lines = append(lines, line[:width])
+			line = line[width:]
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// wrapAfter breaks text into lines of at most width bytes after occurrences of sep.
+// Parts longer than width are broken.
+func wrapAfter(text string, sep byte, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+	for len(text) != 0 {
+		i := strings.IndexByte(text, sep) + 1
+		if i == 0 {
+			i = len(text)
+		}
+		part := text[:i]
+		text = text[i:]
+		if line != "" && len(line)+len(part) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		line += part
+		for len(line) > width {
+			lines = append(lines, line[:width])
+			line = line[width:]
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// hardWrap breaks text into lines of width bytes.
+func hardWrap(text string, width int) []string {
+	var lines []string
+	for len(text) > width {
+		lines = append(lines, text[:width])
+		text = text[width:]
+	}
+	return append(lines, text)
+}
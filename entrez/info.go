@@ -73,3 +73,5 @@ type Info struct {
 	DbInfo *info.DbInfo `xml:"DbInfo"`
 	Err    string       `xml:"ERROR"`
 }
+
+func (i *Info) reportsError() bool { return i.Err != "" }
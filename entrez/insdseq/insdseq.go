@@ -0,0 +1,282 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package insdseq provides types for INSDSeq and GBSeq XML, as returned by EFetch
+// with retmode=xml and rettype=gb, gbc or gp (GBSeq), or rettype=native or insdseq
+// (INSDSeq) for the nucleotide and protein databases.
+//
+// The GBSeq DTD mirrors the INSDSeq DTD with element names prefixed with GB rather
+// than INSD, so both are decoded into the same types by a Reader.
+package insdseq
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// <!--
+//   INSD_INSDSeq.dtd
+//   This file is built from a series of basic modules.
+// -->
+//
+// <!ELEMENT INSDSet (INSDSeq*)>
+//
+// <!ELEMENT INSDSeq (
+//         INSDSeq_locus?,
+//         INSDSeq_length,
+//         INSDSeq_strandedness?,
+//         INSDSeq_moltype,
+//         INSDSeq_topology?,
+//         INSDSeq_division?,
+//         INSDSeq_update-date?,
+//         INSDSeq_create-date?,
+//         INSDSeq_update-release?,
+//         INSDSeq_create-release?,
+//         INSDSeq_definition?,
+//         INSDSeq_primary-accession?,
+//         INSDSeq_entry-version?,
+//         INSDSeq_accession-version?,
+//         INSDSeq_other-seqids?,
+//         INSDSeq_secondary-accessions?,
+//         INSDSeq_project?,
+//         INSDSeq_keywords?,
+//         INSDSeq_segment?,
+//         INSDSeq_source?,
+//         INSDSeq_organism?,
+//         INSDSeq_taxonomy?,
+//         INSDSeq_references?,
+//         INSDSeq_comment?,
+//         INSDSeq_comment-set?,
+//         INSDSeq_struc-comments?,
+//         INSDSeq_primary?,
+//         INSDSeq_source-db?,
+//         INSDSeq_database-reference?,
+//         INSDSeq_xrefs?,
+//         INSDSeq_feature-table?,
+//         INSDSeq_feature-set?,
+//         INSDSeq_sequence?,
+//         INSDSeq_contig?,
+//         INSDSeq_alt-seq?)>
+//
+// <!ELEMENT INSDReference (
+//         INSDReference_reference,
+//         INSDReference_position?,
+//         INSDReference_authors?,
+//         INSDReference_consortium?,
+//         INSDReference_title?,
+//         INSDReference_journal,
+//         INSDReference_xref?,
+//         INSDReference_pubmed?,
+//         INSDReference_remark?)>
+//
+// <!ELEMENT INSDFeature (
+//         INSDFeature_key,
+//         INSDFeature_location,
+//         INSDFeature_intervals?,
+//         INSDFeature_operator?,
+//         INSDFeature_partial5?,
+//         INSDFeature_partial3?,
+//         INSDFeature_quals?,
+//         INSDFeature_xrefs?)>
+//
+// <!ELEMENT INSDInterval (
+//         INSDInterval_from?,
+//         INSDInterval_to?,
+//         INSDInterval_point?,
+//         INSDInterval_iscomp?,
+//         INSDInterval_interbp?,
+//         INSDInterval_accession)>
+//
+// <!ELEMENT INSDQualifier (
+//         INSDQualifier_name,
+//         INSDQualifier_value?)>
+//
+// <!ELEMENT INSDXref (
+//         INSDXref_dbname,
+//         INSDXref_id)>
+
+// Set is an INSDSet.
+type Set struct {
+	Seqs []Seq `xml:"INSDSeq"`
+}
+
+// Seq is an INSDSeq.
+type Seq struct {
+	Locus               string         `xml:"INSDSeq_locus"`
+	Length              int            `xml:"INSDSeq_length"`
+	Strandedness        string         `xml:"INSDSeq_strandedness"`
+	MolType             string         `xml:"INSDSeq_moltype"`
+	Topology            string         `xml:"INSDSeq_topology"`
+	Division            string         `xml:"INSDSeq_division"`
+	UpdateDate          string         `xml:"INSDSeq_update-date"`
+	CreateDate          string         `xml:"INSDSeq_create-date"`
+	UpdateRelease       string         `xml:"INSDSeq_update-release"`
+	CreateRelease       string         `xml:"INSDSeq_create-release"`
+	Definition          string         `xml:"INSDSeq_definition"`
+	PrimaryAccession    string         `xml:"INSDSeq_primary-accession"`
+	EntryVersion        string         `xml:"INSDSeq_entry-version"`
+	AccessionVersion    string         `xml:"INSDSeq_accession-version"`
+	OtherSeqIDs         []string       `xml:"INSDSeq_other-seqids>INSDSeqid"`
+	SecondaryAccessions []string       `xml:"INSDSeq_secondary-accessions>INSDSecondary-accn"`
+	Project             string         `xml:"INSDSeq_project"`
+	Keywords            []string       `xml:"INSDSeq_keywords>INSDKeyword"`
+	Segment             string         `xml:"INSDSeq_segment"`
+	Source              string         `xml:"INSDSeq_source"`
+	Organism            string         `xml:"INSDSeq_organism"`
+	Taxonomy            string         `xml:"INSDSeq_taxonomy"`
+	References          []Reference    `xml:"INSDSeq_references>INSDReference"`
+	Comment             string         `xml:"INSDSeq_comment"`
+	CommentSet          []Comment      `xml:"INSDSeq_comment-set>INSDComment"`
+	StrucComments       []StrucComment `xml:"INSDSeq_struc-comments>INSDStrucComment"`
+	Primary             string         `xml:"INSDSeq_primary"`
+	SourceDB            string         `xml:"INSDSeq_source-db"`
+	DatabaseReference   string         `xml:"INSDSeq_database-reference"`
+	Xrefs               []Xref         `xml:"INSDSeq_xrefs>INSDXref"`
+	FeatureTable        []Feature      `xml:"INSDSeq_feature-table>INSDFeature"`
+	FeatureSet          []FeatureSet   `xml:"INSDSeq_feature-set>INSDFeatureSet"`
+	Sequence            string         `xml:"INSDSeq_sequence"`
+	Contig              string         `xml:"INSDSeq_contig"`
+	AltSeq              []AltSeqData   `xml:"INSDSeq_alt-seq>INSDAltSeqData"`
+}
+
+// Lineage returns the taxonomic lineage of the source organism.
+func (s *Seq) Lineage() []string {
+	var lineage []string
+	for _, t := range strings.Split(strings.TrimSuffix(s.Taxonomy, "."), ";") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			lineage = append(lineage, t)
+		}
+	}
+	return lineage
+}
+
+// Reference is an INSDReference.
+type Reference struct {
+	Reference  string   `xml:"INSDReference_reference"`
+	Position   string   `xml:"INSDReference_position"`
+	Authors    []string `xml:"INSDReference_authors>INSDAuthor"`
+	Consortium string   `xml:"INSDReference_consortium"`
+	Title      string   `xml:"INSDReference_title"`
+	Journal    string   `xml:"INSDReference_journal"`
+	Xref       []Xref   `xml:"INSDReference_xref>INSDXref"`
+	PubMed     int      `xml:"INSDReference_pubmed"`
+	Remark     string   `xml:"INSDReference_remark"`
+}
+
+// Comment is an INSDComment.
+type Comment struct {
+	Type       string             `xml:"INSDComment_type"`
+	Paragraphs []CommentParagraph `xml:"INSDComment_paragraphs>INSDCommentParagraph"`
+}
+
+// CommentParagraph is an INSDCommentParagraph.
+type CommentParagraph struct {
+	Items []CommentItem `xml:"INSDCommentItem"`
+}
+
+// CommentItem is an INSDCommentItem.
+type CommentItem struct {
+	Value string `xml:"INSDCommentItem_value"`
+	URL   string `xml:"INSDCommentItem_url"`
+}
+
+// StrucComment is an INSDStrucComment.
+type StrucComment struct {
+	Name  string             `xml:"INSDStrucComment_name"`
+	Items []StrucCommentItem `xml:"INSDStrucComment_items>INSDStrucCommentItem"`
+}
+
+// StrucCommentItem is an INSDStrucCommentItem.
+type StrucCommentItem struct {
+	Tag   string `xml:"INSDStrucCommentItem_tag"`
+	Value string `xml:"INSDStrucCommentItem_value"`
+	URL   string `xml:"INSDStrucCommentItem_url"`
+}
+
+// FeatureSet is an INSDFeatureSet.
+type FeatureSet struct {
+	AnnotSource string    `xml:"INSDFeatureSet_annot-source"`
+	Features    []Feature `xml:"INSDFeatureSet_features>INSDFeature"`
+}
+
+// Feature is an INSDFeature.
+type Feature struct {
+	Key       string      `xml:"INSDFeature_key"`
+	Location  string      `xml:"INSDFeature_location"`
+	Intervals []Interval  `xml:"INSDFeature_intervals>INSDInterval"`
+	Operator  string      `xml:"INSDFeature_operator"`
+	Partial5  Flag        `xml:"INSDFeature_partial5"`
+	Partial3  Flag        `xml:"INSDFeature_partial3"`
+	Quals     []Qualifier `xml:"INSDFeature_quals>INSDQualifier"`
+	Xrefs     []Xref      `xml:"INSDFeature_xrefs>INSDXref"`
+}
+
+// Qualifier returns the value of the first qualifier of the feature with the given
+// name and whether it was found.
+func (f *Feature) Qualifier(name string) (value string, ok bool) {
+	for _, q := range f.Quals {
+		if q.Name == name {
+			return q.Value, true
+		}
+	}
+	return "", false
+}
+
+// Interval is an INSDInterval. Positions are one-based. The From position of an
+// interval on the complementary strand is greater than its To position.
+type Interval struct {
+	From      int    `xml:"INSDInterval_from"`
+	To        int    `xml:"INSDInterval_to"`
+	Point     int    `xml:"INSDInterval_point"`
+	IsComp    Flag   `xml:"INSDInterval_iscomp"`
+	InterBP   Flag   `xml:"INSDInterval_interbp"`
+	Accession string `xml:"INSDInterval_accession"`
+}
+
+// Qualifier is an INSDQualifier.
+type Qualifier struct {
+	Name  string `xml:"INSDQualifier_name"`
+	Value string `xml:"INSDQualifier_value"`
+}
+
+// Xref is an INSDXref.
+type Xref struct {
+	DBName string `xml:"INSDXref_dbname"`
+	ID     string `xml:"INSDXref_id"`
+}
+
+// AltSeqData is an INSDAltSeqData.
+type AltSeqData struct {
+	Name  string       `xml:"INSDAltSeqData_name"`
+	Items []AltSeqItem `xml:"INSDAltSeqData_items>INSDAltSeqItem"`
+}
+
+// AltSeqItem is an INSDAltSeqItem.
+type AltSeqItem struct {
+	Interval   *Interval `xml:"INSDAltSeqItem_interval>INSDInterval"`
+	IsGap      Flag      `xml:"INSDAltSeqItem_isgap"`
+	GapLength  int       `xml:"INSDAltSeqItem_gap-length"`
+	GapType    string    `xml:"INSDAltSeqItem_gap-type"`
+	GapLinkage string    `xml:"INSDAltSeqItem_gap-linkage"`
+	GapComment string    `xml:"INSDAltSeqItem_gap-comment"`
+	FirstAccn  string    `xml:"INSDAltSeqItem_first-accn"`
+	LastAccn   string    `xml:"INSDAltSeqItem_last-accn"`
+	Value      string    `xml:"INSDAltSeqItem_value"`
+}
+
+// Flag is a boolean element holding its value in a value attribute, for example
+// <INSDInterval_iscomp value="true"/>.
+type Flag bool
+
+var _ xml.Unmarshaler = (*Flag)(nil)
+
+func (f *Flag) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "value" {
+			*f = attr.Value == "true"
+		}
+	}
+	return dec.Skip()
+}
@@ -0,0 +1,324 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package insdseq
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/biogo/ncbi/entrez"
+	"github.com/biogo/ncbi/entrez/location"
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+const insdSet = `<?xml version="1.0" encoding="UTF-8"  ?>
+<!DOCTYPE INSDSet PUBLIC "-//NCBI//INSD INSDSeq/EN" "https://www.ncbi.nlm.nih.gov/dtd/INSD_INSDSeq.dtd">
+<INSDSet>
+  <INSDSeq>
+    <INSDSeq_locus>NM_000546</INSDSeq_locus>
+    <INSDSeq_length>1230</INSDSeq_length>
+    <INSDSeq_strandedness>single</INSDSeq_strandedness>
+    <INSDSeq_moltype>mRNA</INSDSeq_moltype>
+    <INSDSeq_topology>linear</INSDSeq_topology>
+    <INSDSeq_division>PRI</INSDSeq_division>
+    <INSDSeq_update-date>15-MAR-2020</INSDSeq_update-date>
+    <INSDSeq_definition>Homo sapiens tumor protein p53 (TP53), mRNA</INSDSeq_definition>
+    <INSDSeq_primary-accession>NM_000546</INSDSeq_primary-accession>
+    <INSDSeq_accession-version>NM_000546.6</INSDSeq_accession-version>
+    <INSDSeq_other-seqids>
+      <INSDSeqid>ref|NM_000546.6|</INSDSeqid>
+      <INSDSeqid>gi|371502114</INSDSeqid>
+    </INSDSeq_other-seqids>
+    <INSDSeq_keywords>
+      <INSDKeyword>RefSeq</INSDKeyword>
+      <INSDKeyword>MANE Select</INSDKeyword>
+    </INSDSeq_keywords>
+    <INSDSeq_source>Homo sapiens (human)</INSDSeq_source>
+    <INSDSeq_organism>Homo sapiens</INSDSeq_organism>
+    <INSDSeq_taxonomy>Eukaryota; Metazoa; Chordata; Homo</INSDSeq_taxonomy>
+    <INSDSeq_references>
+      <INSDReference>
+        <INSDReference_reference>1</INSDReference_reference>
+        <INSDReference_position>1..1230</INSDReference_position>
+        <INSDReference_authors>
+          <INSDAuthor>Smith,J.</INSDAuthor>
+          <INSDAuthor>Doe,A.</INSDAuthor>
+        </INSDReference_authors>
+        <INSDReference_title>A study of p53</INSDReference_title>
+        <INSDReference_journal>Nature 1 (2), 3-4 (2020)</INSDReference_journal>
+        <INSDReference_xref>
+          <INSDXref>
+            <INSDXref_dbname>doi</INSDXref_dbname>
+            <INSDXref_id>10.1000/xyz</INSDXref_id>
+          </INSDXref>
+        </INSDReference_xref>
+        <INSDReference_pubmed>12345678</INSDReference_pubmed>
+      </INSDReference>
+    </INSDSeq_references>
+    <INSDSeq_comment>REVIEWED REFSEQ</INSDSeq_comment>
+    <INSDSeq_struc-comments>
+      <INSDStrucComment>
+        <INSDStrucComment_name>Evidence-Data</INSDStrucComment_name>
+        <INSDStrucComment_items>
+          <INSDStrucCommentItem>
+            <INSDStrucCommentItem_tag>Transcript exon combination</INSDStrucCommentItem_tag>
+            <INSDStrucCommentItem_value>AB082923.1</INSDStrucCommentItem_value>
+          </INSDStrucCommentItem>
+        </INSDStrucComment_items>
+      </INSDStrucComment>
+    </INSDSeq_struc-comments>
+    <INSDSeq_feature-table>
+      <INSDFeature>
+        <INSDFeature_key>CDS</INSDFeature_key>
+        <INSDFeature_location>complement(join(&lt;12..30,40..120))</INSDFeature_location>
+        <INSDFeature_intervals>
+          <INSDInterval>
+            <INSDInterval_from>120</INSDInterval_from>
+            <INSDInterval_to>40</INSDInterval_to>
+            <INSDInterval_iscomp value="true"/>
+            <INSDInterval_accession>NM_000546.6</INSDInterval_accession>
+          </INSDInterval>
+          <INSDInterval>
+            <INSDInterval_from>30</INSDInterval_from>
+            <INSDInterval_to>12</INSDInterval_to>
+            <INSDInterval_iscomp value="true"/>
+            <INSDInterval_accession>NM_000546.6</INSDInterval_accession>
+          </INSDInterval>
+        </INSDFeature_intervals>
+        <INSDFeature_operator>join</INSDFeature_operator>
+        <INSDFeature_partial3 value="true"/>
+        <INSDFeature_quals>
+          <INSDQualifier>
+            <INSDQualifier_name>gene</INSDQualifier_name>
+            <INSDQualifier_value>TP53</INSDQualifier_value>
+          </INSDQualifier>
+          <INSDQualifier>
+            <INSDQualifier_name>pseudo</INSDQualifier_name>
+          </INSDQualifier>
+        </INSDFeature_quals>
+        <INSDFeature_xrefs>
+          <INSDXref>
+            <INSDXref_dbname>GeneID</INSDXref_dbname>
+            <INSDXref_id>7157</INSDXref_id>
+          </INSDXref>
+        </INSDFeature_xrefs>
+      </INSDFeature>
+      <INSDFeature>
+        <INSDFeature_key>misc_feature</INSDFeature_key>
+        <INSDFeature_location>order(5,600^601,AB082923.1:1..20)</INSDFeature_location>
+      </INSDFeature>
+      <INSDFeature>
+        <INSDFeature_key>site</INSDFeature_key>
+        <INSDFeature_location>10^11</INSDFeature_location>
+        <INSDFeature_intervals>
+          <INSDInterval>
+            <INSDInterval_from>10</INSDInterval_from>
+            <INSDInterval_to>11</INSDInterval_to>
+            <INSDInterval_interbp value="true"/>
+            <INSDInterval_accession>NM_000546.6</INSDInterval_accession>
+          </INSDInterval>
+          <INSDInterval>
+            <INSDInterval_point>7</INSDInterval_point>
+            <INSDInterval_accession>NM_000546.6</INSDInterval_accession>
+          </INSDInterval>
+        </INSDFeature_intervals>
+      </INSDFeature>
+    </INSDSeq_feature-table>
+    <INSDSeq_sequence>gatgggattggggttttcccc</INSDSeq_sequence>
+  </INSDSeq>
+</INSDSet>
+`
+
+const gbSet = `<?xml version="1.0" encoding="UTF-8"  ?>
+<!DOCTYPE GBSet PUBLIC "-//NCBI//NCBI GBSeq/EN" "https://www.ncbi.nlm.nih.gov/dtd/NCBI_GBSeq.dtd">
+<GBSet>
+  <GBSeq>
+    <GBSeq_locus>NP_000537</GBSeq_locus>
+    <GBSeq_length>393</GBSeq_length>
+    <GBSeq_moltype>AA</GBSeq_moltype>
+    <GBSeq_primary-accession>NP_000537</GBSeq_primary-accession>
+    <GBSeq_accession-version>NP_000537.3</GBSeq_accession-version>
+    <GBSeq_feature-table>
+      <GBFeature>
+        <GBFeature_key>Protein</GBFeature_key>
+        <GBFeature_location>1..393</GBFeature_location>
+        <GBFeature_intervals>
+          <GBInterval>
+            <GBInterval_from>1</GBInterval_from>
+            <GBInterval_to>393</GBInterval_to>
+            <GBInterval_accession>NP_000537.3</GBInterval_accession>
+          </GBInterval>
+        </GBFeature_intervals>
+        <GBFeature_quals>
+          <GBQualifier>
+            <GBQualifier_name>product</GBQualifier_name>
+            <GBQualifier_value>cellular tumor antigen p53</GBQualifier_value>
+          </GBQualifier>
+        </GBFeature_quals>
+      </GBFeature>
+    </GBSeq_feature-table>
+    <GBSeq_sequence>meepqsdpsv</GBSeq_sequence>
+  </GBSeq>
+  <GBSeq>
+    <GBSeq_locus>NP_001119584</GBSeq_locus>
+    <GBSeq_length>261</GBSeq_length>
+    <GBSeq_moltype>AA</GBSeq_moltype>
+  </GBSeq>
+</GBSet>
+`
+
+func (s *S) TestReader(c *check.C) {
+	r := NewReader(strings.NewReader(insdSet))
+	var seqs []Seq
+	for r.Next() {
+		seqs = append(seqs, *r.Seq())
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Assert(seqs, check.HasLen, 1)
+
+	seq := seqs[0]
+	c.Check(seq.Locus, check.Equals, "NM_000546")
+	c.Check(seq.Length, check.Equals, 1230)
+	c.Check(seq.MolType, check.Equals, "mRNA")
+	c.Check(seq.AccessionVersion, check.Equals, "NM_000546.6")
+	c.Check(seq.OtherSeqIDs, check.DeepEquals, []string{"ref|NM_000546.6|", "gi|371502114"})
+	c.Check(seq.Keywords, check.DeepEquals, []string{"RefSeq", "MANE Select"})
+	c.Check(seq.Lineage(), check.DeepEquals, []string{"Eukaryota", "Metazoa", "Chordata", "Homo"})
+	c.Check(seq.References, check.DeepEquals, []Reference{{
+		Reference: "1",
+		Position:  "1..1230",
+		Authors:   []string{"Smith,J.", "Doe,A."},
+		Title:     "A study of p53",
+		Journal:   "Nature 1 (2), 3-4 (2020)",
+		Xref:      []Xref{{DBName: "doi", ID: "10.1000/xyz"}},
+		PubMed:    12345678,
+	}})
+	c.Check(seq.StrucComments, check.DeepEquals, []StrucComment{{
+		Name:  "Evidence-Data",
+		Items: []StrucCommentItem{{Tag: "Transcript exon combination", Value: "AB082923.1"}},
+	}})
+	c.Check(seq.Sequence, check.Equals, "gatgggattggggttttcccc")
+
+	c.Assert(seq.FeatureTable, check.HasLen, 3)
+	cds := seq.FeatureTable[0]
+	c.Check(cds.Location, check.Equals, "complement(join(<12..30,40..120))")
+	c.Check(cds.Operator, check.Equals, "join")
+	c.Check(cds.Partial5, check.Equals, Flag(false))
+	c.Check(cds.Partial3, check.Equals, Flag(true))
+	c.Check(cds.Intervals[0].IsComp, check.Equals, Flag(true))
+	gene, ok := cds.Qualifier("gene")
+	c.Check(gene, check.Equals, "TP53")
+	c.Check(ok, check.Equals, true)
+	_, ok = cds.Qualifier("pseudo")
+	c.Check(ok, check.Equals, true)
+	c.Check(cds.Xrefs, check.DeepEquals, []Xref{{DBName: "GeneID", ID: "7157"}})
+
+	var set Set
+	err := xml.Unmarshal([]byte(insdSet), &set)
+	c.Assert(err, check.Equals, nil)
+	c.Check(set.Seqs, check.DeepEquals, seqs)
+}
+
+func (s *S) TestReaderGBSet(c *check.C) {
+	r := NewReader(strings.NewReader(gbSet))
+	var seqs []Seq
+	for r.Next() {
+		seqs = append(seqs, *r.Seq())
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+	c.Assert(seqs, check.HasLen, 2)
+	c.Check(seqs[0].Locus, check.Equals, "NP_000537")
+	c.Check(seqs[0].AccessionVersion, check.Equals, "NP_000537.3")
+	c.Check(seqs[0].Sequence, check.Equals, "meepqsdpsv")
+	c.Check(seqs[0].FeatureTable, check.DeepEquals, []Feature{{
+		Key:       "Protein",
+		Location:  "1..393",
+		Intervals: []Interval{{From: 1, To: 393, Accession: "NP_000537.3"}},
+		Quals:     []Qualifier{{Name: "product", Value: "cellular tumor antigen p53"}},
+	}})
+	c.Check(seqs[1].Locus, check.Equals, "NP_001119584")
+	c.Check(seqs[1].Length, check.Equals, 261)
+}
+
+func (s *S) TestReaderErr(c *check.C) {
+	r := NewReader(strings.NewReader(`<eFetchResult><ERROR>Cannot retrieve</ERROR></eFetchResult>`))
+	c.Check(r.Next(), check.Equals, false)
+	c.Check(r.Err(), check.DeepEquals, &entrez.Error{Util: "efetch", Msg: "Cannot retrieve"})
+
+	r = NewReader(strings.NewReader(insdSet[:len(insdSet)/2]))
+	for r.Next() {
+	}
+	c.Check(r.Err(), check.NotNil)
+}
+
+func (s *S) TestSpans(c *check.C) {
+	r := NewReader(strings.NewReader(insdSet))
+	c.Assert(r.Next(), check.Equals, true)
+	feats := r.Seq().FeatureTable
+
+	for i, want := range [][]location.Span{
+		{
+			{Accession: "NM_000546.6", Start: 39, End: 120, Strand: -1},
+			{Accession: "NM_000546.6", Start: 11, End: 30, Strand: -1},
+		},
+		{
+			{Start: 4, End: 5, Strand: 1},
+			{Start: 600, End: 600, Strand: 1},
+			{Accession: "AB082923.1", Start: 0, End: 20, Strand: 1},
+		},
+		{
+			{Accession: "NM_000546.6", Start: 10, End: 10, Strand: 1},
+			{Accession: "NM_000546.6", Start: 6, End: 7, Strand: 1},
+		},
+	} {
+		got, err := feats[i].Spans()
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, want, check.Commentf("feature %d", i))
+	}
+
+	// Spans from the location agree with spans from the intervals.
+	f := feats[0]
+	f.Intervals = nil
+	got, err := f.Spans()
+	c.Check(err, check.Equals, nil)
+	c.Check(got, check.DeepEquals, []location.Span{{Start: 39, End: 120, Strand: -1}, {Start: 11, End: 30, Strand: -1, PartialStart: true}})
+
+	f.Location = "join(1.."
+	_, err = f.Spans()
+	c.Check(err, check.NotNil)
+}
+
+func (s *S) TestExtract(c *check.C) {
+	r := NewReader(strings.NewReader(insdSet))
+	c.Assert(r.Next(), check.Equals, true)
+	seq := r.Seq()
+
+	for _, t := range []struct {
+		loc  string
+		want string
+		err  string
+	}{
+		{loc: "complement(1..3)", want: "atc"},
+		{loc: "join(2..4,NM_000546.6:1..2)", want: "atgga"},
+		{loc: "join(1..2,AB082923.1:1..4)", err: "insdseq: no sequence for remote accession AB082923.1"},
+		{loc: "join(1..", err: `location: invalid location "join\(1..": missing position`},
+	} {
+		got, err := seq.Extract(&Feature{Location: t.loc}, nil)
+		if t.err != "" {
+			c.Check(err, check.ErrorMatches, t.err, check.Commentf("%q", t.loc))
+			continue
+		}
+		c.Check(err, check.Equals, nil)
+		c.Check(string(got), check.Equals, t.want, check.Commentf("%q", t.loc))
+	}
+}
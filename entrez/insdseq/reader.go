@@ -0,0 +1,83 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package insdseq
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/biogo/ncbi/entrez"
+)
+
+// A Reader reads the sequences of an INSDSet or GBSet one at a time, allowing large
+// responses to be processed without holding them in memory.
+type Reader struct {
+	r   *entrez.ElementReader
+	seq Seq
+}
+
+// NewReader returns a Reader that reads an INSDSet or GBSet from r. If r is an io.Closer,
+// it is closed by the Reader's Close method.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: entrez.NewElementTokenReader(r, &gbTokens{dec: xml.NewDecoder(r)}, "efetch")}
+}
+
+// Next advances the Reader to the next sequence, which is then available through the
+// Seq method. It returns false when there are no more sequences or an error occurs.
+func (r *Reader) Next() bool {
+	r.seq = Seq{}
+	return r.r.Next("INSDSeq", &r.seq)
+}
+
+// Seq returns the current sequence.
+func (r *Reader) Seq() *Seq { return &r.seq }
+
+// Err returns the first error encountered by the Reader. Errors reported by an EFetch
+// error response are returned as an *entrez.Error.
+func (r *Reader) Err() error { return r.r.Err() }
+
+// Close closes the underlying stream. If the stream has been read to the end, any
+// trailing data is read so that the connection may be reused.
+func (r *Reader) Close() error { return r.r.Close() }
+
+// gbTokens is an xml.TokenReader that renames the elements of a GBSet to the
+// corresponding INSDSet elements. The elements of an INSDSet are not altered.
+type gbTokens struct {
+	dec  *xml.Decoder
+	root bool
+	gb   bool
+}
+
+func (r *gbTokens) Token() (xml.Token, error) {
+	t, err := r.dec.Token()
+	if err != nil {
+		return t, err
+	}
+	switch e := t.(type) {
+	case xml.StartElement:
+		if !r.root {
+			r.root = true
+			r.gb = e.Name.Local == "GBSet"
+		}
+		if r.gb {
+			e.Name.Local = rename(e.Name.Local)
+		}
+		return e, nil
+	case xml.EndElement:
+		if r.gb {
+			e.Name.Local = rename(e.Name.Local)
+		}
+		return e, nil
+	}
+	return t, nil
+}
+
+func rename(name string) string {
+	if strings.HasPrefix(name, "GB") {
+		return "INSD" + name[len("GB"):]
+	}
+	return name
+}
@@ -0,0 +1,74 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package insdseq
+
+import (
+	"fmt"
+
+	"github.com/biogo/ncbi/entrez/location"
+)
+
+// Span returns the interval as a zero-based half-open span.
+func (iv *Interval) Span() location.Span {
+	s := location.Span{Accession: iv.Accession, Strand: 1}
+	if iv.IsComp {
+		s.Strand = -1
+	}
+	switch {
+	case iv.Point != 0 && bool(iv.InterBP):
+		s.Start, s.End = iv.Point, iv.Point
+	case iv.Point != 0:
+		s.Start, s.End = iv.Point-1, iv.Point
+	default:
+		from, to := iv.From, iv.To
+		if from > to {
+			from, to = to, from
+			s.Strand = -1
+		}
+		if iv.InterBP {
+			s.Start, s.End = from, from
+		} else {
+			s.Start, s.End = from-1, to
+		}
+	}
+	return s
+}
+
+// Spans returns the spans of the feature in biological order. The spans are taken
+// from the feature's intervals if they are present and otherwise from its location.
+func (f *Feature) Spans() ([]location.Span, error) {
+	if len(f.Intervals) == 0 {
+		l, err := location.Parse(f.Location)
+		if err != nil {
+			return nil, err
+		}
+		return location.Spans(l)
+	}
+	spans := make([]location.Span, len(f.Intervals))
+	for i := range f.Intervals {
+		spans[i] = f.Intervals[i].Span()
+	}
+	return spans, nil
+}
+
+// Extract returns the part of the sequence described by the location of f. Parts
+// of the location held in other sequences are taken from the sequence returned by
+// remote for their accession.
+func (s *Seq) Extract(f *Feature, remote func(accession string) ([]byte, error)) ([]byte, error) {
+	l, err := location.Parse(f.Location)
+	if err != nil {
+		return nil, err
+	}
+	seq := []byte(s.Sequence)
+	return location.Extract(l, seq, func(acc string) ([]byte, error) {
+		if acc == s.AccessionVersion {
+			return seq, nil
+		}
+		if remote == nil {
+			return nil, fmt.Errorf("insdseq: no sequence for remote accession %s", acc)
+		}
+		return remote(acc)
+	})
+}
@@ -101,3 +101,15 @@ type Link struct {
 	LinkSets []link.LinkSet `xml:"LinkSet"`
 	Err      *string        `xml:"ERROR"`
 }
+
+func (l *Link) reportsError() bool { return l.Err != nil }
+
+// partialErr returns a *PartialError holding the errors reported by ELink for
+// individual link sets, or nil if there are none.
+func (l *Link) partialErr() error {
+	e := &PartialError{Util: "elink"}
+	for _, ls := range l.LinkSets {
+		e.Errors = append(e.Errors, ls.Err...)
+	}
+	return e.err()
+}
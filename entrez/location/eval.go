@@ -0,0 +1,198 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package location
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Span is a zero-based half-open interval of a sequence. A site between two adjacent
+// bases is represented by a Span with equal Start and End.
+type Span struct {
+	// Accession is the accession.version of
+	// the sequence holding the span if it is
+	// not the sequence of the record.
+	Accession string
+
+	Start, End int
+
+	// Strand is 1 for the given strand and -1
+	// for the complementary strand.
+	Strand int
+
+	// PartialStart and PartialEnd indicate
+	// that the span extends beyond its Start
+	// or End.
+	PartialStart, PartialEnd bool
+}
+
+func (r *Range) spans(dst []Span, comp bool) ([]Span, error) {
+	s := Span{
+		Accession:    r.Accession,
+		Strand:       1,
+		PartialStart: r.Start.Fuzz == Before,
+		PartialEnd:   r.End.Fuzz == After,
+	}
+	if comp {
+		s.Strand = -1
+	}
+	switch r.Kind {
+	case SingleBase:
+		s.Start, s.End = r.Start.min()-1, r.Start.max()
+	case Site:
+		s.Start, s.End = r.Start.min(), r.Start.min()
+	default:
+		s.Start, s.End = r.Start.min()-1, r.End.max()
+	}
+	if s.Start < 0 || s.End < s.Start {
+		return nil, fmt.Errorf("location: invalid range: %v", r)
+	}
+	return append(dst, s), nil
+}
+
+func (o *Operator) spans(dst []Span, comp bool) ([]Span, error) {
+	var err error
+	switch o.Name {
+	case "complement":
+		n := len(dst)
+		for _, a := range o.Args {
+			dst, err = a.spans(dst, !comp)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reverse(dst[n:])
+		return dst, nil
+	case "join", "order", "bond":
+		for _, a := range o.Args {
+			dst, err = a.spans(dst, comp)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	case "one-of":
+		// The first of the alternatives is
+		// taken as the location.
+		if len(o.Args) == 0 {
+			return nil, errors.New("location: empty one-of")
+		}
+		return o.Args[0].spans(dst, comp)
+	}
+	return nil, fmt.Errorf("location: unknown operator: %s", o.Name)
+}
+
+func (g *Gap) spans(dst []Span, comp bool) ([]Span, error) {
+	return nil, fmt.Errorf("location: %v has no span", g)
+}
+
+func reverse(s []Span) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// Spans returns the spans described by l in biological order. Spans on the
+// complementary strand are given in the order they are read on that strand.
+func Spans(l Location) ([]Span, error) {
+	return l.spans(nil, false)
+}
+
+// Strand returns the strand of l: 1 if all of its spans are on the given strand, -1
+// if all are on the complementary strand and 0 if the spans are on both strands.
+func Strand(l Location) (int, error) {
+	spans, err := Spans(l)
+	if err != nil {
+		return 0, err
+	}
+	strand := 0
+	for i, s := range spans {
+		if i == 0 {
+			strand = s.Strand
+		} else if s.Strand != strand {
+			return 0, nil
+		}
+	}
+	return strand, nil
+}
+
+// Partial returns whether the location extends beyond its 5' or 3' end as read on
+// the strand of the location.
+func Partial(l Location) (five, three bool, err error) {
+	spans, err := Spans(l)
+	if err != nil || len(spans) == 0 {
+		return false, false, err
+	}
+	first, last := spans[0], spans[len(spans)-1]
+	if first.Strand < 0 {
+		five = first.PartialEnd
+	} else {
+		five = first.PartialStart
+	}
+	if last.Strand < 0 {
+		three = last.PartialStart
+	} else {
+		three = last.PartialEnd
+	}
+	return five, three, nil
+}
+
+// Extract returns the subsequence of seq described by l. Spans on the complementary
+// strand are reverse complemented using the IUPAC nucleotide codes. Spans held in
+// other sequences are taken from the sequence returned by remote for their accession;
+// if remote is nil, remote spans are an error.
+func Extract(l Location, seq []byte, remote func(accession string) ([]byte, error)) ([]byte, error) {
+	spans, err := Spans(l)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for _, s := range spans {
+		src := seq
+		if s.Accession != "" {
+			if remote == nil {
+				return nil, fmt.Errorf("location: no sequence for remote accession %s", s.Accession)
+			}
+			src, err = remote(s.Accession)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if s.End > len(src) {
+			return nil, fmt.Errorf("location: span %d..%d out of range of sequence length %d", s.Start+1, s.End, len(src))
+		}
+		n := len(buf)
+		buf = append(buf, src[s.Start:s.End]...)
+		if s.Strand < 0 {
+			reverseComplement(buf[n:])
+		}
+	}
+	return buf, nil
+}
+
+// complement holds the IUPAC nucleotide complements. Other bytes are their own
+// complement.
+var complement [256]byte
+
+func init() {
+	for i := range complement {
+		complement[i] = byte(i)
+	}
+	const (
+		bases = "ACGTUMRWSYKVHDBN"
+		comps = "TGCAAKYWSRMBDHVN"
+	)
+	for i := range bases {
+		complement[bases[i]] = comps[i]
+		complement[bases[i]|0x20] = comps[i] | 0x20
+	}
+}
+
+func reverseComplement(s []byte) {
+	for i, j := 0, len(s)-1; i <= j; i, j = i+1, j-1 {
+		s[i], s[j] = complement[s[j]], complement[s[i]]
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package location provides a parser and evaluator for feature locations written in
+// the INSDC location syntax, as used by GenBank flat files and INSDSeq XML.
+//
+// Locations such as
+//
+//	join(complement(12..>200),300..400)
+//	order(<1..60,AB123.1:10..20)
+//	one-of(1888,1901)..2200
+//
+// are parsed into a tree of Range and Operator values, which may be evaluated to
+// zero-based half-open spans and used to extract the subsequence of a record that
+// a feature refers to.
+package location
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Location is a feature location. A Location is a *Range, an *Operator or a *Gap.
+type Location interface {
+	// String returns the location in the INSDC
+	// location syntax.
+	String() string
+
+	spans(dst []Span, comp bool) ([]Span, error)
+}
+
+// Kind is the kind of a Range.
+type Kind int
+
+const (
+	// BaseRange is a range of bases, 340..565.
+	BaseRange Kind = iota
+
+	// SingleBase is a single base, 467.
+	SingleBase
+
+	// Site is a site between two adjacent
+	// bases, 123^124.
+	Site
+
+	// Within is a single base within a range
+	// of bases, 102.110.
+	Within
+)
+
+// Fuzz indicates that a position is beyond the given position.
+type Fuzz int
+
+const (
+	// Exact is an exact position.
+	Exact Fuzz = iota
+
+	// Before is a position before the given
+	// position, <345.
+	Before
+
+	// After is a position after the given
+	// position, >500.
+	After
+)
+
+// Position is a one-based position in a sequence.
+type Position struct {
+	Pos  int
+	Fuzz Fuzz
+
+	// OneOf holds the alternative positions of
+	// a position written as one-of(1888,1901).
+	// If OneOf is not empty, Pos is ignored.
+	OneOf []int
+}
+
+// min returns the lowest possible value of the position.
+func (p Position) min() int {
+	if len(p.OneOf) == 0 {
+		return p.Pos
+	}
+	m := p.OneOf[0]
+	for _, v := range p.OneOf[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// max returns the highest possible value of the position.
+func (p Position) max() int {
+	if len(p.OneOf) == 0 {
+		return p.Pos
+	}
+	m := p.OneOf[0]
+	for _, v := range p.OneOf[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func (p Position) String() string {
+	if len(p.OneOf) != 0 {
+		s := make([]string, len(p.OneOf))
+		for i, v := range p.OneOf {
+			s[i] = strconv.Itoa(v)
+		}
+		return "one-of(" + strings.Join(s, ",") + ")"
+	}
+	switch p.Fuzz {
+	case Before:
+		return "<" + strconv.Itoa(p.Pos)
+	case After:
+		return ">" + strconv.Itoa(p.Pos)
+	}
+	return strconv.Itoa(p.Pos)
+}
+
+// Range is a base, range of bases or site in a sequence. The End of a SingleBase
+// Range is equal to its Start.
+type Range struct {
+	// Accession is the accession.version of
+	// the sequence holding a remote location.
+	Accession string
+
+	Kind       Kind
+	Start, End Position
+}
+
+func (r *Range) String() string {
+	var buf strings.Builder
+	if r.Accession != "" {
+		buf.WriteString(r.Accession)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(r.Start.String())
+	switch r.Kind {
+	case SingleBase:
+		return buf.String()
+	case Site:
+		buf.WriteByte('^')
+	case Within:
+		buf.WriteByte('.')
+	default:
+		buf.WriteString("..")
+	}
+	buf.WriteString(r.End.String())
+	return buf.String()
+}
+
+// Operator is an operation on a list of locations. The operators of the INSDC
+// syntax are complement, join, order, bond and one-of.
+type Operator struct {
+	Name string
+	Args []Location
+}
+
+func (o *Operator) String() string {
+	var buf strings.Builder
+	buf.WriteString(o.Name)
+	buf.WriteByte('(')
+	for i, a := range o.Args {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(a.String())
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// Gap is a gap of known or unknown length between the parts of a sequence constructed
+// from other sequences, written as gap(100), gap(unk100) or gap().
+type Gap struct {
+	// Length is the length of the gap. It is an
+	// estimate if Unknown is true, and zero if
+	// no estimate is given.
+	Length  int
+	Unknown bool
+}
+
+func (g *Gap) String() string {
+	switch {
+	case g.Unknown && g.Length == 0:
+		return "gap()"
+	case g.Unknown:
+		return "gap(unk" + strconv.Itoa(g.Length) + ")"
+	}
+	return "gap(" + strconv.Itoa(g.Length) + ")"
+}
+
+// Parse parses a location written in the INSDC location syntax. White space in s
+// is ignored.
+func Parse(s string) (Location, error) {
+	s = strings.Join(strings.Fields(s), "")
+	p := parser{s: s}
+	l, err := p.location()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(s) {
+		return nil, p.errorf("unexpected %q", s[p.pos:])
+	}
+	return l, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("location: invalid location %q: %s", p.s, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) location() (Location, error) {
+	name := p.name()
+	if name == "" || !strings.HasPrefix(p.s[p.pos+len(name):], "(") {
+		return p.rangeOf()
+	}
+	if name == "one-of" {
+		// one-of may be a position at the
+		// start of a range or an operator.
+		start := p.pos
+		if r, err := p.rangeOf(); err == nil && r.Kind != SingleBase {
+			return r, nil
+		}
+		p.pos = start
+	}
+	p.pos += len(name) + 1
+	if name == "gap" {
+		return p.gap()
+	}
+	o := &Operator{Name: name}
+	for {
+		l, err := p.location()
+		if err != nil {
+			return nil, err
+		}
+		o.Args = append(o.Args, l)
+		if p.pos == len(p.s) {
+			return nil, p.errorf("missing )")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		if c == ')' {
+			return o, nil
+		}
+		if c != ',' {
+			return nil, p.errorf("unexpected %q", c)
+		}
+	}
+}
+
+// gap parses the arguments of a gap. The opening parenthesis has been consumed.
+func (p *parser) gap() (*Gap, error) {
+	var g Gap
+	if strings.HasPrefix(p.s[p.pos:], "unk") {
+		g.Unknown = true
+		p.pos += len("unk")
+	}
+	if strings.HasPrefix(p.s[p.pos:], ")") {
+		p.pos++
+		g.Unknown = true
+		return &g, nil
+	}
+	var err error
+	g.Length, err = p.number()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(p.s[p.pos:], ")") {
+		return nil, p.errorf("missing )")
+	}
+	p.pos++
+	return &g, nil
+}
+
+// name returns the operator name at the current position, if any.
+func (p *parser) name() string {
+	end := p.pos
+	for end < len(p.s) && ('a' <= p.s[end] && p.s[end] <= 'z' || p.s[end] == '-' || p.s[end] == '_') {
+		end++
+	}
+	return p.s[p.pos:end]
+}
+
+func (p *parser) rangeOf() (*Range, error) {
+	var r Range
+
+	// An accession is followed by a colon
+	// before any punctuation of the range.
+	if i := strings.IndexAny(p.s[p.pos:], ":(),<>^"); i > 0 && p.s[p.pos+i] == ':' {
+		r.Accession = p.s[p.pos : p.pos+i]
+		p.pos += i + 1
+	}
+
+	var err error
+	r.Start, err = p.position()
+	if err != nil {
+		return nil, err
+	}
+	rest := p.s[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, ".."):
+		r.Kind = BaseRange
+		p.pos += 2
+	case strings.HasPrefix(rest, "^"):
+		r.Kind = Site
+		p.pos++
+	case strings.HasPrefix(rest, "."):
+		r.Kind = Within
+		p.pos++
+	default:
+		r.Kind = SingleBase
+		r.End = r.Start
+		return &r, nil
+	}
+	r.End, err = p.position()
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (p *parser) position() (Position, error) {
+	var pos Position
+	if strings.HasPrefix(p.s[p.pos:], "one-of(") {
+		p.pos += len("one-of(")
+		for {
+			n, err := p.number()
+			if err != nil {
+				return pos, err
+			}
+			pos.OneOf = append(pos.OneOf, n)
+			if p.pos == len(p.s) {
+				return pos, p.errorf("missing )")
+			}
+			c := p.s[p.pos]
+			p.pos++
+			if c == ')' {
+				return pos, nil
+			}
+			if c != ',' {
+				return pos, p.errorf("unexpected %q", c)
+			}
+		}
+	}
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '<':
+			pos.Fuzz = Before
+			p.pos++
+		case '>':
+			pos.Fuzz = After
+			p.pos++
+		}
+	}
+	var err error
+	pos.Pos, err = p.number()
+	return pos, err
+}
+
+func (p *parser) number() (int, error) {
+	end := p.pos
+	for end < len(p.s) && '0' <= p.s[end] && p.s[end] <= '9' {
+		end++
+	}
+	if end == p.pos {
+		if end == len(p.s) {
+			return 0, p.errorf("missing position")
+		}
+		return 0, p.errorf("unexpected %q", p.s[end:])
+	}
+	n, err := strconv.Atoi(p.s[p.pos:end])
+	if err != nil {
+		return 0, p.errorf("%v", err)
+	}
+	p.pos = end
+	return n, nil
+}
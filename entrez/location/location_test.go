@@ -0,0 +1,172 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package location
+
+import (
+	"fmt"
+	"testing"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestParse(c *check.C) {
+	for _, t := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "467", want: "467"},
+		{in: "340..565", want: "340..565"},
+		{in: "<345..500", want: "<345..500"},
+		{in: "<1..>888", want: "<1..>888"},
+		{in: ">1", want: ">1"},
+		{in: "102.110", want: "102.110"},
+		{in: "123^124", want: "123^124"},
+		{in: "J00194.1:100..202", want: "J00194.1:100..202"},
+		{in: "join(12..78, 134..202)", want: "join(12..78,134..202)"},
+		{in: "complement(join(2691..4571,4918..5163))", want: "complement(join(2691..4571,4918..5163))"},
+		{in: "one-of(1888,1901)..2200", want: "one-of(1888,1901)..2200"},
+		{in: "one-of(1..10,20..30)", want: "one-of(1..10,20..30)"},
+		{in: "join(1..100,gap(unk100),201..300)", want: "join(1..100,gap(unk100),201..300)"},
+		{in: "gap()", want: "gap()"},
+		{in: "gap(50)", want: "gap(50)"},
+		{in: "join(1..2,", err: true},
+		{in: "1..x", err: true},
+		{in: "gap(x)", err: true},
+		{in: "", err: true},
+	} {
+		l, err := Parse(t.in)
+		if t.err {
+			c.Check(err, check.NotNil, check.Commentf("%q", t.in))
+			continue
+		}
+		c.Assert(err, check.Equals, nil, check.Commentf("%q", t.in))
+		c.Check(l.String(), check.Equals, t.want)
+	}
+}
+
+func (s *S) TestParseTree(c *check.C) {
+	l, err := Parse("complement(order(600^601,700.710,>720))")
+	c.Assert(err, check.Equals, nil)
+	c.Check(l, check.DeepEquals, &Operator{Name: "complement", Args: []Location{
+		&Operator{Name: "order", Args: []Location{
+			&Range{Kind: Site, Start: Position{Pos: 600}, End: Position{Pos: 601}},
+			&Range{Kind: Within, Start: Position{Pos: 700}, End: Position{Pos: 710}},
+			&Range{Kind: SingleBase, Start: Position{Pos: 720, Fuzz: After}, End: Position{Pos: 720, Fuzz: After}},
+		}},
+	}})
+
+	l, err = Parse("one-of(1888,1901)..2200")
+	c.Assert(err, check.Equals, nil)
+	c.Check(l, check.DeepEquals, &Range{Start: Position{OneOf: []int{1888, 1901}}, End: Position{Pos: 2200}})
+}
+
+func (s *S) TestSpans(c *check.C) {
+	for _, t := range []struct {
+		in     string
+		want   []Span
+		strand int
+		five   bool
+		three  bool
+	}{
+		{
+			in:     "<1..>30",
+			want:   []Span{{Start: 0, End: 30, Strand: 1, PartialStart: true, PartialEnd: true}},
+			strand: 1, five: true, three: true,
+		},
+		{
+			in: "join(complement(<12..20),complement(30..40))",
+			want: []Span{
+				{Start: 11, End: 20, Strand: -1, PartialStart: true},
+				{Start: 29, End: 40, Strand: -1},
+			},
+			strand: -1,
+		},
+		{
+			in: "complement(join(<12..20,30..>40))",
+			want: []Span{
+				{Start: 29, End: 40, Strand: -1, PartialEnd: true},
+				{Start: 11, End: 20, Strand: -1, PartialStart: true},
+			},
+			strand: -1, five: true, three: true,
+		},
+		{
+			in: "order(5,9^10,AB123.1:1..4,complement(20..25))",
+			want: []Span{
+				{Start: 4, End: 5, Strand: 1},
+				{Start: 9, End: 9, Strand: 1},
+				{Accession: "AB123.1", Start: 0, End: 4, Strand: 1},
+				{Start: 19, End: 25, Strand: -1},
+			},
+			strand: 0,
+		},
+		{
+			in:     "one-of(1888,1901)..2200",
+			want:   []Span{{Start: 1887, End: 2200, Strand: 1}},
+			strand: 1,
+		},
+	} {
+		l, err := Parse(t.in)
+		c.Assert(err, check.Equals, nil)
+		spans, err := Spans(l)
+		c.Check(err, check.Equals, nil)
+		c.Check(spans, check.DeepEquals, t.want, check.Commentf("%q", t.in))
+		strand, err := Strand(l)
+		c.Check(err, check.Equals, nil)
+		c.Check(strand, check.Equals, t.strand, check.Commentf("%q", t.in))
+		five, three, err := Partial(l)
+		c.Check(err, check.Equals, nil)
+		c.Check([]bool{five, three}, check.DeepEquals, []bool{t.five, t.three}, check.Commentf("%q", t.in))
+	}
+
+	l, err := Parse("join(1..10,gap(10),21..30)")
+	c.Assert(err, check.Equals, nil)
+	_, err = Spans(l)
+	c.Check(err, check.ErrorMatches, "location: gap\\(10\\) has no span")
+}
+
+func (s *S) TestExtract(c *check.C) {
+	const seq = "aaccGGTTacgtRYkmNN"
+	remote := func(acc string) ([]byte, error) {
+		if acc != "AB123.1" {
+			return nil, fmt.Errorf("no sequence for %s", acc)
+		}
+		return []byte("TTTTGGGG"), nil
+	}
+	for _, t := range []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "1..4", want: "aacc"},
+		{in: "complement(1..8)", want: "AACCggtt"},
+		{in: "join(1..2,7..8)", want: "aaTT"},
+		{in: "complement(join(1..2,13..16))", want: "kmRYtt"},
+		{in: "join(3..4,AB123.1:4..6)", want: "ccTGG"},
+		{in: "5^6", want: ""},
+		{in: "X1.1:1..2", err: "no sequence for X1.1"},
+		{in: "10..40", err: "location: span 10..40 out of range of sequence length 18"},
+	} {
+		l, err := Parse(t.in)
+		c.Assert(err, check.Equals, nil)
+		got, err := Extract(l, []byte(seq), remote)
+		if t.err != "" {
+			c.Check(err, check.ErrorMatches, t.err, check.Commentf("%q", t.in))
+			continue
+		}
+		c.Check(err, check.Equals, nil)
+		c.Check(string(got), check.Equals, t.want, check.Commentf("%q", t.in))
+	}
+
+	l, _ := Parse("AB123.1:1..2")
+	_, err := Extract(l, nil, nil)
+	c.Check(err, check.ErrorMatches, "location: no sequence for remote accession AB123.1")
+}
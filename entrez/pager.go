@@ -0,0 +1,263 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biogo/ncbi"
+)
+
+// DefaultBatch is the number of UIDs or records in each page of a Pager with a zero
+// Batch field.
+const DefaultBatch = 500
+
+// A Pager pages through the UIDs or records of the result set of an ESearch query
+// held on the Entrez History server. Pages are retrieved with EFetch.
+//
+// UIDs are paged with NextIDs and records are paged with NextRecords:
+//
+//	pg, err := c.NewPager("protein", query, &entrez.Parameters{RetType: "fasta"})
+//	...
+//	for pg.NextRecords() {
+//		w.Write(pg.Records())
+//	}
+//	if pg.Err() != nil {
+//		// Paging may be resumed from pg.Start.
+//	}
+type Pager struct {
+	// Batch is the number of UIDs or records requested
+	// for each page. If Batch is zero, DefaultBatch is
+	// used.
+	Batch int
+
+	// Start is the offset in the result set of the next
+	// page. Start may be set before the first page is
+	// retrieved to resume paging.
+	Start int
+
+	// Retry is the policy used to retry pages whose
+	// responses are truncated or corrupted after they
+	// have been received. Failed requests are retried
+	// only according to the Retry policy of the Pager's
+	// Client. If Retry is nil, ncbi.DefaultRetry is used.
+	Retry *ncbi.Retry
+
+	// Progress, if not nil, is called after each page
+	// is retrieved with the number of UIDs or records
+	// retrieved so far, including any skipped by setting
+	// Start, and the size of the result set.
+	Progress func(done, count int)
+
+	client  *Client
+	ctx     context.Context
+	db      string
+	params  Parameters
+	history History
+	count   int
+
+	ids     []int
+	records []byte
+	err     error
+}
+
+// NewPager performs an ESearch for query in db, storing the result set on the History
+// server, and returns a Pager for the result set. The search parameters of p are used
+// for the search, and the RetType, RetMode and other EFetch parameters of p are used when
+// retrieving records. The RetStart and RetMax fields of p are ignored.
+func NewPager(db, query string, p *Parameters, tool, email string) (*Pager, error) {
+	return NewPagerContext(context.Background(), db, query, p, tool, email)
+}
+
+// NewPagerContext is like NewPager but uses ctx to cancel the search and the retrieval
+// of pages.
+func NewPagerContext(ctx context.Context, db, query string, p *Parameters, tool, email string) (*Pager, error) {
+	return clientFor(tool, email).NewPagerContext(ctx, db, query, p)
+}
+
+// NewPager performs an ESearch for query in db, storing the result set on the History
+// server, and returns a Pager for the result set. The search parameters of p are used
+// for the search, and the RetType, RetMode and other EFetch parameters of p are used when
+// retrieving records. The RetStart and RetMax fields of p are ignored.
+func (c *Client) NewPager(db, query string, p *Parameters) (*Pager, error) {
+	return c.NewPagerContext(context.Background(), db, query, p)
+}
+
+// NewPagerContext is like NewPager but uses ctx to cancel the search and the retrieval
+// of pages.
+func (c *Client) NewPagerContext(ctx context.Context, db, query string, p *Parameters) (*Pager, error) {
+	pg := &Pager{client: c, ctx: ctx, db: db}
+	if p != nil {
+		pg.params = *p
+	}
+	// Search parameters such as Sort, Field and the date
+	// ranges are used, but the paging and format of the
+	// search response are not.
+	sp := pg.params
+	sp.RetStart, sp.RetMax = 0, 0
+	sp.RetType, sp.RetMode = "", ""
+	s, err := c.DoSearchContext(ctx, db, query, &sp, &pg.history)
+	if err != nil {
+		return nil, err
+	}
+	if pg.history.WebEnv == "" || pg.history.QueryKey == 0 {
+		return nil, errors.New("entrez: search result not stored on history server")
+	}
+	pg.count = s.Count
+	return pg, nil
+}
+
+// Count returns the number of UIDs in the result set.
+func (pg *Pager) Count() int { return pg.count }
+
+// History returns the History holding the result set.
+func (pg *Pager) History() History { return pg.history }
+
+// NextIDs retrieves the next page of UIDs, which are then available through the IDs
+// method. It returns false when the result set is exhausted or an error occurs.
+func (pg *Pager) NextIDs() bool {
+	return pg.next(func(data []byte) error {
+		ids, err := parseIDList(data)
+		pg.ids = ids
+		return err
+	}, "uilist", "text")
+}
+
+// IDs returns the UIDs of the current page.
+func (pg *Pager) IDs() []int { return pg.ids }
+
+// NextRecords retrieves the next page of records, which are then available through
+// the Records method. It returns false when the result set is exhausted or an error
+// occurs.
+func (pg *Pager) NextRecords() bool {
+	return pg.next(func(data []byte) error {
+		pg.records = data
+		return nil
+	}, pg.params.RetType, pg.params.RetMode)
+}
+
+// Records returns the records of the current page as returned by EFetch.
+func (pg *Pager) Records() []byte { return pg.records }
+
+// Err returns the error that terminated paging, if any. After an error, Start holds
+// the offset of the page that failed.
+func (pg *Pager) Err() error { return pg.err }
+
+// next retrieves the page at pg.Start with the given rettype and retmode, and passes
+// the response to handle.
+func (pg *Pager) next(handle func([]byte) error, retType, retMode string) bool {
+	pg.ids = nil
+	pg.records = nil
+	if pg.err != nil || pg.Start >= pg.count {
+		return false
+	}
+	batch := pg.Batch
+	if batch <= 0 {
+		batch = DefaultBatch
+	}
+	if pg.Start+batch > pg.count {
+		batch = pg.count - pg.Start
+	}
+	p := pg.params
+	p.RetStart = pg.Start
+	p.RetMax = batch
+	p.RetType = retType
+	p.RetMode = retMode
+
+	policy := pg.Retry
+	if policy == nil {
+		policy = ncbi.DefaultRetry
+	}
+	for attempt := 1; ; attempt++ {
+		data, received, err := pg.fetch(&p)
+		if err == nil {
+			err = handle(data)
+			if err == nil {
+				break
+			}
+		}
+		// Requests that fail have already been
+		// retried by the Client.
+		if !received || attempt >= policy.Attempts() || !ncbi.Temporary(err) {
+			pg.err = err
+			return false
+		}
+		t := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-t.C:
+		case <-pg.ctx.Done():
+			t.Stop()
+			pg.err = pg.ctx.Err()
+			return false
+		}
+	}
+	pg.Start += batch
+	if pg.Progress != nil {
+		pg.Progress(pg.Start, pg.count)
+	}
+	return true
+}
+
+// fetch returns the complete response to an EFetch for the page described by p. The
+// returned bool reports whether a response was received, in which case any error was
+// found while reading or checking the response.
+func (pg *Pager) fetch(p *Parameters) ([]byte, bool, error) {
+	h := pg.history
+	r, err := pg.client.FetchContext(pg.ctx, pg.db, p, &h)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, true, err
+	}
+	return data, true, fetchError(data)
+}
+
+// fetchError returns an *Error if data is an EFetch error response.
+func fetchError(data []byte) error {
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if !bytes.Contains(head, []byte("<eFetchResult")) {
+		return nil
+	}
+	var res struct {
+		Err []string `xml:"ERROR"`
+	}
+	err := xml.Unmarshal(data, &res)
+	if err != nil || len(res.Err) == 0 {
+		return nil
+	}
+	return &Error{Util: "efetch", Msg: strings.Join(res.Err, "; ")}
+}
+
+// parseIDList returns the UIDs listed one per line in data.
+func parseIDList(data []byte) ([]int, error) {
+	var ids []int
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		f := bytes.TrimSpace(sc.Bytes())
+		if len(f) == 0 {
+			continue
+		}
+		id, err := strconv.Atoi(string(f))
+		if err != nil {
+			return nil, &Error{Util: "efetch", Msg: "invalid uid in list: " + string(f)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, sc.Err()
+}
@@ -0,0 +1,153 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/biogo/ncbi"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPager(c *check.C) {
+	const count = 7
+	var (
+		mu       sync.Mutex
+		fetches  = make(map[int]int)
+		truncate = map[int]bool{2: true}
+		fail     = -1
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch path.Base(r.URL.Path) {
+		case "esearch.fcgi":
+			c.Check(r.Form.Get("usehistory"), check.Equals, "y")
+			c.Check(r.Form.Get("sort"), check.Equals, "relevance")
+			c.Check(r.Form.Get("rettype"), check.Equals, "")
+			fmt.Fprintf(w, "<eSearchResult><Count>%d</Count><QueryKey>1</QueryKey><WebEnv>NCID_1</WebEnv></eSearchResult>", count)
+		case "efetch.fcgi":
+			c.Check(r.Form.Get("webenv"), check.Equals, "NCID_1")
+			c.Check(r.Form.Get("query_key"), check.Equals, "1")
+			start, _ := strconv.Atoi(r.Form.Get("retstart"))
+			max, _ := strconv.Atoi(r.Form.Get("retmax"))
+			mu.Lock()
+			fetches[start]++
+			n := fetches[start]
+			mu.Unlock()
+			if start == fail {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var body string
+			for id := start; id < start+max && id < count; id++ {
+				if r.Form.Get("rettype") == "uilist" {
+					body += fmt.Sprintln(100 + id)
+				} else {
+					body += fmt.Sprintf(">%d\n", 100+id)
+				}
+			}
+			if truncate[start] && n == 1 {
+				// Declare more data than is sent.
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)+10))
+			}
+			fmt.Fprint(w, body)
+		}
+	}))
+	defer srv.Close()
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   ncbi.NoRetry,
+	}}
+
+	pg, err := cl.NewPager("protein", "hoxa1", &Parameters{RetType: "fasta", Sort: "relevance"})
+	c.Assert(err, check.Equals, nil)
+	c.Check(pg.Count(), check.Equals, count)
+	c.Check(pg.History(), check.Equals, History{QueryKey: 1, WebEnv: "NCID_1"})
+	pg.Batch = 2
+	pg.Retry = &ncbi.Retry{MaxAttempts: 2, MinBackoff: time.Millisecond}
+	var progress []int
+	pg.Progress = func(done, total int) {
+		c.Check(total, check.Equals, count)
+		progress = append(progress, done)
+	}
+	var ids []int
+	for pg.NextIDs() {
+		ids = append(ids, pg.IDs()...)
+	}
+	c.Check(pg.Err(), check.Equals, nil)
+	c.Check(ids, check.DeepEquals, []int{100, 101, 102, 103, 104, 105, 106})
+	c.Check(progress, check.DeepEquals, []int{2, 4, 6, 7})
+	c.Check(fetches[2], check.Equals, 2)
+
+	// Resume part way through the result set.
+	progress = nil
+	pg.Start = 3
+	var recs string
+	for pg.NextRecords() {
+		recs += string(pg.Records())
+	}
+	c.Check(pg.Err(), check.Equals, nil)
+	c.Check(recs, check.Equals, ">103\n>104\n>105\n>106\n")
+	c.Check(progress, check.DeepEquals, []int{5, 7})
+
+	// Failures that are not transient are not retried.
+	fail = 2
+	pg.Start = 0
+	for pg.NextIDs() {
+	}
+	var se *ncbi.StatusError
+	c.Check(errors.As(pg.Err(), &se), check.Equals, true, check.Commentf("unexpected error: %v", pg.Err()))
+	c.Check(pg.Start, check.Equals, 2)
+	c.Check(fetches[2], check.Equals, 3)
+}
+
+func (s *S) TestPagerRequestRetry(c *check.C) {
+	var (
+		mu      sync.Mutex
+		fetches int
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "esearch.fcgi":
+			fmt.Fprint(w, "<eSearchResult><Count>4</Count><QueryKey>1</QueryKey><WebEnv>NCID_1</WebEnv></eSearchResult>")
+		case "efetch.fcgi":
+			mu.Lock()
+			fetches++
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+		Retry:   &ncbi.Retry{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}}
+
+	pg, err := cl.NewPager("protein", "hoxa1", nil)
+	c.Assert(err, check.Equals, nil)
+	pg.Retry = &ncbi.Retry{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	for pg.NextIDs() {
+	}
+	c.Check(pg.Err(), check.NotNil)
+	c.Check(pg.Start, check.Equals, 0)
+
+	// Failed requests are retried by the Client,
+	// but not again by the Pager.
+	c.Check(fetches, check.Equals, 3)
+}
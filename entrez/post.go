@@ -25,3 +25,5 @@ type Post struct {
 	*History
 	Err *string `xml:"ERROR"`
 }
+
+func (p *Post) reportsError() bool { return p.Err != nil }
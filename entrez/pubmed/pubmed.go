@@ -0,0 +1,381 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pubmed provides types for PubMed records returned by EFetch with db=pubmed
+// and retmode=xml.
+//
+// Large responses may be read one article at a time with a Reader:
+//
+//	rc, err := entrez.Fetch("pubmed", &entrez.Parameters{RetMode: "xml"}, tool, email, nil, ids...)
+//	...
+//	r := pubmed.NewReader(rc)
+//	defer r.Close()
+//	for r.Next() {
+//		a := r.Article()
+//		...
+//	}
+//	if r.Err() != nil {
+//		...
+//	}
+//
+// Book articles and deleted citations are not returned as articles. Their PMIDs are
+// reported by the Reader's Books and Deleted methods, so that callers can account for
+// every PMID requested.
+package pubmed
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// <!ELEMENT PubmedArticleSet ((PubmedArticle | PubmedBookArticle)+, DeleteCitation?)>
+//
+// <!ELEMENT PubmedArticle (MedlineCitation, PubmedData?)>
+//
+// <!ELEMENT MedlineCitation (PMID, DateCompleted?, DateRevised?, Article,
+//                            MedlineJournalInfo, ChemicalList?, SupplMeshList?,
+//                            CitationSubset*, CommentsCorrectionsList?, GeneSymbolList?,
+//                            MeshHeadingList?, NumberOfReferences?, PersonalNameSubjectList?,
+//                            OtherID*, OtherAbstract*, KeywordList*, CoiStatement?,
+//                            SpaceFlightMission*, InvestigatorList?, GeneralNote*)>
+//
+// <!ELEMENT Article (Journal, ArticleTitle, ((Pagination, ELocationID*) | ELocationID+),
+//                    Abstract?, AuthorList?, Language+, DataBankList?, GrantList?,
+//                    PublicationTypeList, VernacularTitle?, ArticleDate*)>
+//
+// <!ELEMENT PubmedData (History?, PublicationStatus, ArticleIdList, ObjectList?,
+//                       ReferenceList*)>
+
+// ArticleSet holds the articles of a PubmedArticleSet. Book articles are not decoded,
+// but their PMIDs are held in Books. Deleted holds the PMIDs of deleted citations.
+type ArticleSet struct {
+	Articles []Article `xml:"PubmedArticle"`
+	Books    []int     `xml:"PubmedBookArticle>BookDocument>PMID"`
+	Deleted  []int     `xml:"DeleteCitation>PMID"`
+}
+
+// Article is a PubMed article.
+type Article struct {
+	PMID          int   `xml:"MedlineCitation>PMID"`
+	DateCompleted *Date `xml:"MedlineCitation>DateCompleted"`
+	DateRevised   *Date `xml:"MedlineCitation>DateRevised"`
+
+	Journal          Journal           `xml:"MedlineCitation>Article>Journal"`
+	Title            Text              `xml:"MedlineCitation>Article>ArticleTitle"`
+	VernacularTitle  Text              `xml:"MedlineCitation>Article>VernacularTitle"`
+	Pagination       string            `xml:"MedlineCitation>Article>Pagination>MedlinePgn"`
+	ELocationIDs     []ELocationID     `xml:"MedlineCitation>Article>ELocationID"`
+	Abstract         []AbstractText    `xml:"MedlineCitation>Article>Abstract>AbstractText"`
+	Copyright        string            `xml:"MedlineCitation>Article>Abstract>CopyrightInformation"`
+	Authors          []Author          `xml:"MedlineCitation>Article>AuthorList>Author"`
+	Languages        []string          `xml:"MedlineCitation>Article>Language"`
+	PublicationTypes []PublicationType `xml:"MedlineCitation>Article>PublicationTypeList>PublicationType"`
+	ArticleDates     []Date            `xml:"MedlineCitation>Article>ArticleDate"`
+
+	MeshHeadings []MeshHeading `xml:"MedlineCitation>MeshHeadingList>MeshHeading"`
+	Keywords     []Keyword     `xml:"MedlineCitation>KeywordList>Keyword"`
+
+	History           []Date      `xml:"PubmedData>History>PubMedPubDate"`
+	PublicationStatus string      `xml:"PubmedData>PublicationStatus"`
+	ArticleIDs        ArticleIDs  `xml:"PubmedData>ArticleIdList>ArticleId"`
+	References        []Reference `xml:"PubmedData>ReferenceList>Reference"`
+}
+
+// DOI returns the DOI of the article, or the empty string if it has none.
+func (a *Article) DOI() string {
+	if doi := a.ArticleIDs.Get("doi"); doi != "" {
+		return doi
+	}
+	for _, id := range a.ELocationIDs {
+		if id.Type == "doi" {
+			return id.ID
+		}
+	}
+	return ""
+}
+
+// PMC returns the PubMed Central id of the article, or the empty string if it has none.
+func (a *Article) PMC() string { return a.ArticleIDs.Get("pmc") }
+
+// AbstractText returns the text of the abstract sections of the article, each
+// preceded by its label, if it has one, and separated by blank lines.
+func (a *Article) AbstractText() string {
+	var buf strings.Builder
+	for i, s := range a.Abstract {
+		if i != 0 {
+			buf.WriteString("\n\n")
+		}
+		if s.Label != "" {
+			buf.WriteString(s.Label)
+			buf.WriteString(": ")
+		}
+		buf.WriteString(s.Text)
+	}
+	return buf.String()
+}
+
+// Journal describes the journal issue that holds an article.
+type Journal struct {
+	ISSN            ISSN   `xml:"ISSN"`
+	Volume          string `xml:"JournalIssue>Volume"`
+	Issue           string `xml:"JournalIssue>Issue"`
+	PubDate         Date   `xml:"JournalIssue>PubDate"`
+	Title           string `xml:"Title"`
+	ISOAbbreviation string `xml:"ISOAbbreviation"`
+}
+
+// ISSN is an International Standard Serial Number.
+type ISSN struct {
+	ISSN string `xml:",chardata"`
+	Type string `xml:"IssnType,attr"`
+}
+
+// Date is a date in a PubMed record. Month may be numeric or an abbreviated month
+// name. Publication dates that cannot be expressed as a year, month and day are
+// held in MedlineDate, for example "1998 Dec-1999 Jan".
+type Date struct {
+	Year        string `xml:"Year"`
+	Month       string `xml:"Month"`
+	Day         string `xml:"Day"`
+	Season      string `xml:"Season"`
+	MedlineDate string `xml:"MedlineDate"`
+
+	// Type is the type of an article date,
+	// for example "Electronic".
+	Type string `xml:"DateType,attr"`
+
+	// Status is the publication status of a
+	// history date, for example "received".
+	Status string `xml:"PubStatus,attr"`
+}
+
+// Time returns the date as a time.Time. A missing month or day is taken to be the
+// first. An error is returned if the date has no valid year.
+func (d Date) Time() (time.Time, error) {
+	year, err := strconv.Atoi(d.Year)
+	if err != nil {
+		return time.Time{}, errors.New("pubmed: invalid year: " + strconv.Quote(d.Year))
+	}
+	month := time.January
+	if d.Month != "" {
+		m, err := strconv.Atoi(d.Month)
+		if err != nil {
+			t, perr := time.Parse("Jan", d.Month)
+			if perr != nil {
+				return time.Time{}, errors.New("pubmed: invalid month: " + strconv.Quote(d.Month))
+			}
+			m = int(t.Month())
+		}
+		month = time.Month(m)
+	}
+	day := 1
+	if d.Day != "" {
+		day, err = strconv.Atoi(d.Day)
+		if err != nil {
+			return time.Time{}, errors.New("pubmed: invalid day: " + strconv.Quote(d.Day))
+		}
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// ELocationID is an electronic location of an article.
+type ELocationID struct {
+	ID    string `xml:",chardata"`
+	Type  string `xml:"EIdType,attr"`
+	Valid YN     `xml:"ValidYN,attr"`
+}
+
+// AbstractText is a section of an abstract. Markup within the text is removed.
+type AbstractText struct {
+	Text        string
+	Label       string
+	NlmCategory string
+}
+
+var _ xml.Unmarshaler = (*AbstractText)(nil)
+
+func (a *AbstractText) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	*a = AbstractText{}
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "Label":
+			a.Label = attr.Value
+		case "NlmCategory":
+			a.NlmCategory = attr.Value
+		}
+	}
+	var err error
+	a.Text, err = text(dec)
+	return err
+}
+
+// Author is an author of an article. Either the personal name fields or CollectiveName
+// are set.
+type Author struct {
+	LastName       string       `xml:"LastName"`
+	ForeName       string       `xml:"ForeName"`
+	Initials       string       `xml:"Initials"`
+	Suffix         string       `xml:"Suffix"`
+	CollectiveName Text         `xml:"CollectiveName"`
+	Identifiers    []Identifier `xml:"Identifier"`
+	Affiliations   []string     `xml:"AffiliationInfo>Affiliation"`
+	Valid          YN           `xml:"ValidYN,attr"`
+}
+
+// ORCID returns the ORCID iD of the author without any URL prefix, or the empty
+// string if the author has none.
+func (a *Author) ORCID() string {
+	for _, id := range a.Identifiers {
+		if id.Source != "ORCID" {
+			continue
+		}
+		orcid := strings.TrimSpace(id.ID)
+		if i := strings.LastIndex(orcid, "/"); i >= 0 {
+			orcid = orcid[i+1:]
+		}
+		return orcid
+	}
+	return ""
+}
+
+// Identifier is an identifier for a person or organisation, for example an ORCID iD.
+type Identifier struct {
+	ID     string `xml:",chardata"`
+	Source string `xml:"Source,attr"`
+}
+
+// PublicationType is the type of an article, for example "Journal Article".
+type PublicationType struct {
+	Name string `xml:",chardata"`
+	UI   string `xml:"UI,attr"`
+}
+
+// MeshHeading is a MeSH descriptor and its qualifiers.
+type MeshHeading struct {
+	Descriptor MeshTerm   `xml:"DescriptorName"`
+	Qualifiers []MeshTerm `xml:"QualifierName"`
+}
+
+// MeshTerm is a MeSH descriptor or qualifier.
+type MeshTerm struct {
+	Name       string `xml:",chardata"`
+	UI         string `xml:"UI,attr"`
+	MajorTopic YN     `xml:"MajorTopicYN,attr"`
+}
+
+// Keyword is a keyword of an article.
+type Keyword struct {
+	Keyword    string
+	MajorTopic YN
+}
+
+var _ xml.Unmarshaler = (*Keyword)(nil)
+
+func (k *Keyword) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	*k = Keyword{}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "MajorTopicYN" {
+			err := k.MajorTopic.UnmarshalXMLAttr(attr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	k.Keyword, err = text(dec)
+	return err
+}
+
+// ArticleID is an identifier of an article.
+type ArticleID struct {
+	ID string `xml:",chardata"`
+
+	// Type is the type of the identifier,
+	// for example "pubmed", "doi" or "pmc".
+	Type string `xml:"IdType,attr"`
+}
+
+// ArticleIDs is a list of article identifiers.
+type ArticleIDs []ArticleID
+
+// Get returns the first identifier of the given type, or the empty string if there
+// is none.
+func (ids ArticleIDs) Get(typ string) string {
+	for _, id := range ids {
+		if id.Type == typ {
+			return id.ID
+		}
+	}
+	return ""
+}
+
+// Reference is a reference cited by an article.
+type Reference struct {
+	Citation   Text       `xml:"Citation"`
+	ArticleIDs ArticleIDs `xml:"ArticleIdList>ArticleId"`
+}
+
+// PMID returns the PubMed id of the referenced article, or zero if it is not known.
+func (r *Reference) PMID() int {
+	id, _ := strconv.Atoi(r.ArticleIDs.Get("pubmed"))
+	return id
+}
+
+// Text is the text content of an element. Markup within the element, such as
+// <i> and <sup>, is removed.
+type Text string
+
+var _ xml.Unmarshaler = (*Text)(nil)
+
+func (t *Text) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	s, err := text(dec)
+	*t = Text(s)
+	return err
+}
+
+// text returns the character data within the current element, consuming tokens up
+// to and including the element's end.
+func text(dec *xml.Decoder) (string, error) {
+	var (
+		buf   strings.Builder
+		depth int
+	)
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return buf.String(), err
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			buf.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return buf.String(), nil
+			}
+			depth--
+		}
+	}
+}
+
+// YN is a boolean attribute with the values "Y" and "N".
+type YN bool
+
+var _ xml.UnmarshalerAttr = (*YN)(nil)
+
+func (b *YN) UnmarshalXMLAttr(attr xml.Attr) error {
+	switch attr.Value {
+	case "Y":
+		*b = true
+	case "N":
+		*b = false
+	default:
+		return errors.New("pubmed: bad boolean")
+	}
+	return nil
+}
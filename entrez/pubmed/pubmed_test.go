@@ -0,0 +1,199 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pubmed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biogo/ncbi/entrez"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+const articleSet = `<?xml version="1.0" ?>
+<!DOCTYPE PubmedArticleSet PUBLIC "-//NLM//DTD PubMedArticle, 1st January 2019//EN" "https://dtd.nlm.nih.gov/ncbi/pubmed/out/pubmed_190101.dtd">
+<PubmedArticleSet>
+<PubmedArticle>
+  <MedlineCitation Status="MEDLINE" Owner="NLM">
+    <PMID Version="1">23456789</PMID>
+    <DateCompleted><Year>2014</Year><Month>03</Month><Day>12</Day></DateCompleted>
+    <Article PubModel="Print-Electronic">
+      <Journal>
+        <ISSN IssnType="Electronic">1471-2105</ISSN>
+        <JournalIssue CitedMedium="Internet">
+          <Volume>14</Volume>
+          <Issue>2</Issue>
+          <PubDate><Year>2013</Year><Month>Dec</Month></PubDate>
+        </JournalIssue>
+        <Title>BMC bioinformatics</Title>
+        <ISOAbbreviation>BMC Bioinformatics</ISOAbbreviation>
+      </Journal>
+      <ArticleTitle>Expression of <i>HOXA1</i> in C<sub>2</sub> cells.</ArticleTitle>
+      <Pagination><MedlinePgn>101-9</MedlinePgn></Pagination>
+      <ELocationID EIdType="doi" ValidYN="Y">10.1186/1471-2105-14-101</ELocationID>
+      <Abstract>
+        <AbstractText Label="BACKGROUND" NlmCategory="BACKGROUND">HOX genes <i>matter</i>.</AbstractText>
+        <AbstractText Label="RESULTS" NlmCategory="RESULTS">We found things.</AbstractText>
+        <CopyrightInformation>© 2013 The authors.</CopyrightInformation>
+      </Abstract>
+      <AuthorList CompleteYN="Y">
+        <Author ValidYN="Y">
+          <LastName>Smith</LastName>
+          <ForeName>Jane A</ForeName>
+          <Initials>JA</Initials>
+          <Identifier Source="ORCID">https://orcid.org/0000-0002-1825-0097</Identifier>
+          <AffiliationInfo><Affiliation>Dept. of Biology, Some University.</Affiliation></AffiliationInfo>
+          <AffiliationInfo><Affiliation>Institute of Things.</Affiliation></AffiliationInfo>
+        </Author>
+        <Author ValidYN="Y">
+          <CollectiveName>The HOX Consortium</CollectiveName>
+        </Author>
+      </AuthorList>
+      <Language>eng</Language>
+      <PublicationTypeList>
+        <PublicationType UI="D016428">Journal Article</PublicationType>
+      </PublicationTypeList>
+      <ArticleDate DateType="Electronic"><Year>2013</Year><Month>11</Month><Day>02</Day></ArticleDate>
+    </Article>
+    <MedlineJournalInfo><Country>England</Country><MedlineTA>BMC Bioinformatics</MedlineTA></MedlineJournalInfo>
+    <MeshHeadingList>
+      <MeshHeading>
+        <DescriptorName UI="D018398" MajorTopicYN="N">Homeodomain Proteins</DescriptorName>
+        <QualifierName UI="Q000235" MajorTopicYN="Y">genetics</QualifierName>
+      </MeshHeading>
+    </MeshHeadingList>
+    <KeywordList Owner="NOTNLM">
+      <Keyword MajorTopicYN="N">homeobox</Keyword>
+    </KeywordList>
+  </MedlineCitation>
+  <PubmedData>
+    <History>
+      <PubMedPubDate PubStatus="received"><Year>2013</Year><Month>6</Month><Day>1</Day></PubMedPubDate>
+    </History>
+    <PublicationStatus>epublish</PublicationStatus>
+    <ArticleIdList>
+      <ArticleId IdType="pubmed">23456789</ArticleId>
+      <ArticleId IdType="pmc">PMC3600000</ArticleId>
+    </ArticleIdList>
+    <ReferenceList>
+      <Reference>
+        <Citation>Doe J. Earlier work. Nature. 2001.</Citation>
+        <ArticleIdList><ArticleId IdType="pubmed">11111111</ArticleId></ArticleIdList>
+      </Reference>
+    </ReferenceList>
+  </PubmedData>
+</PubmedArticle>
+<PubmedBookArticle><BookDocument><PMID>1</PMID></BookDocument></PubmedBookArticle>
+<PubmedArticle>
+  <MedlineCitation><PMID>2</PMID><Article><ArticleTitle>Second.</ArticleTitle>
+  <Journal><JournalIssue><PubDate><MedlineDate>1998 Dec-1999 Jan</MedlineDate></PubDate></JournalIssue></Journal>
+  </Article></MedlineCitation>
+</PubmedArticle>
+<DeleteCitation><PMID Version="1">3</PMID><PMID Version="1">4</PMID></DeleteCitation>
+</PubmedArticleSet>
+`
+
+func (s *S) TestReader(c *check.C) {
+	r := NewReader(strings.NewReader(articleSet))
+	var got []Article
+	for r.Next() {
+		got = append(got, *r.Article())
+	}
+	c.Assert(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Assert(got, check.HasLen, 2)
+	c.Check(r.Books(), check.DeepEquals, []int{1})
+	c.Check(r.Deleted(), check.DeepEquals, []int{3, 4})
+
+	a := got[0]
+	c.Check(a.PMID, check.Equals, 23456789)
+	c.Check(a.Title, check.Equals, Text("Expression of HOXA1 in C2 cells."))
+	c.Check(a.Journal.Title, check.Equals, "BMC bioinformatics")
+	c.Check(a.Journal.ISSN, check.Equals, ISSN{ISSN: "1471-2105", Type: "Electronic"})
+	c.Check(a.Journal.Volume, check.Equals, "14")
+	c.Check(a.Pagination, check.Equals, "101-9")
+	c.Check(a.Abstract, check.DeepEquals, []AbstractText{
+		{Text: "HOX genes matter.", Label: "BACKGROUND", NlmCategory: "BACKGROUND"},
+		{Text: "We found things.", Label: "RESULTS", NlmCategory: "RESULTS"},
+	})
+	c.Check(a.AbstractText(), check.Equals, "BACKGROUND: HOX genes matter.\n\nRESULTS: We found things.")
+	c.Check(a.Copyright, check.Equals, "© 2013 The authors.")
+
+	c.Assert(a.Authors, check.HasLen, 2)
+	c.Check(a.Authors[0].LastName, check.Equals, "Smith")
+	c.Check(a.Authors[0].ForeName, check.Equals, "Jane A")
+	c.Check(a.Authors[0].ORCID(), check.Equals, "0000-0002-1825-0097")
+	c.Check(a.Authors[0].Affiliations, check.DeepEquals, []string{"Dept. of Biology, Some University.", "Institute of Things."})
+	c.Check(a.Authors[0].Valid, check.Equals, YN(true))
+	c.Check(a.Authors[1].CollectiveName, check.Equals, Text("The HOX Consortium"))
+	c.Check(a.Authors[1].ORCID(), check.Equals, "")
+
+	c.Check(a.Languages, check.DeepEquals, []string{"eng"})
+	c.Check(a.PublicationTypes, check.DeepEquals, []PublicationType{{Name: "Journal Article", UI: "D016428"}})
+	c.Check(a.MeshHeadings, check.DeepEquals, []MeshHeading{{
+		Descriptor: MeshTerm{Name: "Homeodomain Proteins", UI: "D018398"},
+		Qualifiers: []MeshTerm{{Name: "genetics", UI: "Q000235", MajorTopic: true}},
+	}})
+	c.Check(a.Keywords, check.DeepEquals, []Keyword{{Keyword: "homeobox"}})
+
+	c.Check(a.DOI(), check.Equals, "10.1186/1471-2105-14-101")
+	c.Check(a.PMC(), check.Equals, "PMC3600000")
+	c.Check(a.PublicationStatus, check.Equals, "epublish")
+	c.Assert(a.References, check.HasLen, 1)
+	c.Check(a.References[0].Citation, check.Equals, Text("Doe J. Earlier work. Nature. 2001."))
+	c.Check(a.References[0].PMID(), check.Equals, 11111111)
+
+	for _, t := range []struct {
+		date *Date
+		typ  string
+		want time.Time
+	}{
+		{date: a.DateCompleted, want: time.Date(2014, time.March, 12, 0, 0, 0, 0, time.UTC)},
+		{date: &a.Journal.PubDate, want: time.Date(2013, time.December, 1, 0, 0, 0, 0, time.UTC)},
+		{date: &a.ArticleDates[0], typ: "Electronic", want: time.Date(2013, time.November, 2, 0, 0, 0, 0, time.UTC)},
+		{date: &a.History[0], want: time.Date(2013, time.June, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		c.Check(t.date.Type, check.Equals, t.typ)
+		got, err := t.date.Time()
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.Equals, t.want)
+	}
+	c.Check(a.History[0].Status, check.Equals, "received")
+
+	b := got[1]
+	c.Check(b.PMID, check.Equals, 2)
+	c.Check(b.Title, check.Equals, Text("Second."))
+	c.Check(b.Journal.PubDate.MedlineDate, check.Equals, "1998 Dec-1999 Jan")
+	_, err := b.Journal.PubDate.Time()
+	c.Check(err, check.NotNil)
+
+	var set ArticleSet
+	err = xml.Unmarshal([]byte(articleSet), &set)
+	c.Assert(err, check.Equals, nil)
+	c.Check(set.Articles, check.DeepEquals, got)
+	c.Check(set.Books, check.DeepEquals, []int{1})
+	c.Check(set.Deleted, check.DeepEquals, []int{3, 4})
+}
+
+func (s *S) TestReaderErr(c *check.C) {
+	r := NewReader(strings.NewReader(`<eFetchResult><ERROR>Empty result - nothing to do</ERROR></eFetchResult>`))
+	c.Check(r.Next(), check.Equals, false)
+	c.Check(r.Err(), check.DeepEquals, &entrez.Error{Util: "efetch", Msg: "Empty result - nothing to do"})
+
+	r = NewReader(strings.NewReader(articleSet[:len(articleSet)/2]))
+	for r.Next() {
+	}
+	_, ok := r.Err().(*xml.SyntaxError)
+	c.Check(ok, check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+}
@@ -0,0 +1,84 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pubmed
+
+import (
+	"io"
+
+	"github.com/biogo/ncbi/entrez"
+)
+
+// A Reader reads the articles of a PubmedArticleSet one at a time, allowing large
+// responses to be processed without holding them in memory. Book articles are not
+// returned by Next, but their PMIDs are recorded and are available through the Books
+// method. The PMIDs of deleted citations are available through the Deleted method.
+type Reader struct {
+	r       *entrez.ElementReader
+	targets map[string]interface{}
+
+	article Article
+	book    struct {
+		PMID int `xml:"BookDocument>PMID"`
+	}
+	deleted struct {
+		PMIDs []int `xml:"PMID"`
+	}
+
+	books   []int
+	deletes []int
+}
+
+// NewReader returns a Reader that reads a PubmedArticleSet from r. If r is an io.Closer,
+// it is closed by the Reader's Close method.
+func NewReader(r io.Reader) *Reader {
+	pr := &Reader{r: entrez.NewElementReader(r, "efetch")}
+	pr.targets = map[string]interface{}{
+		"PubmedArticle":     &pr.article,
+		"PubmedBookArticle": &pr.book,
+		"DeleteCitation":    &pr.deleted,
+	}
+	return pr
+}
+
+// Next advances the Reader to the next article, which is then available through the
+// Article method. It returns false when there are no more articles or an error occurs.
+func (r *Reader) Next() bool {
+	for {
+		r.article = Article{}
+		r.book.PMID = 0
+		r.deleted.PMIDs = nil
+		name, ok := r.r.NextOf(r.targets)
+		if !ok {
+			return false
+		}
+		switch name {
+		case "PubmedArticle":
+			return true
+		case "PubmedBookArticle":
+			r.books = append(r.books, r.book.PMID)
+		case "DeleteCitation":
+			r.deletes = append(r.deletes, r.deleted.PMIDs...)
+		}
+	}
+}
+
+// Article returns the current article.
+func (r *Reader) Article() *Article { return &r.article }
+
+// Books returns the PMIDs of the book articles read so far.
+func (r *Reader) Books() []int { return r.books }
+
+// Deleted returns the PMIDs of the deleted citations read so far. Deleted citations
+// follow the articles of a PubmedArticleSet, so the list is complete once Next has
+// returned false.
+func (r *Reader) Deleted() []int { return r.deletes }
+
+// Err returns the first error encountered by the Reader. Errors reported by an EFetch
+// error response are returned as an *entrez.Error.
+func (r *Reader) Err() error { return r.r.Err() }
+
+// Close closes the underlying stream. If the stream has been read to the end, any
+// trailing data is read so that the connection may be reused.
+func (r *Reader) Close() error { return r.r.Close() }
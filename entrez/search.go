@@ -87,3 +87,21 @@ type Search struct {
 	NotFound         *search.NotFound        `xml:"ErrorList"`
 	Warnings         *search.Warnings        `xml:"WarningList"`
 }
+
+func (s *Search) reportsError() bool { return s.Err != nil || s.NotFound != nil }
+
+// partialErr returns a *PartialError holding the phrases and fields that were not found
+// and the warnings reported by ESearch, or nil if there are none.
+func (s *Search) partialErr() error {
+	e := &PartialError{Util: "esearch"}
+	if s.NotFound != nil {
+		e.Errors = append(e.Errors, prefixed("phrase not found: ", s.NotFound.Phrase)...)
+		e.Errors = append(e.Errors, prefixed("field not found: ", s.NotFound.Field)...)
+	}
+	if s.Warnings != nil {
+		e.Warnings = append(e.Warnings, prefixed("phrase ignored: ", s.Warnings.Ignored)...)
+		e.Warnings = append(e.Warnings, prefixed("quoted phrase not found: ", s.Warnings.NotFound)...)
+		e.Warnings = append(e.Warnings, s.Warnings.Message...)
+	}
+	return e.err()
+}
@@ -35,3 +35,5 @@ type Spell struct {
 	Replace   spell.Replacements `xml:"SpelledQuery"`
 	Err       string             `xml:"ERROR"`
 }
+
+func (s *Spell) reportsError() bool { return s.Err != "" }
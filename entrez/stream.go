@@ -0,0 +1,315 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/entrez/link"
+	"github.com/biogo/ncbi/entrez/summary"
+)
+
+// An ElementReader reads the child elements of the root element of an E-utility XML
+// response one at a time. The text of ERROR children is collected and reported by Err.
+// ElementReader allows streaming readers to be written for EFetch record formats.
+type ElementReader struct {
+	util  string
+	r     io.Reader
+	dec   *xml.Decoder
+	depth int
+	errs  []string
+	err   error
+	done  bool
+}
+
+// NewElementReader returns an ElementReader that reads a response from the E-utility
+// named util, for example "efetch", from r. If r is an io.Closer, it is closed by the
+// ElementReader's Close method.
+func NewElementReader(r io.Reader, util string) *ElementReader {
+	return &ElementReader{util: util, r: r, dec: xml.NewDecoder(r)}
+}
+
+// NewElementTokenReader is like NewElementReader but decodes the tokens read from t,
+// which must read from r. It allows elements to be transformed before they are decoded.
+func NewElementTokenReader(r io.Reader, t xml.TokenReader, util string) *ElementReader {
+	return &ElementReader{util: util, r: r, dec: xml.NewTokenDecoder(t)}
+}
+
+// Next decodes the next child of the root element named name into v, skipping other
+// elements. It returns false when there are no more elements or an error occurs.
+func (r *ElementReader) Next(name string, v interface{}) bool {
+	_, ok := r.next(func(n string) interface{} {
+		if n == name {
+			return v
+		}
+		return nil
+	})
+	return ok
+}
+
+// NextOf decodes the next child of the root element whose name is a key of targets
+// into the corresponding value, skipping other elements, and returns the name of the
+// element. It returns false when there are no more elements or an error occurs.
+func (r *ElementReader) NextOf(targets map[string]interface{}) (name string, ok bool) {
+	return r.next(func(n string) interface{} { return targets[n] })
+}
+
+// next decodes the next child of the root element for which target returns a non-nil
+// value into that value, and returns the name of the element.
+func (r *ElementReader) next(target func(name string) interface{}) (string, bool) {
+	if r.done {
+		return "", false
+	}
+	for {
+		t, err := r.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			r.err = err
+			r.done = true
+			return "", false
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if r.depth == 0 {
+				r.depth++
+				continue
+			}
+			name := t.Name.Local
+			switch v := target(name); {
+			case v != nil:
+				err = r.dec.DecodeElement(v, &t)
+				if err == nil {
+					return name, true
+				}
+			case name == "ERROR":
+				var msg string
+				err = r.dec.DecodeElement(&msg, &t)
+				r.errs = append(r.errs, msg)
+				// Responses reporting errors
+				// are not cached.
+				ncbi.Uncache(r.r)
+			default:
+				err = r.dec.Skip()
+			}
+			if err != nil {
+				r.err = err
+				r.done = true
+				return "", false
+			}
+		case xml.EndElement:
+			r.depth--
+		}
+	}
+}
+
+// Err returns the first error encountered by the ElementReader. If no other error was
+// encountered and the response held ERROR elements, an *Error holding their text is
+// returned.
+func (r *ElementReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.errs) != 0 {
+		return &Error{Util: r.util, Msg: strings.Join(r.errs, "; ")}
+	}
+	return nil
+}
+
+// Close closes the stream. If the stream has been read to the end of the root element,
+// any trailing data is read so that the response may be cached and the connection reused.
+func (r *ElementReader) Close() error {
+	var err error
+	if r.done && r.err == nil {
+		_, err = io.Copy(ioutil.Discard, r.r)
+	}
+	r.done = true
+	if c, ok := r.r.(io.Closer); ok {
+		cerr := c.Close()
+		if err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// A SummaryReader reads the documents of an ESummary response one at a time, allowing
+// responses holding large numbers of documents to be processed without holding them
+// in memory.
+type SummaryReader struct {
+	r      *ElementReader
+	doc    summary.Document
+	n      int
+	strict bool
+}
+
+// NewSummaryReader returns a SummaryReader that reads an ESummary response from r.
+// If r is an io.Closer, it is closed by the SummaryReader's Close method.
+func NewSummaryReader(r io.Reader) *SummaryReader {
+	return &SummaryReader{r: NewElementReader(r, "esummary")}
+}
+
+// Next advances the SummaryReader to the next document, which is then available
+// through the Document method. It returns false when there are no more documents
+// or an error occurs.
+func (r *SummaryReader) Next() bool {
+	r.doc = summary.Document{}
+	if !r.r.Next("DocSum", &r.doc) {
+		return false
+	}
+	r.n++
+	return true
+}
+
+// Document returns the current document.
+func (r *SummaryReader) Document() summary.Document { return r.doc }
+
+// Err returns the first error encountered by the SummaryReader. If the response held
+// no documents and reported errors, an *Error is returned. If the SummaryReader was
+// returned by a Client with Strict set and errors were reported for the documents read
+// so far, a *PartialError is returned.
+func (r *SummaryReader) Err() error {
+	if r.r.err != nil {
+		return r.r.err
+	}
+	if r.r.done && r.n == 0 && len(r.r.errs) != 0 {
+		return &Error{Util: "esummary", Msg: strings.Join(r.r.errs, "; ")}
+	}
+	if r.strict {
+		s := Summary{Err: r.r.errs}
+		return s.partialErr()
+	}
+	return nil
+}
+
+// Close closes the underlying response.
+func (r *SummaryReader) Close() error { return r.r.Close() }
+
+// A LinkReader reads the link sets of an ELink response one at a time, allowing responses
+// holding large numbers of link sets to be processed without holding them in memory.
+type LinkReader struct {
+	r      *ElementReader
+	set    link.LinkSet
+	errs   []string
+	strict bool
+}
+
+// NewLinkReader returns a LinkReader that reads an ELink response from r. If r is
+// an io.Closer, it is closed by the LinkReader's Close method.
+func NewLinkReader(r io.Reader) *LinkReader {
+	return &LinkReader{r: NewElementReader(r, "elink")}
+}
+
+// Next advances the LinkReader to the next link set, which is then available through
+// the LinkSet method. It returns false when there are no more link sets or an error
+// occurs.
+func (r *LinkReader) Next() bool {
+	r.set = link.LinkSet{}
+	if !r.r.Next("LinkSet", &r.set) {
+		return false
+	}
+	r.errs = append(r.errs, r.set.Err...)
+	return true
+}
+
+// LinkSet returns the current link set.
+func (r *LinkReader) LinkSet() link.LinkSet { return r.set }
+
+// Err returns the first error encountered by the LinkReader. If the response reported
+// an error, an *Error is returned. If the LinkReader was returned by a Client with
+// Strict set and errors were reported for the link sets read so far, a *PartialError
+// is returned.
+func (r *LinkReader) Err() error {
+	err := r.r.Err()
+	if err != nil {
+		return err
+	}
+	if r.strict {
+		e := &PartialError{Util: "elink", Errors: r.errs}
+		return e.err()
+	}
+	return nil
+}
+
+// Close closes the underlying response.
+func (r *LinkReader) Close() error { return r.r.Close() }
+
+// StreamSummary returns a SummaryReader that reads the response to an ESummary query
+// on the specified id list. If h is not nil and its fields are non-zero, its field values
+// are passed to ESummary. StreamSummary returns an error if both h is nil and id has
+// length zero. It is the responsibility of the caller to close the returned SummaryReader.
+func StreamSummary(db string, p *Parameters, tool, email string, h *History, id ...int) (*SummaryReader, error) {
+	return StreamSummaryContext(context.Background(), db, p, tool, email, h, id...)
+}
+
+// StreamSummaryContext is like StreamSummary but uses ctx to cancel the request.
+func StreamSummaryContext(ctx context.Context, db string, p *Parameters, tool, email string, h *History, id ...int) (*SummaryReader, error) {
+	return clientFor(tool, email).StreamSummaryContext(ctx, db, p, h, id...)
+}
+
+// StreamSummary returns a SummaryReader that reads the response to an ESummary query
+// on the specified id list. If h is not nil and its fields are non-zero, its field values
+// are passed to ESummary. StreamSummary returns an error if both h is nil and id has
+// length zero. It is the responsibility of the caller to close the returned SummaryReader.
+func (c *Client) StreamSummary(db string, p *Parameters, h *History, id ...int) (*SummaryReader, error) {
+	return c.StreamSummaryContext(context.Background(), db, p, h, id...)
+}
+
+// StreamSummaryContext is like StreamSummary but uses ctx to cancel the request.
+func (c *Client) StreamSummaryContext(ctx context.Context, db string, p *Parameters, h *History, id ...int) (*SummaryReader, error) {
+	v, err := summaryValues(db, p, h, id)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := c.client(SummaryURL, v).GetContext(ctx, SummaryURL, v)
+	if err != nil {
+		return nil, err
+	}
+	r := NewSummaryReader(rc)
+	r.strict = c.Strict
+	return r, nil
+}
+
+// StreamLink returns a LinkReader that reads the response to an ELink action on the
+// specified ids list. If h is not nil and its fields are non-zero, its field values are
+// passed to ELink. StreamLink returns an error if both h is nil and ids has length zero.
+// It is the responsibility of the caller to close the returned LinkReader.
+func StreamLink(fromDb, toDb, cmd, query string, p *Parameters, tool, email string, h *History, ids ...[]int) (*LinkReader, error) {
+	return StreamLinkContext(context.Background(), fromDb, toDb, cmd, query, p, tool, email, h, ids...)
+}
+
+// StreamLinkContext is like StreamLink but uses ctx to cancel the request.
+func StreamLinkContext(ctx context.Context, fromDb, toDb, cmd, query string, p *Parameters, tool, email string, h *History, ids ...[]int) (*LinkReader, error) {
+	return clientFor(tool, email).StreamLinkContext(ctx, fromDb, toDb, cmd, query, p, h, ids...)
+}
+
+// StreamLink returns a LinkReader that reads the response to an ELink action on the
+// specified ids list. If h is not nil and its fields are non-zero, its field values are
+// passed to ELink. StreamLink returns an error if both h is nil and ids has length zero.
+// It is the responsibility of the caller to close the returned LinkReader.
+func (c *Client) StreamLink(fromDb, toDb, cmd, query string, p *Parameters, h *History, ids ...[]int) (*LinkReader, error) {
+	return c.StreamLinkContext(context.Background(), fromDb, toDb, cmd, query, p, h, ids...)
+}
+
+// StreamLinkContext is like StreamLink but uses ctx to cancel the request.
+func (c *Client) StreamLinkContext(ctx context.Context, fromDb, toDb, cmd, query string, p *Parameters, h *History, ids ...[]int) (*LinkReader, error) {
+	v, err := linkValues(fromDb, toDb, cmd, query, p, h, ids)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := c.client(LinkURL, v).GetContext(ctx, LinkURL, v)
+	if err != nil {
+		return nil, err
+	}
+	r := NewLinkReader(rc)
+	r.strict = c.Strict
+	return r, nil
+}
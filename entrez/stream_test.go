@@ -0,0 +1,170 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package entrez
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/biogo/ncbi"
+	"github.com/biogo/ncbi/entrez/link"
+	"github.com/biogo/ncbi/entrez/summary"
+
+	"gopkg.in/check.v1"
+)
+
+const (
+	streamSummary = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE eSummaryResult PUBLIC "-//NLM//DTD eSummaryResult, 29 October 2004//EN" "http://www.ncbi.nlm.nih.gov/entrez/query/DTD/eSummary_041029.dtd">
+<eSummaryResult>
+<DocSum>
+	<Id>6678417</Id>
+	<Item Name="Caption" Type="String">NP_033443</Item>
+	<Item Name="Gi" Type="Integer">6678417</Item>
+</DocSum>
+<ERROR>Invalid uid 1 at position=1</ERROR>
+<DocSum>
+	<Id>9507199</Id>
+	<Item Name="Caption" Type="String">NP_062226</Item>
+	<Item Name="Gi" Type="Integer">9507199</Item>
+</DocSum>
+</eSummaryResult>
+`
+
+	streamLink = `<?xml version="1.0"?>
+<!DOCTYPE eLinkResult PUBLIC "-//NLM//DTD eLinkResult, 23 November 2010//EN" "http://www.ncbi.nlm.nih.gov/entrez/query/DTD/eLink_101123.dtd">
+<eLinkResult>
+	<LinkSet>
+		<DbFrom>protein</DbFrom>
+		<IdList>
+			<Id>15718680</Id>
+		</IdList>
+		<LinkSetDb>
+			<DbTo>gene</DbTo>
+			<LinkName>protein_gene</LinkName>
+			<Link>
+				<Id>3702</Id>
+			</Link>
+		</LinkSetDb>
+	</LinkSet>
+	<LinkSet>
+		<DbFrom>protein</DbFrom>
+		<IdList>
+			<Id>157427902</Id>
+		</IdList>
+		<ERROR>Some link error</ERROR>
+	</LinkSet>
+</eLinkResult>
+`
+)
+
+func (s *S) TestElementReader(c *check.C) {
+	r := NewElementReader(strings.NewReader(`<Set><Rec><Id>1</Id></Rec><Other/><Rec><Id>2</Id></Rec><ERROR>Bad id</ERROR></Set>`), "efetch")
+	var ids []int
+	for {
+		var rec struct{ Id int }
+		if !r.Next("Rec", &rec) {
+			break
+		}
+		ids = append(ids, rec.Id)
+	}
+	c.Check(ids, check.DeepEquals, []int{1, 2})
+	c.Check(r.Err(), check.DeepEquals, &Error{Util: "efetch", Msg: "Bad id"})
+	c.Check(r.Close(), check.Equals, nil)
+
+	r = NewElementReader(strings.NewReader(`<Set><Rec><Id>1</Id></Rec><Skip/><Other><Id>2</Id></Other></Set>`), "efetch")
+	var rec, other struct{ Id int }
+	targets := map[string]interface{}{"Rec": &rec, "Other": &other}
+	var names []string
+	for {
+		name, ok := r.NextOf(targets)
+		if !ok {
+			break
+		}
+		names = append(names, name)
+	}
+	c.Check(names, check.DeepEquals, []string{"Rec", "Other"})
+	c.Check([]int{rec.Id, other.Id}, check.DeepEquals, []int{1, 2})
+	c.Check(r.Err(), check.Equals, nil)
+}
+
+func (s *S) TestSummaryReader(c *check.C) {
+	var want Summary
+	err := xml.Unmarshal([]byte(streamSummary), &want)
+	c.Assert(err, check.Equals, nil)
+
+	r := NewSummaryReader(strings.NewReader(streamSummary))
+	var got []summary.Document
+	for r.Next() {
+		got = append(got, r.Document())
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Check(got, check.DeepEquals, want.Documents)
+
+	r = NewSummaryReader(strings.NewReader(streamSummary))
+	r.strict = true
+	for r.Next() {
+	}
+	var pe *PartialError
+	c.Assert(errors.As(r.Err(), &pe), check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+	c.Check(pe.InvalidIDs, check.DeepEquals, []int{1})
+
+	r = NewSummaryReader(strings.NewReader(`<eSummaryResult><ERROR>Empty id list</ERROR></eSummaryResult>`))
+	c.Check(r.Next(), check.Equals, false)
+	var e *Error
+	c.Check(errors.As(r.Err(), &e), check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+}
+
+func (s *S) TestLinkReader(c *check.C) {
+	var want Link
+	err := xml.Unmarshal([]byte(streamLink), &want)
+	c.Assert(err, check.Equals, nil)
+
+	r := NewLinkReader(strings.NewReader(streamLink))
+	var got []link.LinkSet
+	for r.Next() {
+		got = append(got, r.LinkSet())
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Check(got, check.DeepEquals, want.LinkSets)
+
+	r = NewLinkReader(strings.NewReader(streamLink))
+	r.strict = true
+	for r.Next() {
+	}
+	var pe *PartialError
+	c.Check(errors.As(r.Err(), &pe), check.Equals, true, check.Commentf("unexpected error: %v", r.Err()))
+}
+
+func (s *S) TestStreamSummary(c *check.C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(streamSummary))
+	}))
+	defer srv.Close()
+	cl := &Client{Client: ncbi.Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{Base: srv.URL + "/"},
+		Tool:    tool,
+		Limiter: ncbi.NewLimiter(0),
+	}}
+
+	_, err := cl.StreamSummary("protein", nil, nil)
+	c.Check(err, check.Equals, ErrNoIdProvided)
+
+	r, err := cl.StreamSummary("protein", nil, nil, 6678417, 1, 9507199)
+	c.Assert(err, check.Equals, nil)
+	var ids []int
+	for r.Next() {
+		ids = append(ids, r.Document().Id)
+	}
+	c.Check(r.Err(), check.Equals, nil)
+	c.Check(r.Close(), check.Equals, nil)
+	c.Check(ids, check.DeepEquals, []int{6678417, 9507199})
+}
@@ -5,6 +5,9 @@
 package entrez
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/biogo/ncbi/entrez/summary"
 )
 
@@ -35,3 +38,23 @@ type Summary struct {
 	Documents []summary.Document `xml:"DocSum"`
 	Err       []string           `xml:"ERROR"`
 }
+
+func (s *Summary) reportsError() bool { return len(s.Err) != 0 }
+
+// partialErr returns a *PartialError holding the errors reported by ESummary for ids
+// that could not be summarised, or nil if there are none.
+func (s *Summary) partialErr() error {
+	e := &PartialError{Util: "esummary", Errors: s.Err}
+	for _, msg := range s.Err {
+		// ESummary reports invalid ids as "Invalid uid <id> at position=<pos>".
+		if !strings.HasPrefix(msg, "Invalid uid ") {
+			continue
+		}
+		var id int
+		_, err := fmt.Sscanf(msg, "Invalid uid %d", &id)
+		if err == nil {
+			e.InvalidIDs = append(e.InvalidIDs, id)
+		}
+	}
+	return e.err()
+}
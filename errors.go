@@ -0,0 +1,72 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// maxErrorBody is the maximum number of bytes of a response body
+// retained by a StatusError.
+const maxErrorBody = 512
+
+// StatusError is returned when a request receives a response with an HTTP status
+// other than 200 OK.
+type StatusError struct {
+	// Code and Status are the status code and
+	// status line of the response.
+	Code   int
+	Status string
+
+	// Body holds the leading bytes of the
+	// response body.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	body := bytes.TrimSpace(e.Body)
+	if len(body) == 0 {
+		return fmt.Sprintf("ncbi: %s", e.Status)
+	}
+	return fmt.Sprintf("ncbi: %s: %s", e.Status, body)
+}
+
+// RateLimitError is returned when a server responds with status 429 (Too Many
+// Requests), indicating that requests are being sent too frequently.
+type RateLimitError struct {
+	StatusError
+
+	// RetryAfter is the delay requested by the
+	// server, or zero if none was given.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter == 0 {
+		return "ncbi: rate limit exceeded"
+	}
+	return fmt.Sprintf("ncbi: rate limit exceeded: retry after %v", e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error { return &e.StatusError }
+
+// statusError returns a *StatusError or *RateLimitError describing resp and
+// closes the response body.
+func statusError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	se := StatusError{Code: resp.StatusCode, Status: resp.Status, Body: body}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		d, _ := retryAfter(resp)
+		return &RateLimitError{StatusError: se, RetryAfter: d}
+	}
+	return &se
+}
@@ -0,0 +1,66 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestStatusError(c *check.C) {
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	for i, t := range []struct {
+		status     int
+		header     string
+		body       string
+		wantRate   bool
+		retryAfter time.Duration
+	}{
+		{status: http.StatusBadRequest, body: "bad request"},
+		{status: http.StatusNotFound, body: strings.Repeat("x", 2*maxErrorBody)},
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusTooManyRequests, header: "7", wantRate: true, retryAfter: 7 * time.Second},
+	} {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t.header != "" {
+				w.Header().Set("Retry-After", t.header)
+			}
+			w.WriteHeader(t.status)
+			w.Write([]byte(t.body))
+		}))
+		cl := &Client{
+			HTTP:  srv.Client(),
+			Bases: map[string]string{base: srv.URL + "/"},
+			Retry: NoRetry,
+		}
+
+		var d struct{}
+		err := cl.GetXML(Util(base+"einfo.fcgi"), url.Values{}, &d)
+		var serr *StatusError
+		c.Assert(errors.As(err, &serr), check.Equals, true, check.Commentf("Test %d: %v", i, err))
+		c.Check(serr.Code, check.Equals, t.status, check.Commentf("Test %d", i))
+		want := t.body
+		if len(want) > maxErrorBody {
+			want = want[:maxErrorBody]
+		}
+		c.Check(string(serr.Body), check.Equals, want, check.Commentf("Test %d", i))
+
+		var rerr *RateLimitError
+		c.Check(errors.As(err, &rerr), check.Equals, t.wantRate, check.Commentf("Test %d", i))
+		if rerr != nil {
+			c.Check(rerr.RetryAfter, check.Equals, t.retryAfter, check.Commentf("Test %d", i))
+		}
+
+		_, err = cl.Get(Util(base+"efetch.fcgi"), url.Values{})
+		c.Check(errors.As(err, &serr), check.Equals, true, check.Commentf("Test %d: %v", i, err))
+		srv.Close()
+	}
+}
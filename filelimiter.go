@@ -0,0 +1,94 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"time"
+)
+
+var errNoFileLock = errors.New("ncbi: file limiter not supported on " + runtime.GOOS)
+
+// NewFileLimiter returns a Limiter with the specified interval and burst size that holds
+// its state in the file at path, creating the file if necessary. All Limiters sharing a
+// path, whether in the same or different processes on a host, share a single limit.
+// This allows a group of processes sending requests from one address to collectively
+// respect a service's request rate. All users of a path should use the same interval and
+// burst size. If the file cannot be locked, read or written when the Limiter is used,
+// events are limited using the Limiter's own state and the error is reported by the
+// Limiter's Err method. NewFileLimiter is not supported on all platforms.
+func NewFileLimiter(path string, d time.Duration, burst int) (*Limiter, error) {
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := NewBurstLimiter(d, burst)
+	l.shared = f
+	return l, nil
+}
+
+// Close closes the file used by a Limiter returned by NewFileLimiter. The Limiter
+// continues to limit events within the process after Close is called. Close has no
+// effect on other Limiters.
+func (l *Limiter) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.shared == nil {
+		return nil
+	}
+	err := l.shared.f.Close()
+	l.shared = nil
+	return err
+}
+
+// lockFile holds a Limiter's state in a file so that it can be shared between
+// processes. The state is stored as the little-endian encoding of the token count
+// as a float64, the time of the last update in nanoseconds since the Unix epoch
+// and the reservation sequence number.
+type lockFile struct {
+	f *os.File
+}
+
+const lockFileStateSize = 24
+
+// update reads the state of l from the file, calls fn and writes the state back
+// to the file while the file is locked.
+func (lf *lockFile) update(l *Limiter, fn func(now time.Time)) error {
+	err := lf.lock()
+	if err != nil {
+		fn(time.Now())
+		return err
+	}
+	defer lf.unlock()
+
+	var buf [lockFileStateSize]byte
+	n, err := lf.f.ReadAt(buf[:], 0)
+	now := time.Now()
+	switch {
+	case n == len(buf):
+		l.tokens = math.Float64frombits(binary.LittleEndian.Uint64(buf[0:]))
+		l.last = time.Unix(0, int64(binary.LittleEndian.Uint64(buf[8:])))
+		l.seq = binary.LittleEndian.Uint64(buf[16:])
+	case err == io.EOF:
+		// The file is new, so the Limiter's own
+		// state is written to it.
+	default:
+		fn(now)
+		return err
+	}
+
+	fn(now)
+
+	binary.LittleEndian.PutUint64(buf[0:], math.Float64bits(l.tokens))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(l.last.UnixNano()))
+	binary.LittleEndian.PutUint64(buf[16:], l.seq)
+	_, err = lf.f.WriteAt(buf[:], 0)
+	return err
+}
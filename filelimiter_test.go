@@ -0,0 +1,68 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestFileLimiter(c *check.C) {
+	path := filepath.Join(c.MkDir(), "limit")
+	a, err := NewFileLimiter(path, time.Hour, 2)
+	if err == errNoFileLock {
+		c.Skip(err.Error())
+	}
+	c.Assert(err, check.Equals, nil)
+	defer a.Close()
+	b, err := NewFileLimiter(path, time.Hour, 2)
+	c.Assert(err, check.Equals, nil)
+	defer b.Close()
+
+	// The two Limiters hold separate file descriptions, as
+	// Limiters in different processes would, but share a limit.
+	c.Check(a.Reserve().Delay(), check.Equals, time.Duration(0))
+	c.Check(b.Reserve().Delay(), check.Equals, time.Duration(0))
+	r := a.Reserve()
+	c.Check(r.Delay() > 59*time.Minute, check.Equals, true)
+	d := b.Reserve().Delay()
+	c.Check(d > 119*time.Minute, check.Equals, true, check.Commentf("delay %v", d))
+
+	// A later reservation from another Limiter prevents the
+	// token from being returned.
+	r.Cancel()
+	d = a.Reserve().Delay()
+	c.Check(d > 179*time.Minute, check.Equals, true, check.Commentf("delay %v", d))
+
+	c.Check(a.Close(), check.Equals, nil)
+	c.Check(a.Close(), check.Equals, nil)
+	d = b.Reserve().Delay()
+	c.Check(d > 239*time.Minute, check.Equals, true, check.Commentf("delay %v", d))
+}
+
+func (s *S) TestFileLimiterUnwritable(c *check.C) {
+	path := filepath.Join(c.MkDir(), "limit")
+	c.Assert(ioutil.WriteFile(path, nil, 0444), check.Equals, nil)
+	f, err := os.Open(path)
+	c.Assert(err, check.Equals, nil)
+	defer f.Close()
+
+	// The read-only file cannot hold the Limiter's state,
+	// but events are still limited.
+	l := NewLimiter(50 * time.Millisecond)
+	l.shared = &lockFile{f: f}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Check(l.WaitContext(context.Background()), check.Equals, nil)
+	}
+	d := time.Since(start)
+	c.Check(d >= 100*time.Millisecond, check.Equals, true, check.Commentf("elapsed %v", d))
+	c.Check(l.Err(), check.NotNil)
+}
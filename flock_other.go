@@ -0,0 +1,15 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package ncbi
+
+func openLockFile(path string) (*lockFile, error) {
+	return nil, errNoFileLock
+}
+
+func (lf *lockFile) lock() error   { return errNoFileLock }
+func (lf *lockFile) unlock() error { return errNoFileLock }
@@ -0,0 +1,34 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ncbi
+
+import (
+	"os"
+	"syscall"
+)
+
+func openLockFile(path string) (*lockFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	return &lockFile{f: f}, nil
+}
+
+func (lf *lockFile) lock() error {
+	for {
+		err := syscall.Flock(int(lf.f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func (lf *lockFile) unlock() error {
+	return syscall.Flock(int(lf.f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,190 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook receives notifications of the progress of requests made by a Client's GetResponse,
+// GetXML and Get methods, and the methods based on them. Hook methods are called
+// synchronously by the goroutine making the request and must be safe for concurrent use.
+// The value of any api_key parameter is redacted from the URLs held by the errors passed
+// to a Hook and returned by the Client.
+type Hook interface {
+	// Start is called before each attempt at a request,
+	// before the Client's Limiter is waited on.
+	Start(r *RequestInfo)
+
+	// Wait is called after the Client's Limiter has been
+	// waited on with the time spent waiting.
+	Wait(r *RequestInfo, d time.Duration)
+
+	// Response is called when the status of the response
+	// to an attempt has been received.
+	Response(r *RequestInfo, status int)
+
+	// Done is called when the body of a response returned
+	// to the caller is closed with the number of bytes read
+	// from the body.
+	Done(r *RequestInfo, n int64)
+
+	// Retry is called when a failed attempt is to be retried
+	// after the given delay. err describes the failure.
+	Retry(r *RequestInfo, delay time.Duration, err error)
+
+	// Error is called when a request fails without a response
+	// being returned to the caller. Responses with an error
+	// status are reported by Response.
+	Error(r *RequestInfo, err error)
+}
+
+// RequestInfo describes a request for a Hook. The same RequestInfo is passed to
+// each Hook method called for a request.
+type RequestInfo struct {
+	// Context is the context of the request.
+	Context context.Context
+
+	// Util is the short name of the utility, for example
+	// "esearch" for an ESearch request or "Blast Put" for
+	// a BLAST Put command.
+	Util string
+
+	// Method is the HTTP method used for the request.
+	Method string
+
+	// URL is the URL of the request, including the parameters
+	// sent in the body of POST requests. The value of any
+	// api_key parameter is redacted.
+	URL *url.URL
+
+	// Attempt is the number of the current attempt,
+	// starting from one.
+	Attempt int
+
+	// Start is the time the request was started.
+	Start time.Time
+
+	// Sent is the time the current attempt was sent.
+	Sent time.Time
+}
+
+// newRequestInfo returns a RequestInfo for a request to ut with the given method and
+// prepared URL u.
+func newRequestInfo(ctx context.Context, ut Util, method string, u *url.URL) *RequestInfo {
+	return &RequestInfo{
+		Context: ctx,
+		Util:    utilName(ut, u.Query()),
+		Method:  method,
+		URL:     redact(u),
+		Start:   time.Now(),
+	}
+}
+
+// redact returns a copy of u with the value of any api_key parameter redacted.
+func redact(u *url.URL) *url.URL {
+	ru := *u
+	v := u.Query()
+	if v.Get("api_key") != "" {
+		v["api_key"] = []string{"REDACTED"}
+		ru.RawQuery = v.Encode()
+	}
+	return &ru
+}
+
+// redactError redacts the value of any api_key parameter in the URL held by a
+// *url.Error in the chain of err.
+func redactError(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		u, perr := url.Parse(uerr.URL)
+		if perr == nil {
+			uerr.URL = redact(u).String()
+		}
+	}
+	return err
+}
+
+// utilName returns the short name of the utility ut called with the parameters v.
+func utilName(ut Util, v url.Values) string {
+	name := string(ut)
+	if u, err := url.Parse(name); err == nil {
+		name = u.Path
+	}
+	name = path.Base(name)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if cmd := v.Get("CMD"); cmd != "" {
+		name += " " + cmd
+	}
+	return name
+}
+
+// Hooks is a Hook that calls each of its elements in order.
+type Hooks []Hook
+
+func (h Hooks) Start(r *RequestInfo) {
+	for _, e := range h {
+		e.Start(r)
+	}
+}
+
+func (h Hooks) Wait(r *RequestInfo, d time.Duration) {
+	for _, e := range h {
+		e.Wait(r, d)
+	}
+}
+
+func (h Hooks) Response(r *RequestInfo, status int) {
+	for _, e := range h {
+		e.Response(r, status)
+	}
+}
+
+func (h Hooks) Done(r *RequestInfo, n int64) {
+	for _, e := range h {
+		e.Done(r, n)
+	}
+}
+
+func (h Hooks) Retry(r *RequestInfo, delay time.Duration, err error) {
+	for _, e := range h {
+		e.Retry(r, delay, err)
+	}
+}
+
+func (h Hooks) Error(r *RequestInfo, err error) {
+	for _, e := range h {
+		e.Error(r, err)
+	}
+}
+
+// countingBody is a response body that reports the number of bytes read
+// from it to a Hook when it is closed.
+type countingBody struct {
+	io.ReadCloser
+	hook Hook
+	info *RequestInfo
+	n    int64
+	once sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.hook.Done(b.info, b.n) })
+	return err
+}
@@ -0,0 +1,126 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+// recordingHook is a Hook that records the events it receives.
+type recordingHook struct {
+	mu     sync.Mutex
+	events []string
+	urls   []string
+}
+
+func (h *recordingHook) add(r *RequestInfo, format string, args ...interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf("%s/%d ", r.Util, r.Attempt)+fmt.Sprintf(format, args...))
+	h.urls = append(h.urls, r.URL.String())
+}
+
+func (h *recordingHook) Start(r *RequestInfo)                 { h.add(r, "start") }
+func (h *recordingHook) Wait(r *RequestInfo, _ time.Duration) { h.add(r, "wait") }
+func (h *recordingHook) Response(r *RequestInfo, status int)  { h.add(r, "response %d", status) }
+func (h *recordingHook) Done(r *RequestInfo, n int64)         { h.add(r, "done %d", n) }
+func (h *recordingHook) Retry(r *RequestInfo, _ time.Duration, err error) {
+	h.add(r, "retry %v", err)
+}
+func (h *recordingHook) Error(r *RequestInfo, err error) { h.add(r, "error") }
+
+func (s *S) TestHook(c *check.C) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	rec := &recordingHook{}
+	var m Metrics
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Retry:   &Retry{MaxAttempts: 2, MinBackoff: time.Millisecond},
+		Hook:    Hooks{rec, &m},
+	}
+	r, err := cl.Get(Util(base+"esearch.fcgi"), url.Values{"term": []string{"hox"}, "api_key": []string{"secret"}})
+	c.Assert(err, check.Equals, nil)
+	b, err := ioutil.ReadAll(r)
+	c.Check(err, check.Equals, nil)
+	c.Check(string(b), check.Equals, "hello")
+	r.Close()
+
+	c.Check(rec.events, check.DeepEquals, []string{
+		"esearch/1 start",
+		"esearch/1 wait",
+		"esearch/1 response 503",
+		"esearch/1 retry ncbi: 503 Service Unavailable",
+		"esearch/2 start",
+		"esearch/2 wait",
+		"esearch/2 response 200",
+		"esearch/2 done 5",
+	})
+	for _, u := range rec.urls {
+		c.Check(strings.Contains(u, "secret"), check.Equals, false, check.Commentf("unredacted URL: %s", u))
+		c.Check(strings.Contains(u, "term=hox"), check.Equals, true, check.Commentf("missing parameters: %s", u))
+	}
+
+	srv.Close()
+	cl.Retry = NoRetry
+	_, err = cl.Get(Util(base+"efetch.fcgi"), url.Values{})
+	c.Check(err, check.Not(check.Equals), nil)
+	c.Check(rec.events[len(rec.events)-1], check.Equals, "efetch/1 error")
+
+	snap := m.Snapshot()
+	c.Check(snap["esearch"].Requests, check.Equals, int64(1))
+	c.Check(snap["esearch"].Retries, check.Equals, int64(1))
+	c.Check(snap["esearch"].Bytes, check.Equals, int64(5))
+	c.Check(snap["esearch"].Responses, check.DeepEquals, map[int]int64{200: 1, 503: 1})
+	c.Check(snap["efetch"].Errors, check.Equals, int64(1))
+
+	var buf strings.Builder
+	c.Assert(m.WritePrometheus(&buf), check.Equals, nil)
+	for _, want := range []string{
+		"# TYPE ncbi_requests_total counter\n",
+		`ncbi_requests_total{util="esearch"} 1` + "\n",
+		`ncbi_errors_total{util="efetch"} 1` + "\n",
+		`ncbi_responses_total{util="esearch",code="503"} 1` + "\n",
+		`ncbi_response_bytes_total{util="esearch"} 5` + "\n",
+	} {
+		c.Check(strings.Contains(buf.String(), want), check.Equals, true, check.Commentf("missing %q in:\n%s", want, buf.String()))
+	}
+	c.Check(strings.Contains(m.String(), `"esearch":{"requests":1,`), check.Equals, true, check.Commentf("unexpected JSON: %s", m.String()))
+}
+
+func (s *S) TestUtilName(c *check.C) {
+	for _, t := range []struct {
+		ut   Util
+		v    url.Values
+		want string
+	}{
+		{ut: "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/esearch.fcgi", want: "esearch"},
+		{ut: "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/ecitmatch.cgi", want: "ecitmatch"},
+		{ut: "http://www.ncbi.nlm.nih.gov/blast/Blast.cgi", v: url.Values{"CMD": []string{"Put"}}, want: "Blast Put"},
+	} {
+		c.Check(utilName(t.ut, t.v), check.Equals, t.want)
+	}
+}
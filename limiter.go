@@ -0,0 +1,375 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter implements a thread-safe event frequency limit. Limiter is a token bucket;
+// tokens are added to the bucket at a rate of one per interval up to the Limiter's
+// burst size, and each event consumes a token. A Limiter with a zero interval does
+// not limit events.
+type Limiter struct {
+	m        sync.Mutex
+	interval time.Duration
+	burst    int
+	tokens   float64
+	last     time.Time
+	seq      uint64
+
+	// wait serialises waiting events so that
+	// the interval before an event is measured
+	// from the time the previous event was
+	// permitted.
+	wait sync.Mutex
+
+	// parent is a Limiter that must also
+	// permit each event.
+	parent *Limiter
+
+	// shared holds the lock file used to share
+	// the Limiter's state between processes.
+	// err is the error from the most recent
+	// use of the file.
+	shared *lockFile
+	err    error
+}
+
+// NewLimiter returns a Limiter that will wait for the specified duration between Wait calls.
+func NewLimiter(d time.Duration) *Limiter {
+	return NewBurstLimiter(d, 1)
+}
+
+// NewBurstLimiter returns a Limiter that allows events at an average rate of one per
+// the specified duration with bursts of up to burst events. Burst values less than one
+// are treated as one.
+func NewBurstLimiter(d time.Duration, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{interval: d, burst: burst, tokens: float64(burst)}
+}
+
+// NewNestedLimiter returns a Limiter like NewBurstLimiter that also waits for parent to
+// permit each event. Events permitted by the returned Limiter are subject to both
+// limits, allowing a lower rate to be imposed on a class of events sharing a limit.
+func NewNestedLimiter(parent *Limiter, d time.Duration, burst int) *Limiter {
+	l := NewBurstLimiter(d, burst)
+	l.parent = parent
+	return l
+}
+
+// Wait blocks until the Limiter permits an event.
+func (l *Limiter) Wait() {
+	l.WaitContext(context.Background())
+}
+
+// WaitContext blocks until the Limiter permits an event or until ctx is done. If ctx
+// is done before the wait has completed, ctx.Err() is returned and the reservation
+// made for the event is cancelled. As for the Wait method of earlier versions of the
+// Limiter, waiting events are permitted in turn and the next event is delayed by the
+// interval from the time the waiting event is permitted.
+func (l *Limiter) WaitContext(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = l.lockWait(ctx)
+	if err != nil {
+		return err
+	}
+	err = l.waitContext(ctx)
+	l.wait.Unlock()
+	if err != nil || l.parent == nil {
+		return err
+	}
+	return l.parent.WaitContext(ctx)
+}
+
+func (l *Limiter) waitContext(ctx context.Context) error {
+	// A reservation is made from the Limiter's
+	// own state if its file cannot be used, so
+	// the error is reported only by Err.
+	r := l.Reserve()
+	d := r.Delay()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		l.settle(r)
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// lockWait acquires l.wait, returning ctx.Err() if ctx is done first.
+func (l *Limiter) lockWait(ctx context.Context) error {
+	if ctx.Done() == nil {
+		l.wait.Lock()
+		return nil
+	}
+	locked := make(chan struct{})
+	go func() {
+		l.wait.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			l.wait.Unlock()
+		}()
+		return ctx.Err()
+	}
+}
+
+// settle records that the event reserved by r was permitted late so that the time
+// between the reserved time and now is not credited to the Limiter. It has no effect
+// if a later reservation has been made.
+func (l *Limiter) settle(r *Reservation) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.update(func(now time.Time) {
+		if r.seq != l.seq || !now.After(r.at) {
+			return
+		}
+		l.advance(r.at)
+		l.last = now
+	})
+}
+
+// Reserve reserves a token from the Limiter and returns a Reservation holding the
+// time at which the reserved event may occur. Reserve does not block. If the Limiter
+// is shared through a lock file that cannot be read or written, the reservation is
+// made from the Limiter's most recently known state and the error is reported by Err.
+// Reserve does not reserve a token from the parent of a nested Limiter.
+func (l *Limiter) Reserve() *Reservation {
+	r, _ := l.reserve()
+	return r
+}
+
+func (l *Limiter) reserve() (*Reservation, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	var r *Reservation
+	err := l.update(func(now time.Time) {
+		l.advance(now)
+		l.tokens--
+		l.seq++
+		r = &Reservation{l: l, at: now, seq: l.seq}
+		if l.tokens < 0 {
+			r.at = now.Add(time.Duration(-l.tokens * float64(l.interval)))
+		}
+	})
+	return r, err
+}
+
+// Err returns the error encountered by the most recent use of the file holding the
+// state of a Limiter returned by NewFileLimiter, or nil if it succeeded. Events are
+// limited using the Limiter's own state while its file cannot be used, so the error
+// is informational.
+func (l *Limiter) Err() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.err
+}
+
+// Interval returns the interval between token additions for the Limiter.
+func (l *Limiter) Interval() time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.interval
+}
+
+// SetInterval sets the interval between token additions for the Limiter. Existing
+// reservations are not altered.
+func (l *Limiter) SetInterval(d time.Duration) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.update(l.advance)
+	l.interval = d
+}
+
+// Burst returns the burst size of the Limiter.
+func (l *Limiter) Burst() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.burstSize()
+}
+
+// SetBurst sets the burst size of the Limiter. Values less than one are treated as one.
+func (l *Limiter) SetBurst(n int) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	l.update(func(now time.Time) {
+		l.advance(now)
+		l.burst = n
+		if l.tokens > float64(n) {
+			l.tokens = float64(n)
+		}
+	})
+}
+
+// Throttle prevents the Limiter from permitting any unreserved event until at least
+// the specified duration has passed. Throttle is intended to be used when a server
+// indicates that requests are being made too frequently. Throttle has no effect on a
+// Limiter with a zero interval. The parent of a nested Limiter is also throttled.
+func (l *Limiter) Throttle(d time.Duration) {
+	if l.parent != nil {
+		l.parent.Throttle(d)
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.interval <= 0 {
+		return
+	}
+	l.update(func(now time.Time) {
+		l.advance(now)
+		need := 1 - float64(d)/float64(l.interval)
+		if l.tokens > need {
+			l.tokens = need
+		}
+	})
+}
+
+func (l *Limiter) burstSize() int {
+	if l.burst < 1 {
+		return 1
+	}
+	return l.burst
+}
+
+// update calls fn with the current time to modify the Limiter's state. If the
+// Limiter is shared through a lock file, the state is read from the file before
+// fn is called and written back afterwards while the file is locked. If the file
+// cannot be read, fn operates on the Limiter's most recently known state.
+// update must be called with l.m held.
+func (l *Limiter) update(fn func(now time.Time)) error {
+	if l.shared == nil {
+		fn(time.Now())
+		return nil
+	}
+	l.err = l.shared.update(l, fn)
+	return l.err
+}
+
+// advance adds the tokens accumulated since the last update of the Limiter.
+func (l *Limiter) advance(now time.Time) {
+	burst := float64(l.burstSize())
+	if l.interval <= 0 {
+		l.tokens = burst
+		l.last = now
+		return
+	}
+	if !now.After(l.last) {
+		return
+	}
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+}
+
+// A Reservation holds a token reserved from a Limiter.
+type Reservation struct {
+	l         *Limiter
+	at        time.Time
+	seq       uint64
+	cancelled bool
+}
+
+// Time returns the time at which the reserved event may occur.
+func (r *Reservation) Time() time.Time {
+	return r.at
+}
+
+// Delay returns the duration until the reserved event may occur.
+func (r *Reservation) Delay() time.Duration {
+	d := time.Until(r.at)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Cancel indicates that the reserved event will not occur. If the reserved time has
+// not yet passed and no later reservation has been made from the Limiter, the token
+// is returned to the Limiter.
+func (r *Reservation) Cancel() {
+	l := r.l
+	l.m.Lock()
+	defer l.m.Unlock()
+	if r.cancelled {
+		return
+	}
+	r.cancelled = true
+	l.update(func(now time.Time) {
+		if !r.at.After(now) || r.seq != l.seq {
+			return
+		}
+		l.advance(now)
+		l.tokens++
+		if burst := float64(l.burstSize()); l.tokens > burst {
+			l.tokens = burst
+		}
+	})
+}
+
+var limiters = struct {
+	sync.Mutex
+	byHost map[string]*Limiter
+}{byHost: make(map[string]*Limiter)}
+
+// SharedLimiter returns the Limiter registered for host. If no Limiter is registered
+// for host, a Limiter with the specified interval and burst size is created and
+// registered. SharedLimiter allows independently constructed clients to share a
+// single request frequency limit for a service.
+func SharedLimiter(host string, d time.Duration, burst int) *Limiter {
+	host = strings.ToLower(host)
+	limiters.Lock()
+	defer limiters.Unlock()
+	l, ok := limiters.byHost[host]
+	if !ok {
+		l = NewBurstLimiter(d, burst)
+		limiters.byHost[host] = l
+	}
+	return l
+}
+
+// RegisterLimiter registers l as the Limiter for host, replacing any existing
+// registration. If l is nil, the registration for host is removed.
+func RegisterLimiter(host string, l *Limiter) {
+	host = strings.ToLower(host)
+	limiters.Lock()
+	defer limiters.Unlock()
+	if l == nil {
+		delete(limiters.byHost, host)
+		return
+	}
+	limiters.byHost[host] = l
+}
+
+// LimiterFor returns the Limiter registered for host, or nil if no Limiter is
+// registered.
+func LimiterFor(host string) *Limiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+	return limiters.byHost[strings.ToLower(host)]
+}
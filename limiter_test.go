@@ -0,0 +1,154 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestBurstLimiter(c *check.C) {
+	l := NewBurstLimiter(time.Hour, 3)
+	for i := 0; i < 3; i++ {
+		c.Check(l.Reserve().Delay(), check.Equals, time.Duration(0), check.Commentf("event %d", i))
+	}
+	d := l.Reserve().Delay()
+	c.Check(d > 59*time.Minute && d <= time.Hour, check.Equals, true, check.Commentf("delay %v", d))
+}
+
+func (s *S) TestReservationCancel(c *check.C) {
+	l := NewLimiter(time.Hour)
+	l.Reserve()
+	r := l.Reserve()
+	c.Check(r.Delay() > 59*time.Minute, check.Equals, true)
+	r.Cancel()
+	r = l.Reserve()
+	c.Check(r.Delay() > 59*time.Minute && r.Delay() <= time.Hour, check.Equals, true)
+
+	// Only the most recent reservation returns its token.
+	early := l.Reserve()
+	l.Reserve()
+	early.Cancel()
+	d := l.Reserve().Delay()
+	c.Check(d > 3*time.Hour, check.Equals, true, check.Commentf("delay %v", d))
+}
+
+func (s *S) TestLimiterSetInterval(c *check.C) {
+	l := NewLimiter(time.Hour)
+	l.Reserve()
+	l.SetInterval(0)
+	c.Check(l.Interval(), check.Equals, time.Duration(0))
+	c.Check(l.Reserve().Delay(), check.Equals, time.Duration(0))
+	l.SetInterval(time.Hour)
+	l.Reserve()
+	c.Check(l.Reserve().Delay() > 59*time.Minute, check.Equals, true)
+
+	l.SetBurst(0)
+	c.Check(l.Burst(), check.Equals, 1)
+}
+
+func (s *S) TestLimiterThrottle(c *check.C) {
+	l := NewBurstLimiter(time.Millisecond, 10)
+	l.Throttle(time.Hour)
+	d := l.Reserve().Delay()
+	c.Check(d > 59*time.Minute && d <= time.Hour, check.Equals, true, check.Commentf("delay %v", d))
+
+	u := NewLimiter(0)
+	u.Throttle(time.Hour)
+	c.Check(u.Reserve().Delay(), check.Equals, time.Duration(0))
+}
+
+func (s *S) TestSharedLimiter(c *check.C) {
+	const host = "limiter.example.org"
+	defer RegisterLimiter(host, nil)
+
+	c.Check(LimiterFor(host), check.Equals, (*Limiter)(nil))
+	l := SharedLimiter(host, time.Second, 1)
+	c.Check(SharedLimiter("Limiter.Example.org", time.Minute, 5), check.Equals, l)
+	c.Check(LimiterFor(host), check.Equals, l)
+
+	other := NewLimiter(time.Second)
+	RegisterLimiter(host, other)
+	c.Check(LimiterFor(host), check.Equals, other)
+	RegisterLimiter(host, nil)
+	c.Check(LimiterFor(host), check.Equals, (*Limiter)(nil))
+}
+
+func (s *S) TestClientSharedLimiter(c *check.C) {
+	var calls int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	c.Assert(err, check.Equals, nil)
+
+	l := NewLimiter(time.Millisecond)
+	RegisterLimiter(u.Host, l)
+	defer RegisterLimiter(u.Host, nil)
+
+	// A Client without a Limiter uses the Limiter registered for the
+	// host, and a 429 response throttles it for the Retry-After delay.
+	cl := &Client{HTTP: srv.Client(), Retry: &Retry{MaxAttempts: 2}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cl.GetResponseContext(ctx, Util(srv.URL+"/"), url.Values{})
+	c.Check(err, check.Equals, context.DeadlineExceeded)
+	c.Check(calls, check.Equals, 1)
+	d := l.Reserve().Delay()
+	c.Check(d > 59*time.Minute, check.Equals, true, check.Commentf("delay %v", d))
+}
+
+func (s *S) TestLimiterInterval(c *check.C) {
+	l := NewLimiter(50 * time.Millisecond)
+	l.Reserve()
+	r := l.Reserve()
+
+	// An event permitted late delays the next event by
+	// the full interval from the time it was permitted.
+	time.Sleep(80 * time.Millisecond)
+	l.settle(r)
+	d := l.Reserve().Delay()
+	c.Check(d > 45*time.Millisecond, check.Equals, true, check.Commentf("delay %v", d))
+
+	l = NewLimiter(20 * time.Millisecond)
+	start := time.Now()
+	done := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		go func() {
+			l.Wait()
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		<-done
+	}
+	d = time.Since(start)
+	c.Check(d >= 4*20*time.Millisecond, check.Equals, true, check.Commentf("elapsed %v", d))
+}
+
+func (s *S) TestNestedLimiter(c *check.C) {
+	parent := NewLimiter(time.Hour)
+	l := NewNestedLimiter(parent, 0, 1)
+	c.Check(l.WaitContext(context.Background()), check.Equals, nil)
+
+	// The parent's token has been used.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c.Check(l.WaitContext(ctx), check.Equals, context.DeadlineExceeded)
+
+	other := NewLimiter(time.Millisecond)
+	l = NewNestedLimiter(other, time.Millisecond, 1)
+	l.Throttle(time.Hour)
+	d := other.Reserve().Delay()
+	c.Check(d > 59*time.Minute, check.Equals, true, check.Commentf("delay %v", d))
+}
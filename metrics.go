@@ -0,0 +1,189 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UtilMetrics holds the counters kept by a Metrics for a utility.
+type UtilMetrics struct {
+	// Requests is the number of requests made.
+	Requests int64 `json:"requests"`
+
+	// Retries is the number of failed attempts
+	// that were retried.
+	Retries int64 `json:"retries"`
+
+	// Errors is the number of requests that failed
+	// without a response.
+	Errors int64 `json:"errors"`
+
+	// Responses holds the number of responses
+	// received keyed by status code.
+	Responses map[int]int64 `json:"responses"`
+
+	// Bytes is the number of response body bytes read.
+	Bytes int64 `json:"bytes"`
+
+	// Wait is the total time spent waiting on Limiters.
+	Wait time.Duration `json:"wait_ns"`
+
+	// Latency is the total time between sending requests
+	// and receiving the response status.
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// Metrics is a Hook that counts requests and their outcomes for each utility. Metrics
+// implements the expvar.Var interface and may be published with expvar.Publish. The
+// zero value is ready to use.
+type Metrics struct {
+	mu    sync.Mutex
+	utils map[string]*UtilMetrics
+}
+
+// util returns the counters for the named utility. It must be called with m.mu held.
+func (m *Metrics) util(name string) *UtilMetrics {
+	if m.utils == nil {
+		m.utils = make(map[string]*UtilMetrics)
+	}
+	u, ok := m.utils[name]
+	if !ok {
+		u = &UtilMetrics{Responses: make(map[int]int64)}
+		m.utils[name] = u
+	}
+	return u
+}
+
+func (m *Metrics) update(r *RequestInfo, fn func(u *UtilMetrics)) {
+	m.mu.Lock()
+	fn(m.util(r.Util))
+	m.mu.Unlock()
+}
+
+// Start implements the Hook interface.
+func (m *Metrics) Start(r *RequestInfo) {
+	if r.Attempt == 1 {
+		m.update(r, func(u *UtilMetrics) { u.Requests++ })
+	}
+}
+
+// Wait implements the Hook interface.
+func (m *Metrics) Wait(r *RequestInfo, d time.Duration) {
+	m.update(r, func(u *UtilMetrics) { u.Wait += d })
+}
+
+// Response implements the Hook interface.
+func (m *Metrics) Response(r *RequestInfo, status int) {
+	d := time.Since(r.Sent)
+	m.update(r, func(u *UtilMetrics) {
+		u.Responses[status]++
+		u.Latency += d
+	})
+}
+
+// Done implements the Hook interface.
+func (m *Metrics) Done(r *RequestInfo, n int64) {
+	m.update(r, func(u *UtilMetrics) { u.Bytes += n })
+}
+
+// Retry implements the Hook interface.
+func (m *Metrics) Retry(r *RequestInfo, _ time.Duration, _ error) {
+	m.update(r, func(u *UtilMetrics) { u.Retries++ })
+}
+
+// Error implements the Hook interface.
+func (m *Metrics) Error(r *RequestInfo, _ error) {
+	m.update(r, func(u *UtilMetrics) { u.Errors++ })
+}
+
+// Snapshot returns a copy of the counters held by m keyed by utility name.
+func (m *Metrics) Snapshot() map[string]UtilMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := make(map[string]UtilMetrics, len(m.utils))
+	for name, u := range m.utils {
+		c := *u
+		c.Responses = make(map[int]int64, len(u.Responses))
+		for k, v := range u.Responses {
+			c.Responses[k] = v
+		}
+		s[name] = c
+	}
+	return s
+}
+
+// String returns a JSON representation of the counters held by m. It implements
+// the expvar.Var interface.
+func (m *Metrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// WritePrometheus writes the counters held by m to w in the Prometheus text exposition
+// format. Metric names are prefixed with "ncbi_" and labeled with the utility name.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	s := m.Snapshot()
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, c := range []struct {
+		name, help string
+		value      func(UtilMetrics) string
+	}{
+		{"ncbi_requests_total", "Requests made.", func(u UtilMetrics) string { return strconv.FormatInt(u.Requests, 10) }},
+		{"ncbi_retries_total", "Failed attempts that were retried.", func(u UtilMetrics) string { return strconv.FormatInt(u.Retries, 10) }},
+		{"ncbi_errors_total", "Requests that failed without a response.", func(u UtilMetrics) string { return strconv.FormatInt(u.Errors, 10) }},
+		{"ncbi_response_bytes_total", "Response body bytes read.", func(u UtilMetrics) string { return strconv.FormatInt(u.Bytes, 10) }},
+		{"ncbi_limiter_wait_seconds_total", "Time spent waiting on Limiters.", func(u UtilMetrics) string { return seconds(u.Wait) }},
+		{"ncbi_latency_seconds_total", "Time between sending requests and receiving responses.", func(u UtilMetrics) string { return seconds(u.Latency) }},
+	} {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			_, err = fmt.Fprintf(w, "%s{util=%q} %s\n", c.name, name, c.value(s[name]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprint(w, "# HELP ncbi_responses_total Responses received.\n# TYPE ncbi_responses_total counter\n")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		codes := make([]int, 0, len(s[name].Responses))
+		for code := range s[name].Responses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			_, err = fmt.Fprintf(w, "ncbi_responses_total{util=%q,code=\"%d\"} %d\n", name, code, s[name].Responses[code])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
@@ -19,12 +19,13 @@
 package ncbi
 
 import (
+	"context"
 	"encoding/xml"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -35,30 +36,6 @@ func SetTimeout(d time.Duration) {
 	client.Timeout = d
 }
 
-// Limiter implements a thread-safe event frequency limit.
-type Limiter struct {
-	m     sync.Mutex
-	delay time.Duration
-	next  time.Time
-}
-
-// NewLimiter returns a Limiter that will wait for the specified duration between Wait calls.
-func NewLimiter(d time.Duration) *Limiter {
-	return &Limiter{delay: d}
-}
-
-// Wait blocks until the Limiter's specified duration has passed since the last Wait call.
-func (d *Limiter) Wait() {
-	d.m.Lock()
-	defer d.m.Unlock()
-	now := time.Now()
-	if d.next.After(now) {
-		time.Sleep(d.next.Sub(now))
-		now = time.Now()
-	}
-	d.next = now.Add(d.delay)
-}
-
 // Util implements low level request generator for interaction with the NCBI services. It is the
 // clients responsibility to provide appropriate program parameters and deserialise the returned
 // data using the appropriate unmarshaling method.
@@ -70,81 +47,348 @@ type Util string
 // circumvent, though circumvention may result in IP blocking by the NCBI servers, so please do not
 // do this.
 func (ut Util) NewRequest(method, db string, v url.Values, tool, email string, l *Limiter) (*http.Request, error) {
+	return defaultClient(tool, email, l).NewRequest(method, ut, db, v)
+}
+
+// NewRequestContext is like NewRequest but includes a context. The returned http.Request
+// uses ctx, and the Limiter wait is abandoned if ctx is done.
+func (ut Util) NewRequestContext(ctx context.Context, method, db string, v url.Values, tool, email string, l *Limiter) (*http.Request, error) {
+	return defaultClient(tool, email, l).NewRequestContext(ctx, method, ut, db, v)
+}
+
+// Prepare constructs a URL with the base provided by ut and the parameters provided by v, tool and email.
+func (ut Util) Prepare(v url.Values, tool, email string) (*url.URL, error) {
+	return defaultClient(tool, email, nil).Prepare(ut, v)
+}
+
+// GetMethodLimit is the maximum length of a constructed URL that will be retrieved by
+// the high level API functions using the GET method.
+var GetMethodLimit = 2048
+
+// GetResponse performs a GET or POST method call to the URI in ut, passing the parameters in v,
+// tool and email. The decision on which method to use is based on the length of the
+// constructed URL the value of GetMethodLimit. An http.Response is returned for a successful
+// request. It is the caller's responsibility to close the response body.
+func (ut Util) GetResponse(v url.Values, tool, email string, l *Limiter) (*http.Response, error) {
+	return defaultClient(tool, email, l).GetResponse(ut, v)
+}
+
+// GetResponseContext is like GetResponse but uses ctx to cancel the request.
+func (ut Util) GetResponseContext(ctx context.Context, v url.Values, tool, email string, l *Limiter) (*http.Response, error) {
+	return defaultClient(tool, email, l).GetResponseContext(ctx, ut, v)
+}
+
+// GetXML performs a GET or POST method call to the URI in ut, passing the parameters in v,
+// tool and email. The returned stream is unmarshaled into d. The decision on which
+// method to use is based on the length of the constructed URL the value of GetMethodLimit.
+// A response status other than 200 OK results in a *StatusError or *RateLimitError.
+func (ut Util) GetXML(v url.Values, tool, email string, l *Limiter, d interface{}) error {
+	return defaultClient(tool, email, l).GetXML(ut, v, d)
+}
+
+// GetXMLContext is like GetXML but uses ctx to cancel the request.
+func (ut Util) GetXMLContext(ctx context.Context, v url.Values, tool, email string, l *Limiter, d interface{}) error {
+	return defaultClient(tool, email, l).GetXMLContext(ctx, ut, v, d)
+}
+
+// Get performs a GET or POST method call to the URI in ut, passing the parameters in v,
+// tool and email. The decision on which method to use is based on the length of the
+// constructed URL the value of GetMethodLimit. An io.ReadCloser is returned for a successful
+// request. It is the caller's responsibility to close this. A response status other than
+// 200 OK results in a *StatusError or *RateLimitError.
+func (ut Util) Get(v url.Values, tool, email string, l *Limiter) (io.ReadCloser, error) {
+	return defaultClient(tool, email, l).Get(ut, v)
+}
+
+// GetContext is like Get but uses ctx to cancel the request.
+func (ut Util) GetContext(ctx context.Context, v url.Values, tool, email string, l *Limiter) (io.ReadCloser, error) {
+	return defaultClient(tool, email, l).GetContext(ctx, ut, v)
+}
+
+// Client holds the configuration used to make requests to the NCBI services. A Client
+// must not be altered while it is in use.
+type Client struct {
+	// HTTP is the http.Client used to send requests. If HTTP is nil, the
+	// package's default http.Client is used. The timeout of the default
+	// http.Client is set by SetTimeout.
+	HTTP *http.Client
+
+	// Bases maps NCBI service base URLs to replacement base URLs. A request
+	// to a Util that has a key of Bases as a prefix is sent to the URL
+	// obtained by replacing that prefix with the corresponding value. If
+	// more than one key matches, the longest is used. The http or https
+	// scheme is ignored when matching, so a key given as an https URL
+	// matches the http base URLs defined by the service packages. Bases
+	// allows requests to be sent to mirrors or local servers.
+	Bases map[string]string
+
+	// AllowHTTP lists the hosts, optionally including a port, that
+	// may be sent requests using plain http. Requests to other hosts,
+	// including all NCBI hosts unless listed, are sent using https.
+	// AllowHTTP allows use of local servers and NCBI cloud images
+	// that do not provide https.
+	AllowHTTP []string
+
+	// Tool and Email are the tool and email parameters included in all
+	// requests made by the Client.
+	Tool  string
+	Email string
+
+	// Limiter limits the frequency of requests made by the Client. If
+	// Limiter is nil, requests are subject to the Limiter registered
+	// for the request's host, if any.
+	Limiter *Limiter
+
+	// Retry is the policy used to retry failed requests. If Retry is
+	// nil, DefaultRetry is used.
+	Retry *Retry
+
+	// Hook, if not nil, is notified of the progress of requests
+	// made by the Client.
+	Hook Hook
+
+	// Cache, if not nil, holds responses to requests made by the
+	// Client. Responses served from the Cache are not subject to
+	// the Limiter and are not reported to the Hook.
+	Cache *Cache
+
+	// DisableCompression prevents the Client requesting gzip
+	// compressed responses. Compressed responses are decompressed
+	// and their checksums verified as they are read.
+	DisableCompression bool
+}
+
+// DefaultClient is the Client used by the Util methods. The tool, email and Limiter
+// passed to the Util methods replace those held by DefaultClient.
+var DefaultClient = &Client{}
+
+// defaultClient returns a copy of DefaultClient with the given tool, email and Limiter.
+func defaultClient(tool, email string, l *Limiter) *Client {
+	c := *DefaultClient
+	c.Tool = tool
+	c.Email = email
+	c.Limiter = l
+	return &c
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return &client
+	}
+	return c.HTTP
+}
+
+// limiter returns the Limiter that applies to requests to u.
+func (c *Client) limiter(u *url.URL) *Limiter {
+	if c.Limiter != nil {
+		return c.Limiter
+	}
+	return LimiterFor(u.Host)
+}
+
+func (c *Client) wait(ctx context.Context, u *url.URL) error {
+	l := c.limiter(u)
+	if l == nil {
+		return ctx.Err()
+	}
+	return l.WaitContext(ctx)
+}
+
+// Resolve returns the Util that requests to ut are sent to after applying the
+// base replacements held in c.Bases. The http or https scheme of ut and of the
+// keys of c.Bases is ignored when matching.
+func (c *Client) Resolve(ut Util) Util {
+	path := trimScheme(string(ut))
+	var base, key string
+	for b := range c.Bases {
+		tb := trimScheme(b)
+		if !strings.HasPrefix(path, tb) {
+			continue
+		}
+		// Prefer the longest match, and a key
+		// with the scheme of ut over one with
+		// a different scheme.
+		if len(tb) > len(base) || (len(tb) == len(base) && strings.HasPrefix(string(ut), b)) {
+			base = tb
+			key = b
+		}
+	}
+	if key == "" {
+		return ut
+	}
+	return Util(c.Bases[key] + strings.TrimPrefix(path, base))
+}
+
+// trimScheme returns s without a leading http or https scheme.
+func trimScheme(s string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		if len(s) >= len(scheme) && strings.EqualFold(s[:len(scheme)], scheme) {
+			return s[len(scheme):]
+		}
+	}
+	return s
+}
+
+// NewRequest returns an http.Request for the utility, ut using the given method. Parameters to
+// be sent to the utility program should be placed in db and v. NewRequest is subject to the
+// Client's Limiter.
+func (c *Client) NewRequest(method string, ut Util, db string, v url.Values) (*http.Request, error) {
+	return c.NewRequestContext(context.Background(), method, ut, db, v)
+}
+
+// NewRequestContext is like NewRequest but includes a context. The returned http.Request
+// uses ctx, and the Limiter wait is abandoned if ctx is done.
+func (c *Client) NewRequestContext(ctx context.Context, method string, ut Util, db string, v url.Values) (*http.Request, error) {
 	if db != "" {
 		v["db"] = []string{db}
 	}
-	u, err := ut.Prepare(v, tool, email)
+	u, err := c.Prepare(ut, v)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(method, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	err = c.wait(ctx, u)
 	if err != nil {
 		return nil, err
 	}
-	l.Wait()
 	return req, nil
 }
 
-// Prepare constructs a URL with the base provided by ut and the parameters provided by v, tool and email.
-func (ut Util) Prepare(v url.Values, tool, email string) (*url.URL, error) {
-	u, err := url.Parse(string(ut))
+// allowsHTTP returns whether u's host is listed in c.AllowHTTP.
+func (c *Client) allowsHTTP(u *url.URL) bool {
+	for _, h := range c.AllowHTTP {
+		if strings.EqualFold(h, u.Host) || strings.EqualFold(h, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prepare constructs a URL with the base provided by ut, after base replacement, and the
+// parameters provided by v and the Client's Tool and Email. The URL uses the https scheme
+// unless its scheme is http and its host is listed in the Client's AllowHTTP.
+func (c *Client) Prepare(ut Util, v url.Values) (*url.URL, error) {
+	u, err := url.Parse(string(c.Resolve(ut)))
 	if err != nil {
 		return nil, err
 	}
-	// Force https scheme. See http://www.ncbi.nlm.nih.gov/news/06-10-2016-ncbi-https/
-	u.Scheme = "https"
-	if tool != "" {
-		v["tool"] = []string{tool}
+	// Force https scheme unless http is explicitly allowed for the host.
+	// See http://www.ncbi.nlm.nih.gov/news/06-10-2016-ncbi-https/
+	if u.Scheme != "http" || !c.allowsHTTP(u) {
+		u.Scheme = "https"
 	}
-	if email != "" {
-		v["email"] = []string{email}
+	if c.Tool != "" {
+		v["tool"] = []string{c.Tool}
+	}
+	if c.Email != "" {
+		v["email"] = []string{c.Email}
 	}
 	u.RawQuery = v.Encode()
 	return u, nil
 }
 
-// GetMethodLimit is the maximum length of a constructed URL that will be retrieved by
-// the high level API functions using the GET method.
-var GetMethodLimit = 2048
+// GetResponse performs a GET or POST method call to the URI in ut, passing the parameters
+// in v. The decision on which method to use is based on the length of the constructed URL
+// the value of GetMethodLimit. Requests failing due to transient conditions are retried
+// according to the Client's Retry policy; if all attempts fail, or a retried request fails,
+// a *RetryError is returned. An http.Response is returned for a successful request. It is
+// the caller's responsibility to close the response body.
+func (c *Client) GetResponse(ut Util, v url.Values) (*http.Response, error) {
+	return c.GetResponseContext(context.Background(), ut, v)
+}
 
-// GetResponse performs a GET or POST method call to the URI in ut, passing the parameters in v,
-// tool and email. The decision on which method to use is based on the length of the
-// constructed URL the value of GetMethodLimit. An http.Response is returned for a successful
-// request. It is the caller's responsibility to close the response body.
-func (ut Util) GetResponse(v url.Values, tool, email string, l *Limiter) (*http.Response, error) {
-	u, err := ut.Prepare(v, tool, email)
+// GetResponseContext is like GetResponse but uses ctx to cancel the request.
+func (c *Client) GetResponseContext(ctx context.Context, ut Util, v url.Values) (*http.Response, error) {
+	u, err := c.Prepare(ut, v)
 	if err != nil {
 		return nil, err
 	}
-	l.Wait()
+	var key string
+	if c.Cache != nil {
+		var ttl time.Duration
+		key, ttl = c.Cache.lookup(ut, u)
+		if key != "" {
+			if body, ok := c.Cache.get(key, ttl); ok {
+				return cachedResponse(body, u), nil
+			}
+		}
+	}
+	resp, err := c.getResponse(ctx, ut, u)
+	if key != "" && err == nil && resp.StatusCode == http.StatusOK {
+		resp.Body = c.Cache.put(key, resp.Body)
+	}
+	return resp, err
+}
+
+// getResponse performs a GET or POST method call to the prepared URL u for ut.
+func (c *Client) getResponse(ctx context.Context, ut Util, u *url.URL) (*http.Response, error) {
 	if len(ut)+len(u.RawQuery) < GetMethodLimit {
-		return client.Get(u.String())
+		return c.do(ctx, c.requestInfo(ctx, ut, http.MethodGet, u), func() (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		})
 	}
-	buf := strings.NewReader(u.RawQuery)
-	u.RawQuery = ""
-	return client.Post(u.String(), "", buf)
+	info := c.requestInfo(ctx, ut, http.MethodPost, u)
+	query := u.RawQuery
+	pu := *u
+	pu.RawQuery = ""
+	return c.do(ctx, info, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, pu.String(), strings.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
 }
 
-// GetXML performs a GET or POST method call to the URI in ut, passing the parameters in v,
-// tool and email. The returned stream is unmarshaled into d. The decision on which
-// method to use is based on the length of the constructed URL the value of GetMethodLimit.
-func (ut Util) GetXML(v url.Values, tool, email string, l *Limiter, d interface{}) error {
-	resp, err := ut.GetResponse(v, tool, email, l)
+// GetXML performs a GET or POST method call to the URI in ut, passing the parameters in v.
+// The returned stream is unmarshaled into d. The decision on which method to use is based
+// on the length of the constructed URL the value of GetMethodLimit. A response status other
+// than 200 OK results in a *StatusError or *RateLimitError.
+func (c *Client) GetXML(ut Util, v url.Values, d interface{}) error {
+	return c.GetXMLContext(context.Background(), ut, v, d)
+}
+
+// GetXMLContext is like GetXML but uses ctx to cancel the request.
+func (c *Client) GetXMLContext(ctx context.Context, ut Util, v url.Values, d interface{}) error {
+	resp, err := c.GetResponseContext(ctx, ut, v)
 	if err != nil {
 		return err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
 	defer resp.Body.Close()
-	return xml.NewDecoder(resp.Body).Decode(d)
+	err = xml.NewDecoder(resp.Body).Decode(d)
+	if err != nil {
+		return err
+	}
+	// Read any trailing data so that the response may be cached
+	// and the connection reused.
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
 }
 
-// Get performs a GET or POST method call to the URI in ut, passing the parameters in v,
-// tool and email. The decision on which method to use is based on the length of the
-// constructed URL the value of GetMethodLimit. An io.ReadCloser is returned for a successful
-// request. It is the caller's responsibility to close this.
-func (ut Util) Get(v url.Values, tool, email string, l *Limiter) (io.ReadCloser, error) {
-	resp, err := ut.GetResponse(v, tool, email, l)
+// Get performs a GET or POST method call to the URI in ut, passing the parameters in v.
+// The decision on which method to use is based on the length of the constructed URL the
+// value of GetMethodLimit. An io.ReadCloser is returned for a successful request. It is
+// the caller's responsibility to close this. A response status other than 200 OK results
+// in a *StatusError or *RateLimitError.
+func (c *Client) Get(ut Util, v url.Values) (io.ReadCloser, error) {
+	return c.GetContext(context.Background(), ut, v)
+}
+
+// GetContext is like Get but uses ctx to cancel the request.
+func (c *Client) GetContext(ctx context.Context, ut Util, v url.Values) (io.ReadCloser, error) {
+	resp, err := c.GetResponseContext(ctx, ut, v)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
 	return resp.Body, nil
 }
@@ -5,6 +5,11 @@
 package ncbi
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -32,3 +37,106 @@ func (s *S) TestLimiter(c *check.C) {
 	time.Sleep(3 * time.Second)
 	c.Check(count < 10, check.Equals, true)
 }
+
+func (s *S) TestResolve(c *check.C) {
+	cl := &Client{Bases: map[string]string{
+		"https://eutils.ncbi.nlm.nih.gov/":               "https://mirror.example.org/",
+		"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/": "https://local.example.org/eutils/",
+	}}
+	for _, t := range []struct {
+		ut   Util
+		want Util
+	}{
+		{ut: "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/esearch.fcgi", want: "https://local.example.org/eutils/esearch.fcgi"},
+		{ut: "https://eutils.ncbi.nlm.nih.gov/other", want: "https://mirror.example.org/other"},
+		{ut: "http://eutils.ncbi.nlm.nih.gov/entrez/eutils/efetch.fcgi", want: "https://local.example.org/eutils/efetch.fcgi"},
+		{ut: "HTTP://eutils.ncbi.nlm.nih.gov/other", want: "https://mirror.example.org/other"},
+		{ut: "https://blast.ncbi.nlm.nih.gov/Blast.cgi", want: "https://blast.ncbi.nlm.nih.gov/Blast.cgi"},
+		{ut: "https://eutils.ncbi.nlm.nih.gov.example.org/", want: "https://eutils.ncbi.nlm.nih.gov.example.org/"},
+	} {
+		c.Check(cl.Resolve(t.ut), check.Equals, t.want)
+	}
+}
+
+func (s *S) TestClient(c *check.C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		fmt.Fprintf(w, "<Result><Path>%s</Path><Tool>%s</Tool><Email>%s</Email><Db>%s</Db></Result>",
+			r.URL.Path, q.Get("tool"), q.Get("email"), q.Get("db"))
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	cl := &Client{
+		HTTP:  srv.Client(),
+		Bases: map[string]string{base: srv.URL + "/eutils/"},
+		Tool:  "tool",
+		Email: "email@example.org",
+	}
+	var got struct {
+		Path  string
+		Tool  string
+		Email string
+		Db    string
+	}
+	err := cl.GetXML(Util(base+"einfo.fcgi"), url.Values{"db": []string{"pubmed"}}, &got)
+	c.Assert(err, check.Equals, nil)
+	c.Check(got.Path, check.Equals, "/eutils/einfo.fcgi")
+	c.Check(got.Tool, check.Equals, "tool")
+	c.Check(got.Email, check.Equals, "email@example.org")
+	c.Check(got.Db, check.Equals, "pubmed")
+}
+
+func (s *S) TestAllowHTTP(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<Result><Path>"+r.URL.Path+"</Path></Result>")
+	}))
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+
+	const base = "https://blast.ncbi.nlm.nih.gov/"
+	cl := &Client{
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Retry:   NoRetry,
+	}
+	for _, t := range []struct {
+		allow []string
+		ut    Util
+		want  string
+	}{
+		{ut: "http://www.ncbi.nlm.nih.gov/blast/Blast.cgi", want: "https"},
+		{ut: base + "Blast.cgi", want: "https"},
+		{allow: []string{host}, ut: base + "Blast.cgi", want: "http"},
+		{allow: []string{host}, ut: "http://blast.ncbi.nlm.nih.gov/Blast.cgi", want: "http"},
+		{allow: []string{"127.0.0.1"}, ut: base + "Blast.cgi", want: "http"},
+		{allow: []string{host}, ut: "http://www.ncbi.nlm.nih.gov/blast/Blast.cgi", want: "https"},
+		{allow: []string{"www.ncbi.nlm.nih.gov"}, ut: "http://www.ncbi.nlm.nih.gov/blast/Blast.cgi", want: "http"},
+	} {
+		cl.AllowHTTP = t.allow
+		u, err := cl.Prepare(t.ut, url.Values{})
+		c.Assert(err, check.Equals, nil)
+		c.Check(u.Scheme, check.Equals, t.want, check.Commentf("allow=%v ut=%s", t.allow, t.ut))
+	}
+
+	cl.AllowHTTP = []string{host}
+	var got struct{ Path string }
+	err := cl.GetXML(Util(base+"Blast.cgi"), url.Values{}, &got)
+	c.Assert(err, check.Equals, nil)
+	c.Check(got.Path, check.Equals, "/Blast.cgi")
+
+	cl.AllowHTTP = nil
+	err = cl.GetXML(Util(base+"Blast.cgi"), url.Values{}, &got)
+	c.Check(err, check.Not(check.Equals), nil)
+}
+
+func (s *S) TestLimiterWaitContext(c *check.C) {
+	l := NewLimiter(time.Hour)
+	c.Check(l.WaitContext(context.Background()), check.Equals, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	c.Check(l.WaitContext(ctx), check.Equals, context.DeadlineExceeded)
+	c.Check(time.Since(start) < time.Second, check.Equals, true)
+}
@@ -0,0 +1,309 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ncbitest provides support for testing code that uses the NCBI services
+// without network access.
+//
+// A Recorder is an http.RoundTripper that stores the requests it sends and the
+// responses it receives in a cassette directory, and that serves the stored
+// responses when replaying. A Recorder is used by setting the HTTP field of an
+// ncbi.Client, or of ncbi.DefaultClient for the package level functions, to the
+// http.Client returned by its Client method.
+//
+//	rec := ncbitest.NewRecorder("testdata/search", ncbitest.Replay)
+//	c := &entrez.Client{Client: ncbi.Client{HTTP: rec.Client()}}
+//	s, err := c.DoSearch("pubmed", "hox", nil, nil)
+//
+// Requests are matched by method, URL, query parameters and body. The tool, email
+// and api_key parameters are ignored when matching and are not stored, so cassettes
+// may be recorded with personal credentials and shared. Repeated identical requests,
+// such as BLAST status polls, are replayed in the order they were recorded.
+package ncbitest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode specifies the behaviour of a Recorder.
+type Mode int
+
+const (
+	// Replay serves responses from the cassette and fails requests
+	// that have not been recorded.
+	Replay Mode = iota
+
+	// Record sends requests to the network and stores the responses
+	// in the cassette, replacing any existing recording.
+	Record
+
+	// ReplayOrRecord serves responses from the cassette and records
+	// requests that have not been recorded.
+	ReplayOrRecord
+)
+
+// ErrNotRecorded is returned by a replaying Recorder for requests that are
+// not held in its cassette.
+var ErrNotRecorded = errors.New("ncbitest: request not recorded")
+
+// volatile holds parameters that are ignored when matching requests and
+// are not stored in cassettes.
+var volatile = []string{"tool", "email", "api_key"}
+
+// Recorder is an http.RoundTripper that records and replays HTTP interactions
+// using a cassette directory.
+type Recorder struct {
+	// Dir is the cassette directory.
+	Dir string
+
+	// Mode is the Recorder's mode.
+	Mode Mode
+
+	// Transport is used to send requests when recording.
+	// If Transport is nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewRecorder returns a Recorder using the cassette directory dir in the given mode.
+func NewRecorder(dir string, mode Mode) *Recorder {
+	return &Recorder{Dir: dir, Mode: mode}
+}
+
+// Client returns an http.Client that uses the Recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// interaction is the stored form of a request and its response.
+type interaction struct {
+	Request  request  `json:"request"`
+	Response response `json:"response"`
+}
+
+type request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type response struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+
+	// Body holds the response body if it is valid UTF-8.
+	// Otherwise the body is held in RawBody.
+	Body    string `json:"body,omitempty"`
+	RawBody []byte `json:"raw_body,omitempty"`
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	stored := request{
+		Method: req.Method,
+		URL:    canonicalURL(req.URL),
+		Body:   canonicalBody(req.Header.Get("Content-Type"), body),
+	}
+	key := stored.Method + " " + stored.URL + "\n" + stored.Body
+
+	r.mu.Lock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	n := r.calls[key]
+	r.calls[key]++
+	r.mu.Unlock()
+
+	name := fileName(req.URL, key)
+	if r.Mode != Record {
+		resp, err := r.replay(req, name, n)
+		if err == nil || r.Mode == Replay || !errors.Is(err, ErrNotRecorded) {
+			return resp, err
+		}
+	}
+	return r.record(req, body, stored, name, n)
+}
+
+// replay returns the nth response recorded for the request with the given
+// cassette file name stem. If fewer than n+1 responses were recorded, the
+// last recorded response is returned.
+func (r *Recorder) replay(req *http.Request, name string, n int) (*http.Response, error) {
+	var (
+		b   []byte
+		err error
+	)
+	for ; n >= 0; n-- {
+		b, err = ioutil.ReadFile(filepath.Join(r.Dir, fmt.Sprintf("%s-%d.json", name, n)))
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, canonicalURL(req.URL))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var in interaction
+	err = json.Unmarshal(b, &in)
+	if err != nil {
+		return nil, err
+	}
+	return in.Response.http(req), nil
+}
+
+// record sends req, stores the interaction as the nth recording for the request
+// and returns the response.
+func (r *Recorder) record(req *http.Request, body []byte, stored request, name string, n int) (*http.Response, error) {
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	in := interaction{
+		Request: stored,
+		Response: response{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+		},
+	}
+	if utf8.Valid(b) {
+		in.Response.Body = string(b)
+	} else {
+		in.Response.RawBody = b
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(in)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(r.Dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(filepath.Join(r.Dir, fmt.Sprintf("%s-%d.json", name, n)), buf.Bytes(), 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// http returns the stored response as an http.Response to req.
+func (s response) http(req *http.Request) *http.Response {
+	body := s.RawBody
+	if body == nil {
+		body = []byte(s.Body)
+	}
+	h := s.Header
+	if h == nil {
+		h = make(http.Header)
+	}
+	return &http.Response{
+		Status:        s.Status,
+		StatusCode:    s.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// canonicalURL returns u with its query sorted and volatile parameters removed.
+func canonicalURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = canonicalQuery(u.Query())
+	c.Fragment = ""
+	return c.String()
+}
+
+// canonicalBody returns body with its parameters sorted and volatile parameters
+// removed if it is form-encoded.
+func canonicalBody(contentType string, body []byte) string {
+	if !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return string(body)
+	}
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+	return canonicalQuery(v)
+}
+
+func canonicalQuery(v url.Values) string {
+	for _, p := range volatile {
+		delete(v, p)
+	}
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for _, k := range keys {
+		for _, val := range v[k] {
+			if buf.Len() != 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k))
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(val))
+		}
+	}
+	return buf.String()
+}
+
+// fileName returns the cassette file name stem for a request to u with the
+// given matching key.
+func fileName(u *url.URL, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	base := path.Base(u.Path)
+	if base == "/" || base == "." {
+		base = "root"
+	}
+	return base + "-" + hex.EncodeToString(sum[:8])
+}
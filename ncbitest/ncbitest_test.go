@@ -0,0 +1,126 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbitest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+// server returns a server that responds with the request's term parameter
+// and the number of requests it has received, and a function reporting that
+// number.
+func server() (*httptest.Server, func() int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		r.ParseForm()
+		fmt.Fprintf(w, "%s %d", r.Form.Get("term"), n)
+	}))
+	return srv, func() int32 { return atomic.LoadInt32(&calls) }
+}
+
+func get(c *http.Client, u string) (string, error) {
+	resp, err := c.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func (s *S) TestRecordReplay(c *check.C) {
+	srv, calls := server()
+	dir := c.MkDir()
+
+	rec := NewRecorder(dir, Record).Client()
+	for i, t := range []struct {
+		url  string
+		want string
+	}{
+		{url: srv.URL + "/esearch.fcgi?term=a&tool=x&email=y", want: "a 1"},
+		{url: srv.URL + "/esearch.fcgi?term=b", want: "b 2"},
+		{url: srv.URL + "/esearch.fcgi?term=a", want: "a 3"},
+	} {
+		got, err := get(rec, t.url)
+		c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(got, check.Equals, t.want, check.Commentf("Test %d", i))
+	}
+	resp, err := rec.PostForm(srv.URL+"/epost.fcgi", url.Values{"term": {"c"}, "api_key": {"secret"}})
+	c.Assert(err, check.Equals, nil)
+	resp.Body.Close()
+	srv.Close()
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, check.Equals, nil)
+	c.Check(len(files), check.Equals, 4)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(dir + "/" + f.Name())
+		c.Assert(err, check.Equals, nil)
+		for _, p := range []string{"tool=", "email=", "api_key", "secret"} {
+			c.Check(strings.Contains(string(b), p), check.Equals, false, check.Commentf("%s contains %q", f.Name(), p))
+		}
+	}
+
+	play := NewRecorder(dir, Replay).Client()
+	for i, t := range []struct {
+		url  string
+		want string
+	}{
+		{url: srv.URL + "/esearch.fcgi?term=a&tool=z", want: "a 1"},
+		{url: srv.URL + "/esearch.fcgi?term=a", want: "a 3"},
+		// Requests beyond those recorded replay the last recording.
+		{url: srv.URL + "/esearch.fcgi?term=a", want: "a 3"},
+		{url: srv.URL + "/esearch.fcgi?term=b&api_key=other", want: "b 2"},
+	} {
+		got, err := get(play, t.url)
+		c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+		c.Check(got, check.Equals, t.want, check.Commentf("Test %d", i))
+	}
+	resp, err = play.PostForm(srv.URL+"/epost.fcgi", url.Values{"term": {"c"}})
+	c.Assert(err, check.Equals, nil)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Check(string(b), check.Equals, "c 4")
+
+	_, err = get(play, srv.URL+"/esearch.fcgi?term=d")
+	c.Check(errors.Is(err, ErrNotRecorded), check.Equals, true, check.Commentf("unexpected error: %v", err))
+	c.Check(calls(), check.Equals, int32(4))
+}
+
+func (s *S) TestReplayOrRecord(c *check.C) {
+	srv, calls := server()
+	defer srv.Close()
+	dir := c.MkDir()
+
+	first := NewRecorder(dir, ReplayOrRecord).Client()
+	got, err := get(first, srv.URL+"/esearch.fcgi?term=a")
+	c.Check(err, check.Equals, nil)
+	c.Check(got, check.Equals, "a 1")
+
+	second := NewRecorder(dir, ReplayOrRecord).Client()
+	for _, want := range []string{"a 1", "b 2"} {
+		got, err = get(second, srv.URL+"/esearch.fcgi?term="+want[:1])
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.Equals, want)
+	}
+	c.Check(calls(), check.Equals, int32(2))
+}
@@ -108,6 +108,7 @@ func BLAST(query string, retry int, pp *blast.PutParameters, gp *blast.GetParame
 	var o *blast.Output
 	for k := 0; k < retry; k++ {
 		// Wait for RTOE to elapse and get search status.
+		// Failed and expired searches are reported as errors.
 		var s *blast.SearchInfo
 		s, err = r.SearchInfo(tool, email)
 		if err != nil {
@@ -120,10 +121,6 @@ func BLAST(query string, retry int, pp *blast.PutParameters, gp *blast.GetParame
 		switch s.Status {
 		case "WAITING":
 			continue
-		case "FAILED":
-			return nil, fmt.Errorf("search: %s failed", r)
-		case "UNKNOWN":
-			return nil, fmt.Errorf("search: %s expired", r)
 		case "READY":
 			if !s.HaveHits {
 				return nil, fmt.Errorf("search: %s no hits", r)
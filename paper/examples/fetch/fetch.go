@@ -6,11 +6,11 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"io"
 	"log"
 	"os"
+	"time"
 
 	"github.com/biogo/ncbi"
 	"github.com/biogo/ncbi/entrez"
@@ -25,7 +25,7 @@ var (
 	db      = flag.String("db", "protein", "db specifies the database to search")
 	rettype = flag.String("rettype", "fasta", "rettype specifies the format of the returned data.")
 	retmax  = flag.Int("retmax", 500, "retmax specifies the number of records to be retrieved per request.")
-	out     = flag.String("out", "", "out specifies destination of the returned data (default to stdout).")
+	out     = flag.String("out", "", "out specifies destination of the returned data (default to stdout); a .gz extension compresses the output.")
 	email   = flag.String("email", "", "email specifies the email address to be sent to the server (required).")
 	retries = flag.Int("retry", 5, "retry specifies the number of attempts to retrieve the data.")
 	help    = flag.Bool("help", false, "help prints this message.")
@@ -45,69 +45,59 @@ func main() {
 		os.Exit(1)
 	}
 
-	var of *os.File
-	var err error
-	if *out == "" {
-		of = os.Stdout
-	} else {
-		of, err = os.Create(*out)
-		if err != nil {
-			log.Fatalf("failed to create output file: %v\n", err)
-		}
-		defer of.Close()
-	}
-
-	h := entrez.History{}
-	s, err := entrez.DoSearch(*db, *clQuery, nil, &h, tool, *email)
+	// Failed requests are retried by the ncbi package, and pages
+	// that fail while being read are retried by the Pager.
+	retry := &ncbi.Retry{MaxAttempts: *retries, MinBackoff: time.Second, MaxBackoff: time.Minute}
+	ncbi.DefaultClient.Retry = retry
+	pg, err := entrez.NewPager(*db, *clQuery, &entrez.Parameters{RetType: *rettype, RetMode: "text"}, tool, *email)
 	if err != nil {
 		log.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
-	log.Printf("will retrieve %d records.\n", s.Count)
+	log.Printf("will retrieve %d records.\n", pg.Count())
+	pg.Batch = *retmax
+	pg.Retry = retry
+	pg.Progress = func(done, count int) {
+		log.Printf("retrieved %d of %d records.\n", done, count)
+	}
 
 	var (
-		buf   = &bytes.Buffer{}
-		p     = &entrez.Parameters{RetMax: *retmax, RetType: *rettype, RetMode: "text"}
-		bn, n int64
+		w  io.Writer = os.Stdout
+		fw *entrez.FileWriter
 	)
-	for p.RetStart = 0; p.RetStart < s.Count; p.RetStart += p.RetMax {
-		log.Printf("attempting to retrieve %d records starting from %d with %d retries.\n", p.RetMax, p.RetStart, *retries)
-		var t int
-		for t = 0; t < *retries; t++ {
-			buf.Reset()
-			var (
-				r   io.ReadCloser
-				_bn int64
-			)
-			r, err = entrez.Fetch(*db, p, tool, *email, &h)
-			if err != nil {
-				if r != nil {
-					r.Close()
-				}
-				log.Printf("failed to retrieve on attempt %d... error: %v ... retrying.\n", t, err)
-				continue
-			}
-			_bn, err = io.Copy(buf, r)
-			bn += _bn
-			r.Close()
-			if err == nil {
-				break
-			}
-			log.Printf("failed to buffer on attempt %d... error: %v ... retrying.\n", t, err)
-		}
+	if *out != "" {
+		// Output is compressed according to the file extension.
+		fw, err = entrez.NewFileWriter(*out)
 		if err != nil {
-			os.Exit(1)
+			log.Fatalf("failed to create output file: %v\n", err)
 		}
+		w = fw
+	}
 
-		log.Printf("retrieved records with %d retries... writing out.\n", t)
-		_n, err := io.Copy(of, buf)
-		n += _n
+	for pg.NextRecords() {
+		_, err = w.Write(pg.Records())
 		if err != nil {
-			log.Printf("Error: %v\n", err)
+			log.Printf("failed to write records: %v\n", err)
+			if fw != nil {
+				fw.Abort()
+			}
 			os.Exit(1)
 		}
 	}
-	if bn != n {
-		log.Printf("writethrough mismatch: %d != %d\n", bn, n)
+	if pg.Err() != nil {
+		log.Printf("failed to retrieve records from %d: %v\n", pg.Start, pg.Err())
+		if fw != nil {
+			fw.Abort()
+		}
+		os.Exit(1)
+	}
+	if fw != nil {
+		err = fw.Close()
+		if err != nil {
+			log.Fatalf("failed to write output file: %v\n", err)
+		}
+		st := fw.Stats()
+		log.Printf("wrote %d bytes (sha256 %s) to %s as %d bytes (sha256 %s).\n",
+			st.Bytes, st.SHA256, st.Path, st.FileBytes, st.FileSHA256)
 	}
 }
@@ -0,0 +1,259 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Retry specifies the policy used by a Client to retry requests that fail due to
+// transient conditions. Requests are retried when the server responds with status
+// 429 (Too Many Requests) or a 5xx status, or when the connection is reset or times
+// out. Requests resulting in other 4xx statuses are never retried.
+type Retry struct {
+	// MaxAttempts is the maximum number of attempts made for a
+	// request. Values less than one are treated as one.
+	MaxAttempts int
+
+	// MinBackoff is the base delay before a retry. The delay is
+	// doubled for each subsequent retry up to MaxBackoff, and a
+	// random jitter of up to half the delay is subtracted.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetry is the retry policy used by a Client with a nil Retry field.
+var DefaultRetry = &Retry{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// NoRetry is a retry policy that makes a single attempt for each request.
+var NoRetry = &Retry{MaxAttempts: 1}
+
+// RetryError is returned when a request has failed after the number of attempts
+// allowed by a Retry policy, or when a request fails after it has been retried.
+type RetryError struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+
+	// Err is the error from the final attempt. A final
+	// response with an error status is reported as a
+	// *StatusError or *RateLimitError.
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("ncbi: request failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// Attempts returns the maximum number of attempts allowed by r.
+func (r *Retry) Attempts() int {
+	if r.MaxAttempts < 1 {
+		return 1
+	}
+	return r.MaxAttempts
+}
+
+// Backoff returns the delay before the retry following the given attempt number.
+func (r *Retry) Backoff(attempt int) time.Duration {
+	d := r.MinBackoff
+	for i := 1; i < attempt && (r.MaxBackoff <= 0 || d < r.MaxBackoff); i++ {
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d - time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// retryable returns whether a request with the given outcome should be retried.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+			errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return true
+		}
+		var nerr net.Error
+		return errors.As(err, &nerr) && nerr.Timeout()
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// Temporary returns whether err is the result of a transient condition that may
+// be resolved by repeating the request. Errors returned by a Client after the
+// attempts allowed by its Retry policy are considered temporary if the final
+// attempt failed due to a transient condition.
+func Temporary(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var re *RateLimitError
+	if errors.As(err, &re) {
+		return true
+	}
+	if errors.Is(err, gzip.ErrChecksum) {
+		// The response was corrupted in transit.
+		return true
+	}
+	return err != nil && retryable(nil, err)
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func (c *Client) retry() *Retry {
+	if c.Retry == nil {
+		return DefaultRetry
+	}
+	return c.Retry
+}
+
+// requestInfo returns a RequestInfo for a request to ut if the Client has a Hook.
+func (c *Client) requestInfo(ctx context.Context, ut Util, method string, u *url.URL) *RequestInfo {
+	if c.Hook == nil {
+		return nil
+	}
+	return newRequestInfo(ctx, ut, method, u)
+}
+
+// do performs the request returned by newRequest, retrying according to the
+// Client's retry policy. Each attempt is subject to the Client's Limiter. A 429
+// response throttles the Limiter for the duration of the retry delay. If info
+// is not nil, the progress of the request is reported to the Client's Hook.
+func (c *Client) do(ctx context.Context, info *RequestInfo, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	hook := c.Hook
+	if info == nil {
+		hook = nil
+	}
+	var attempt int
+	fail := func(err error) (*http.Response, error) {
+		var rerr *RetryError
+		if attempt > 1 && !errors.As(err, &rerr) {
+			err = &RetryError{Attempts: attempt, Err: err}
+		}
+		if hook != nil {
+			hook.Error(info, err)
+		}
+		return nil, err
+	}
+
+	policy := c.retry()
+	for attempt = 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return fail(err)
+		}
+		compressed := c.acceptEncoding(req)
+		if hook != nil {
+			info.Attempt = attempt
+			hook.Start(info)
+		}
+		l := c.limiter(req.URL)
+		if l != nil {
+			start := time.Now()
+			err = l.WaitContext(ctx)
+			if hook != nil {
+				hook.Wait(info, time.Since(start))
+			}
+			if err != nil {
+				return fail(err)
+			}
+		}
+		if hook != nil {
+			info.Sent = time.Now()
+		}
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			// The error holds the request URL, which
+			// must not expose the API key.
+			err = redactError(err)
+		}
+		if hook != nil && resp != nil {
+			hook.Response(info, resp.StatusCode)
+		}
+		if compressed && err == nil {
+			err = decodeBody(resp)
+			if err != nil {
+				return fail(err)
+			}
+		}
+		if !retryable(resp, err) {
+			if err != nil {
+				return fail(err)
+			}
+			if attempt > 1 && resp.StatusCode >= 400 {
+				// Report the attempts made.
+				return fail(statusError(resp))
+			}
+			if hook != nil {
+				resp.Body = &countingBody{ReadCloser: resp.Body, hook: hook, info: info}
+			}
+			return resp, nil
+		}
+
+		delay, ok := retryAfter(resp)
+		if !ok {
+			delay = policy.Backoff(attempt)
+		}
+		if l != nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			// Slow all users of the Limiter, not just this request.
+			l.Throttle(delay)
+		}
+		if resp != nil {
+			err = statusError(resp)
+		}
+		if attempt >= policy.Attempts() {
+			return fail(&RetryError{Attempts: attempt, Err: err})
+		}
+		if hook != nil {
+			hook.Retry(info, delay, err)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fail(ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ncbi
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRetry(c *check.C) {
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	for i, t := range []struct {
+		statuses []int
+		attempts int
+
+		wantCalls  int32
+		wantStatus int
+		wantErr    bool
+	}{
+		{statuses: []int{200}, attempts: 3, wantCalls: 1, wantStatus: 200},
+		{statuses: []int{503, 429, 200}, attempts: 3, wantCalls: 3, wantStatus: 200},
+		{statuses: []int{500, 502, 503, 504}, attempts: 3, wantCalls: 3, wantErr: true},
+		{statuses: []int{404, 200}, attempts: 3, wantCalls: 1, wantStatus: 404},
+		{statuses: []int{400, 200}, attempts: 3, wantCalls: 1, wantStatus: 400},
+		{statuses: []int{503, 200}, attempts: 1, wantCalls: 1, wantErr: true},
+		{statuses: []int{503, 503, 400}, attempts: 5, wantCalls: 3, wantStatus: 400, wantErr: true},
+	} {
+		var calls int32
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			w.WriteHeader(t.statuses[n-1])
+		}))
+
+		cl := &Client{
+			HTTP:  srv.Client(),
+			Bases: map[string]string{base: srv.URL + "/"},
+			Retry: &Retry{MaxAttempts: t.attempts, MinBackoff: time.Millisecond},
+		}
+		resp, err := cl.GetResponse(Util(base+"einfo.fcgi"), url.Values{})
+		c.Check(atomic.LoadInt32(&calls), check.Equals, t.wantCalls, check.Commentf("Test %d", i))
+		if t.wantErr {
+			var rerr *RetryError
+			c.Check(errors.As(err, &rerr), check.Equals, true, check.Commentf("Test %d", i))
+			if rerr != nil {
+				c.Check(rerr.Attempts, check.Equals, int(t.wantCalls), check.Commentf("Test %d", i))
+			}
+			var serr *StatusError
+			if t.wantStatus != 0 && c.Check(errors.As(err, &serr), check.Equals, true, check.Commentf("Test %d", i)) {
+				c.Check(serr.Code, check.Equals, t.wantStatus, check.Commentf("Test %d", i))
+			}
+		} else {
+			c.Check(err, check.Equals, nil, check.Commentf("Test %d", i))
+			if resp != nil {
+				c.Check(resp.StatusCode, check.Equals, t.wantStatus, check.Commentf("Test %d", i))
+				resp.Body.Close()
+			}
+		}
+		srv.Close()
+	}
+}
+
+func (s *S) TestRetryPost(c *check.C) {
+	var calls int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	cl := &Client{
+		HTTP:  srv.Client(),
+		Bases: map[string]string{base: srv.URL + "/"},
+		Retry: &Retry{MaxAttempts: 2, MinBackoff: time.Millisecond},
+	}
+	long := make([]byte, GetMethodLimit)
+	for i := range long {
+		long[i] = 'a'
+	}
+	r, err := cl.Get(Util(base+"efetch.fcgi"), url.Values{"id": []string{string(long)}})
+	c.Assert(err, check.Equals, nil)
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	c.Check(err, check.Equals, nil)
+	c.Check(string(b), check.Equals, "id="+string(long))
+}
+
+func (s *S) TestRetryAfter(c *check.C) {
+	for _, t := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "5", want: 5 * time.Second, ok: true},
+		{header: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, ok: true},
+		{header: "soon", ok: false},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if t.header != "" {
+			resp.Header.Set("Retry-After", t.header)
+		}
+		d, ok := retryAfter(resp)
+		c.Check(ok, check.Equals, t.ok, check.Commentf("header: %q", t.header))
+		c.Check(d, check.Equals, t.want, check.Commentf("header: %q", t.header))
+	}
+}
+
+func (s *S) TestBackoff(c *check.C) {
+	r := &Retry{MinBackoff: time.Second, MaxBackoff: 4 * time.Second}
+	for attempt, max := range []time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 4 * time.Second} {
+		if attempt == 0 {
+			continue
+		}
+		d := r.Backoff(attempt)
+		c.Check(d <= max && d >= max/2, check.Equals, true, check.Commentf("attempt %d: %v", attempt, d))
+	}
+}
+
+func (s *S) TestTemporary(c *check.C) {
+	for _, t := range []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("other"), want: false},
+		{err: io.ErrUnexpectedEOF, want: true},
+		{err: gzip.ErrChecksum, want: true},
+		{err: context.Canceled, want: false},
+		{err: &StatusError{Code: http.StatusBadRequest}, want: false},
+		{err: &StatusError{Code: http.StatusBadGateway}, want: true},
+		{err: &RateLimitError{StatusError: StatusError{Code: http.StatusTooManyRequests}}, want: true},
+		{err: &RetryError{Attempts: 5, Err: &StatusError{Code: http.StatusServiceUnavailable}}, want: true},
+	} {
+		c.Check(Temporary(t.err), check.Equals, t.want, check.Commentf("%v", t.err))
+	}
+}
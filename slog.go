@@ -0,0 +1,76 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package ncbi
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogHook is a Hook that logs the progress of requests to a slog.Logger. Request
+// starts, Limiter waits, responses and completed bodies are logged at the debug
+// level, retries at the warn level and errors at the error level.
+type SlogHook struct {
+	Logger *slog.Logger
+}
+
+// NewSlogHook returns a SlogHook logging to l. If l is nil, slog.Default() is used.
+func NewSlogHook(l *slog.Logger) *SlogHook {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogHook{Logger: l}
+}
+
+func (h *SlogHook) log(r *RequestInfo, level slog.Level, msg string, attrs ...slog.Attr) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !h.Logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("util", r.Util),
+		slog.String("method", r.Method),
+		slog.String("url", r.URL.String()),
+		slog.Int("attempt", r.Attempt),
+	}, attrs...)
+	h.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// Start implements the Hook interface.
+func (h *SlogHook) Start(r *RequestInfo) {
+	h.log(r, slog.LevelDebug, "ncbi request start")
+}
+
+// Wait implements the Hook interface.
+func (h *SlogHook) Wait(r *RequestInfo, d time.Duration) {
+	h.log(r, slog.LevelDebug, "ncbi limiter wait", slog.Duration("wait", d))
+}
+
+// Response implements the Hook interface.
+func (h *SlogHook) Response(r *RequestInfo, status int) {
+	h.log(r, slog.LevelDebug, "ncbi response", slog.Int("status", status), slog.Duration("latency", time.Since(r.Sent)))
+}
+
+// Done implements the Hook interface.
+func (h *SlogHook) Done(r *RequestInfo, n int64) {
+	h.log(r, slog.LevelDebug, "ncbi request done", slog.Int64("bytes", n), slog.Duration("elapsed", time.Since(r.Start)))
+}
+
+// Retry implements the Hook interface.
+func (h *SlogHook) Retry(r *RequestInfo, delay time.Duration, err error) {
+	h.log(r, slog.LevelWarn, "ncbi request retry", slog.Duration("delay", delay), slog.Any("error", err))
+}
+
+// Error implements the Hook interface.
+func (h *SlogHook) Error(r *RequestInfo, err error) {
+	h.log(r, slog.LevelError, "ncbi request failed", slog.Any("error", err), slog.Duration("elapsed", time.Since(r.Start)))
+}
@@ -0,0 +1,85 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package ncbi
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSlogHook(c *check.C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	var buf bytes.Buffer
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Hook:    NewSlogHook(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	}
+	r, err := cl.Get(Util(base+"einfo.fcgi"), url.Values{})
+	c.Assert(err, check.Equals, nil)
+	ioutil.ReadAll(r)
+	r.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(len(lines), check.Equals, 4)
+	for i, want := range []string{
+		`msg="ncbi request start" util=einfo method=GET`,
+		`msg="ncbi limiter wait" util=einfo`,
+		`msg="ncbi response" util=einfo`,
+		`msg="ncbi request done" util=einfo`,
+	} {
+		c.Check(strings.Contains(lines[i], want), check.Equals, true, check.Commentf("line %d: %s", i, lines[i]))
+	}
+	c.Check(strings.Contains(lines[2], "status=200"), check.Equals, true)
+	c.Check(strings.Contains(lines[3], "bytes=5"), check.Equals, true)
+}
+
+func (s *S) TestSlogHookRedactsError(c *check.C) {
+	// Connections are closed without a response.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	const base = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/"
+	var buf bytes.Buffer
+	cl := &Client{
+		HTTP:    srv.Client(),
+		Bases:   map[string]string{base: srv.URL + "/"},
+		Limiter: NewLimiter(0),
+		Retry:   &Retry{MaxAttempts: 2, MinBackoff: time.Millisecond},
+		Hook:    NewSlogHook(slog.New(slog.NewTextHandler(&buf, nil))),
+	}
+	_, err := cl.Get(Util(base+"einfo.fcgi"), url.Values{"api_key": []string{"secret"}})
+	c.Assert(err, check.NotNil)
+	c.Check(strings.Contains(err.Error(), "secret"), check.Equals, false, check.Commentf("unredacted error: %v", err))
+
+	log := buf.String()
+	c.Check(strings.Contains(log, "ncbi request retry"), check.Equals, true)
+	c.Check(strings.Contains(log, "ncbi request failed"), check.Equals, true)
+	c.Check(strings.Contains(log, "REDACTED"), check.Equals, true)
+	c.Check(strings.Contains(log, "secret"), check.Equals, false, check.Commentf("unredacted log: %s", log))
+}